@@ -25,8 +25,9 @@ build_platform() {
     local output_name="${APP_NAME}-${os}-${arch}${ext}"
     
     GOOS="$os" GOARCH="$arch" go build \
+        -ldflags "-X main.version=$VERSION" \
         -o "$BUILD_DIR/$output_name" \
-        "$CMD_DIR/main.go"
+        "./$CMD_DIR"
     
     echo "✓ Built: $BUILD_DIR/$output_name"
 }
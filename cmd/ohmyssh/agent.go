@@ -0,0 +1,198 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pozgo/OhMySSH/pkg/launch"
+	"github.com/pozgo/OhMySSH/pkg/parser"
+	"github.com/pozgo/OhMySSH/pkg/sshagent"
+	"github.com/pozgo/OhMySSH/pkg/sshkeys"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// agentState is what ssh-agent holds, next to the keys that the hosts'
+// IdentityFile settings point at, so the two can be matched by fingerprint.
+type agentState struct {
+	available  bool
+	err        error
+	identities []sshagent.Identity
+	loaded     map[string]bool        // fingerprints of loaded keys
+	keys       map[string]sshkeys.Key // by IdentityFile path
+}
+
+// agentMsg delivers the result of loadAgent.
+type agentMsg struct {
+	state agentState
+}
+
+// loadAgent reads the IdentityFile keys of all hosts and asks the agent
+// which keys it holds. Reading keys can be slow, so it runs as a command.
+func loadAgent(config *parser.SSHConfig) tea.Cmd {
+	return func() tea.Msg {
+		state := agentState{keys: make(map[string]sshkeys.Key)}
+		for _, host := range config.GetHosts() {
+			path, ok := identityPath(config.Effective(host.Patterns()[0])["identityfile"])
+			if _, seen := state.keys[path]; !ok || seen {
+				continue
+			}
+			if key, err := sshkeys.Inspect(path); err == nil && key.Fingerprint != "" {
+				state.keys[path] = key
+			}
+		}
+
+		client, err := sshagent.Dial()
+		if err != nil {
+			state.err = err
+			return agentMsg{state: state}
+		}
+		defer client.Close()
+		state.identities, state.err = client.Identities()
+		state.available = state.err == nil
+		state.loaded = make(map[string]bool, len(state.identities))
+		for _, identity := range state.identities {
+			state.loaded[identity.Fingerprint] = true
+		}
+		return agentMsg{state: state}
+	}
+}
+
+// hostIdentity returns the key the effective IdentityFile of host points at,
+// if it could be read.
+func (m model) hostIdentity(host parser.Host) (sshkeys.Key, bool) {
+	path, ok := identityPath(m.sshConfig.Effective(host.Patterns()[0])["identityfile"])
+	if !ok {
+		return sshkeys.Key{}, false
+	}
+	key, ok := m.agent.keys[path]
+	return key, ok
+}
+
+// keyInAgent reports whether the IdentityFile key of host is loaded. known
+// is false when there is no agent or the key could not be read.
+func (m model) keyInAgent(host parser.Host) (loaded, known bool) {
+	key, ok := m.hostIdentity(host)
+	if !ok || !m.agent.available {
+		return false, false
+	}
+	return m.agent.loaded[key.Fingerprint], true
+}
+
+// connectHost connects to host in the default launch target.
+func (m model) connectHost(host parser.Host) (tea.Model, tea.Cmd) {
+	return m.connectHostIn(host, m.defaultTarget(), launch.Options{})
+}
+
+// connectHostIn opens a session to host in target, with options on top of
+// the host's settings. When its key is protected by a passphrase and not in
+// the agent, the user is offered to add it first.
+func (m model) connectHostIn(host parser.Host, target launch.Target, options launch.Options) (tea.Model, tea.Cmd) {
+	m.launchTarget = target
+	m.connectOptions = options
+	if key, ok := m.hostIdentity(host); ok && key.Encrypted && m.agent.available && !m.agent.loaded[key.Fingerprint] {
+		m.form = newForm(formAddAgentKey, fmt.Sprintf("🔑 Add %s to ssh-agent before connecting to %s?", key.Name(), host.Name), host.Name,
+			newPasswordField("Passphrase", "passphrase"))
+		m.form.help = "enter: add key and connect (empty: connect without adding) | esc: cancel"
+		m.currentMode = modeForm
+		return m, nil
+	}
+	return m.launchSession(host, target, options)
+}
+
+// submitAgentKey adds the key of the host being connected to the agent and
+// connects. An empty passphrase connects without adding the key.
+func (m model) submitAgentKey() (tea.Model, tea.Cmd) {
+	host, ok := m.hostByName(m.form.target)
+	if !ok {
+		m.form.err = fmt.Errorf("%w: %s", parser.ErrHostNotFound, m.form.target)
+		return m, nil
+	}
+	// Passphrases may start or end with spaces, so do not use form.value
+	if passphrase := m.form.fields[0].input.Value(); passphrase != "" {
+		key, ok := m.hostIdentity(host)
+		if !ok {
+			// The config or the key file changed since the form opened
+			if file := m.sshConfig.Effective(host.Patterns()[0])["identityfile"]; file != "" {
+				m.form.err = fmt.Errorf("cannot read IdentityFile %s of %s", file, host.Name)
+			} else {
+				m.form.err = fmt.Errorf("%s has no IdentityFile to add", host.Name)
+			}
+			return m, nil
+		}
+		client, err := sshagent.Dial()
+		if err != nil {
+			m.form.err = err
+			return m, nil
+		}
+		defer client.Close()
+		if err := client.AddKey(key.Path, []byte(passphrase)); err != nil {
+			m.form.err = err
+			m.form.fields[0].input.SetValue("")
+			return m, nil
+		}
+	}
+	return m.launchSession(host, m.launchTarget, m.connectOptions)
+}
+
+func (m model) openAgent() (tea.Model, tea.Cmd) {
+	m.currentMode = modeAgent
+	return m, loadAgent(m.sshConfig)
+}
+
+func (m model) handleAgentKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc", "q", "A":
+		m.currentMode = modeNormal
+	case "r":
+		return m, loadAgent(m.sshConfig)
+	}
+	return m, nil
+}
+
+// renderAgent lists the keys loaded in the agent and, for each, the hosts
+// whose IdentityFile it is.
+func (m model) renderAgent() string {
+	height := m.height - 1
+	dim := lipgloss.NewStyle().Foreground(lipgloss.Color("241"))
+
+	// Hosts by the fingerprint of their IdentityFile key
+	hostsByKey := make(map[string][]string)
+	for _, host := range m.hosts {
+		if key, ok := m.hostIdentity(host); ok {
+			hostsByKey[key.Fingerprint] = append(hostsByKey[key.Fingerprint], host.Name)
+		}
+	}
+
+	var items []string
+	switch {
+	case m.agent.err != nil:
+		items = append(items, lipgloss.NewStyle().Foreground(lipgloss.Color("196")).Render(m.agent.err.Error()))
+	case len(m.agent.identities) == 0:
+		items = append(items, "The agent holds no keys")
+	}
+	for _, identity := range m.agent.identities {
+		items = append(items, fmt.Sprintf("%-10s %s  %s", truncate(shortKeyType(identity.Type), 10), identity.Fingerprint, identity.Comment))
+		if hosts := hostsByKey[identity.Fingerprint]; len(hosts) > 0 {
+			items = append(items, dim.Render(fmt.Sprintf("           used by %s", truncate(strings.Join(hosts, ", "), m.width-20))))
+		}
+	}
+
+	var missing []string
+	for _, host := range m.hosts {
+		if loaded, known := m.keyInAgent(host); known && !loaded {
+			missing = append(missing, host.Name)
+		}
+	}
+	if len(missing) > 0 {
+		items = append(items, "", lipgloss.NewStyle().Bold(true).Render("Hosts whose key is not loaded"))
+		for _, name := range missing {
+			items = append(items, dim.Render(name))
+		}
+	}
+
+	panel := renderPanel(fmt.Sprintf("🗝️  SSH-AGENT (%d)", len(m.agent.identities)), lipgloss.Color("62"), m.width, height, items)
+	helpBar := m.renderHelpBar("r: refresh", "keys are added when connecting to a host", "esc/q: back")
+	return lipgloss.JoinVertical(lipgloss.Left, panel, helpBar)
+}
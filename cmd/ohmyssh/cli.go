@@ -0,0 +1,980 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/pozgo/OhMySSH/pkg/ansible"
+	"github.com/pozgo/OhMySSH/pkg/importer"
+	"github.com/pozgo/OhMySSH/pkg/knownhosts"
+	"github.com/pozgo/OhMySSH/pkg/launch"
+	"github.com/pozgo/OhMySSH/pkg/parser"
+	"github.com/pozgo/OhMySSH/pkg/settings"
+	"github.com/pozgo/OhMySSH/pkg/state"
+	"github.com/pozgo/OhMySSH/pkg/tunnel"
+)
+
+// version is set at build time with -ldflags "-X main.version=...".
+var version = "dev"
+
+// Exit codes of the subcommands. connect exits with the status of ssh.
+const (
+	exitOK      = 0
+	exitFailure = 1
+	exitUsage   = 2
+)
+
+const cliUsage = `Usage: ohmyssh [command] [flags]
+
+Without a command the interactive interface is started.
+
+Commands:
+  list                  list hosts (-output table|json|yaml)
+  show <alias>          show the settings of a host (-output table|json|yaml)
+  connect <alias>       connect to a host; a unique partial name is enough
+                        (-client ssh|mosh|autossh|<name>, -user, -port,
+                        -o Key=Value, -verbose 1-3, -forward-agent:
+                        override settings for this connection; -hold:
+                        wait for enter if ssh cannot connect)
+  add <alias> [flags]   add a host to the SSH config
+  rm <alias>            remove a host from the SSH config
+  check                 check the SSH config for problems
+  export ansible        print hosts as an Ansible inventory (-format ini|yaml)
+  import <format> <file>
+                        add hosts from an Ansible inventory, a Termius/CSV
+                        export, a PuTTY .reg export or a hosts file
+                        (format: ansible, csv, putty, hosts)
+  history               list connections made from ohmyssh
+  discover              list hosts in known_hosts without a Host entry
+  keys                  list SSH keys and IdentityFile problems
+  probe <alias>         show the SSH version and host key of a host
+                        without logging in
+  tunnel list|start|stop [alias...]
+                        show, start or stop the tunnels defined in the
+                        tunnels view, all or those of the given hosts
+  version               print the version
+
+Run "ohmyssh <command> -h" for the flags of a command.
+
+Exit codes: 0 success, 1 failure (e.g. unknown host, problems found),
+2 invalid usage. connect exits with the exit status of ssh.
+`
+
+// runCLI runs a subcommand and returns the process exit code.
+func runCLI(args []string, stdout, stderr io.Writer) int {
+	switch args[0] {
+	case "list":
+		return runList(args[1:], stdout, stderr)
+	case "show":
+		return runShow(args[1:], stdout, stderr)
+	case "connect":
+		return runConnect(args[1:], stdout, stderr)
+	case "add":
+		return runAdd(args[1:], stdout, stderr)
+	case "rm":
+		return runRemove(args[1:], stdout, stderr)
+	case "check":
+		return runCheck(args[1:], stdout, stderr)
+	case "history":
+		return runHistory(args[1:], stdout, stderr)
+	case "discover":
+		return runDiscover(args[1:], stdout, stderr)
+	case "keys":
+		return runKeys(args[1:], stdout, stderr)
+	case "probe":
+		return runProbe(args[1:], stdout, stderr)
+	case "tunnel":
+		return runTunnel(args[1:], stdout, stderr)
+	case "export":
+		return runExport(args[1:], stdout, stderr)
+	case "import":
+		return runImport(args[1:], os.Stdin, stdout, stderr)
+	case "version", "--version":
+		fmt.Fprintf(stdout, "ohmyssh %s\n", version)
+		return exitOK
+	case "help", "-h", "--help":
+		fmt.Fprint(stdout, cliUsage)
+		return exitOK
+	default:
+		fmt.Fprintf(stderr, "Unknown command %q\n\n%s", args[0], cliUsage)
+		return exitUsage
+	}
+}
+
+// newFlagSet creates the flags of a subcommand, including -config for the
+// SSH config to work on.
+func newFlagSet(name string, stderr io.Writer) (*flag.FlagSet, *string) {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	configPath := flags.String("config", parser.NewSSHConfig().Path, "SSH config file")
+	return flags, configPath
+}
+
+// stringList is a flag that may be given several times.
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ", ")
+}
+
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+// parseArgs parses flags that may appear before or after the positional
+// arguments, e.g. "show db1 -config x", and returns the positional ones.
+func parseArgs(flags *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := flags.Parse(args); err != nil {
+			return nil, err
+		}
+		if flags.NArg() == 0 {
+			return positional, nil
+		}
+		positional = append(positional, flags.Arg(0))
+		args = flags.Args()[1:]
+	}
+}
+
+// loadConfig parses the SSH config for a subcommand.
+func loadConfig(path string) (*parser.SSHConfig, error) {
+	config := &parser.SSHConfig{Path: parser.ExpandHome(path)}
+	if err := config.Load(); err != nil {
+		return nil, fmt.Errorf("failed to load SSH config: %v", err)
+	}
+	return config, nil
+}
+
+// findHost looks up a host by exact alias.
+func findHost(config *parser.SSHConfig, alias string) (parser.Host, bool) {
+	for _, host := range config.GetHosts() {
+		if host.Name == alias {
+			return host, true
+		}
+	}
+	return parser.Host{}, false
+}
+
+// matchHosts resolves a possibly partial host name. An exact alias wins,
+// then alias prefixes, then substrings of the alias or hostname and finally
+// aliases containing the query's characters in order. The first kind of
+// match that finds anything decides the result.
+func matchHosts(hosts []parser.Host, query string) []parser.Host {
+	query = strings.ToLower(query)
+	matchers := []func(parser.Host) bool{
+		func(h parser.Host) bool { return strings.ToLower(h.Name) == query },
+		func(h parser.Host) bool { return strings.HasPrefix(strings.ToLower(h.Name), query) },
+		func(h parser.Host) bool {
+			return strings.Contains(strings.ToLower(h.Name), query) || strings.Contains(strings.ToLower(h.Hostname), query)
+		},
+		func(h parser.Host) bool { return isSubsequence(query, strings.ToLower(h.Name)) },
+	}
+	for _, matches := range matchers {
+		var found []parser.Host
+		for _, host := range hosts {
+			if matches(host) {
+				found = append(found, host)
+			}
+		}
+		if len(found) > 0 {
+			return found
+		}
+	}
+	return nil
+}
+
+func isSubsequence(needle, haystack string) bool {
+	for _, r := range haystack {
+		if len(needle) == 0 {
+			break
+		}
+		if strings.HasPrefix(needle, string(r)) {
+			needle = needle[len(string(r)):]
+		}
+	}
+	return len(needle) == 0
+}
+
+func runList(args []string, stdout, stderr io.Writer) int {
+	flags, configPath := newFlagSet("list", stderr)
+	group := flags.String("group", "", "only list hosts in this group")
+	tag := flags.String("tag", "", "only list hosts with this tag")
+	output := outputFlag(flags)
+	if _, err := parseArgs(flags, args); err != nil {
+		return exitUsage
+	}
+	if !validOutput(*output, stderr) {
+		return exitUsage
+	}
+
+	config, err := loadConfig(*configPath)
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return exitFailure
+	}
+
+	hosts := filterHosts(config.GetHosts(), *group, *tag)
+	if *output != "table" {
+		return writeInventory(stdout, stderr, config, hosts, *output)
+	}
+
+	w := tabwriter.NewWriter(stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ALIAS\tHOSTNAME\tUSER\tPORT\tGROUP\tTAGS")
+	for _, host := range hosts {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", host.Name, host.Hostname, host.User, host.Port,
+			host.Meta.Group, strings.Join(host.Meta.Tags, ","))
+	}
+	return flushOrFail(w, stderr)
+}
+
+// filterHosts keeps the hosts in group and with tag; empty values match all.
+func filterHosts(hosts []parser.Host, group, tag string) []parser.Host {
+	var filtered []parser.Host
+	for _, host := range hosts {
+		if group != "" && !inGroup(host.Meta.Group, group) {
+			continue
+		}
+		if tag != "" && !hasTag(host, tag) {
+			continue
+		}
+		filtered = append(filtered, host)
+	}
+	return filtered
+}
+
+func runShow(args []string, stdout, stderr io.Writer) int {
+	flags, configPath := newFlagSet("show", stderr)
+	output := outputFlag(flags)
+	positional, err := parseArgs(flags, args)
+	if err != nil {
+		return exitUsage
+	}
+	if len(positional) != 1 {
+		fmt.Fprintln(stderr, "Usage: ohmyssh show <alias> [-output table|json|yaml]")
+		return exitUsage
+	}
+	if !validOutput(*output, stderr) {
+		return exitUsage
+	}
+
+	config, err := loadConfig(*configPath)
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return exitFailure
+	}
+	host, ok := findHost(config, positional[0])
+	if !ok {
+		fmt.Fprintf(stderr, "Error: %v: %s\n", parser.ErrHostNotFound, positional[0])
+		return exitFailure
+	}
+	if *output != "table" {
+		return writeInventory(stdout, stderr, config, []parser.Host{host}, *output)
+	}
+
+	w := tabwriter.NewWriter(stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "Host:\t%s\n", host.Name)
+	for _, field := range []struct{ key, value string }{
+		{"HostName", host.Hostname},
+		{"User", host.User},
+		{"Port", host.Port},
+	} {
+		if field.value != "" {
+			fmt.Fprintf(w, "%s:\t%s\n", field.key, field.value)
+		}
+	}
+	keys := make([]string, 0, len(host.Options))
+	for key := range host.Options {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		fmt.Fprintf(w, "%s:\t%s\n", parser.CanonicalKey(key), host.Options[key])
+	}
+	if host.Meta.Group != "" {
+		fmt.Fprintf(w, "Group:\t%s\n", host.Meta.Group)
+	}
+	if len(host.Meta.Tags) > 0 {
+		fmt.Fprintf(w, "Tags:\t%s\n", strings.Join(host.Meta.Tags, ", "))
+	}
+	if host.Meta.Owner != "" {
+		fmt.Fprintf(w, "Owner:\t%s\n", host.Meta.Owner)
+	}
+	for _, note := range strings.Split(host.Meta.Notes, "\n") {
+		if note != "" {
+			fmt.Fprintf(w, "Note:\t%s\n", note)
+		}
+	}
+	fmt.Fprintf(w, "Source:\t%s:%d\n", host.Source, host.Line)
+	return flushOrFail(w, stderr)
+}
+
+// outputFlag adds the -output flag of commands that can print data.
+func outputFlag(flags *flag.FlagSet) *string {
+	return flags.String("output", "table", "output format: table, json or yaml")
+}
+
+func validOutput(output string, stderr io.Writer) bool {
+	switch output {
+	case "table", "json", "yaml":
+		return true
+	}
+	fmt.Fprintf(stderr, "Error: unsupported output format %q, use table, json or yaml\n", output)
+	return false
+}
+
+// writeInventory prints hosts in the versioned inventory schema. show uses it
+// too, with a single host, so scripts can parse both the same way.
+func writeInventory(stdout, stderr io.Writer, config *parser.SSHConfig, hosts []parser.Host, format string) int {
+	data, err := config.Inventory(hosts).Marshal(format)
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return exitFailure
+	}
+	stdout.Write(data)
+	return exitOK
+}
+
+func runConnect(args []string, stdout, stderr io.Writer) int {
+	flags, configPath := newFlagSet("connect", stderr)
+	hold := flags.Bool("hold", false, "wait for enter when the connection fails, so a new window stays open")
+	var options launch.Options
+	flags.StringVar(&options.Client, "client", "", "connect with ssh, mosh, autossh or a client from the settings")
+	flags.StringVar(&options.User, "user", "", "log in as this user instead of the configured one")
+	flags.StringVar(&options.Port, "port", "", "connect to this port instead of the configured one")
+	flags.Var((*stringList)(&options.Options), "o", "extra ssh option as Key=Value, may be repeated")
+	flags.IntVar(&options.Verbose, "verbose", 0, "ssh verbosity, 1-3 for -v to -vvv")
+	flags.BoolVar(&options.ForwardAgent, "forward-agent", false, "forward the ssh-agent connection (-A)")
+	positional, err := parseArgs(flags, args)
+	if err != nil {
+		return exitUsage
+	}
+	if err := options.Validate(); err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return exitUsage
+	}
+	if len(positional) != 1 {
+		fmt.Fprintln(stderr, "Usage: ohmyssh connect <alias>")
+		return exitUsage
+	}
+
+	config, err := loadConfig(*configPath)
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return exitFailure
+	}
+	matches := matchHosts(config.GetHosts(), positional[0])
+	switch len(matches) {
+	case 0:
+		fmt.Fprintf(stderr, "Error: %v: %s\n", parser.ErrHostNotFound, positional[0])
+		return exitFailure
+	case 1:
+	default:
+		fmt.Fprintf(stderr, "Error: %q matches several hosts:\n", positional[0])
+		for _, host := range matches {
+			fmt.Fprintf(stderr, "  %s\n", host.Name)
+		}
+		return exitFailure
+	}
+
+	appSettings, err := settings.Load(settings.DefaultPath())
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return exitFailure
+	}
+	command, err := clientCommand(config, appSettings, matches[0], options)
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return exitFailure
+	}
+	err = connectToServer(config, matches[0], options, command)
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		if *hold && exitErr.ExitCode() == 255 {
+			holdOn(stderr)
+		}
+		return exitErr.ExitCode()
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "SSH connection failed: %v\n", err)
+		if *hold {
+			holdOn(stderr)
+		}
+		return exitFailure
+	}
+	return exitOK
+}
+
+// holdOn waits for enter, so that an error stays readable in a window that
+// closes when ohmyssh exits.
+func holdOn(stderr io.Writer) {
+	fmt.Fprint(stderr, "Press enter to close...")
+	bufio.NewReader(os.Stdin).ReadString('\n')
+}
+
+func runAdd(args []string, stdout, stderr io.Writer) int {
+	flags, configPath := newFlagSet("add", stderr)
+	hostname := flags.String("hostname", "", "HostName of the new host")
+	user := flags.String("user", "", "User to log in as")
+	port := flags.String("port", "", "Port to connect to")
+	identity := flags.String("identity", "", "IdentityFile to use")
+	proxyJump := flags.String("proxyjump", "", "ProxyJump hosts")
+	group := flags.String("group", "", "ohmyssh group, e.g. prod/db")
+	tags := flags.String("tags", "", "comma-separated ohmyssh tags")
+	positional, err := parseArgs(flags, args)
+	if err != nil {
+		return exitUsage
+	}
+	if len(positional) != 1 {
+		fmt.Fprintln(stderr, "Usage: ohmyssh add <alias> [-hostname h] [-user u] [-port p] [-identity f] [-proxyjump j] [-group g] [-tags a,b]")
+		return exitUsage
+	}
+	alias := positional[0]
+	if *port != "" {
+		if n, convErr := strconv.Atoi(*port); convErr != nil || n < 1 || n > 65535 {
+			fmt.Fprintf(stderr, "Error: invalid port %q\n", *port)
+			return exitUsage
+		}
+	}
+
+	path := parser.ExpandHome(*configPath)
+	before, err := ioutil.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		fmt.Fprintf(stderr, "Error: failed to read config: %v\n", err)
+		return exitFailure
+	}
+	if err == nil {
+		// The alias must be unused in included files too
+		config, loadErr := loadConfig(path)
+		if loadErr != nil {
+			fmt.Fprintf(stderr, "Error: %v\n", loadErr)
+			return exitFailure
+		}
+		if _, exists := findHost(config, alias); exists {
+			fmt.Fprintf(stderr, "Error: %v: %s\n", parser.ErrHostExists, alias)
+			return exitFailure
+		}
+	}
+
+	content, err := parser.AddHost(string(before), alias, []parser.Directive{
+		{Key: "HostName", Value: *hostname},
+		{Key: "User", Value: *user},
+		{Key: "Port", Value: *port},
+		{Key: "IdentityFile", Value: *identity},
+		{Key: "ProxyJump", Value: *proxyJump},
+	})
+	if err == nil && (*group != "" || *tags != "") {
+		meta := parser.Meta{Group: *group}
+		for _, tag := range strings.Split(*tags, ",") {
+			if tag = strings.TrimSpace(tag); tag != "" {
+				meta.Tags = append(meta.Tags, tag)
+			}
+		}
+		content, err = parser.SetHostMeta(content, alias, meta)
+	}
+	if err == nil {
+		err = writeOrCreateConfig(path, content)
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return exitFailure
+	}
+	fmt.Fprintf(stdout, "Added %s to %s\n", alias, path)
+	return exitOK
+}
+
+// writeOrCreateConfig writes the config like the TUI does, or creates it with
+// private permissions if it does not exist yet.
+func writeOrCreateConfig(path, content string) error {
+	if _, err := os.Stat(path); err == nil {
+		return writeConfigFile(path, content)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("failed to create config directory: %v", err)
+	}
+	if err := ioutil.WriteFile(path, []byte(content), 0600); err != nil {
+		return fmt.Errorf("failed to save config: %v", err)
+	}
+	return nil
+}
+
+func runRemove(args []string, stdout, stderr io.Writer) int {
+	flags, configPath := newFlagSet("rm", stderr)
+	positional, err := parseArgs(flags, args)
+	if err != nil {
+		return exitUsage
+	}
+	if len(positional) != 1 {
+		fmt.Fprintln(stderr, "Usage: ohmyssh rm <alias>")
+		return exitUsage
+	}
+
+	config, err := loadConfig(*configPath)
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return exitFailure
+	}
+	host, ok := findHost(config, positional[0])
+	if !ok {
+		fmt.Fprintf(stderr, "Error: %v: %s\n", parser.ErrHostNotFound, positional[0])
+		return exitFailure
+	}
+
+	// Delete from the file that defines the host, which may be an Include
+	before, err := ioutil.ReadFile(host.Source)
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: failed to read config: %v\n", err)
+		return exitFailure
+	}
+	content, err := parser.DeleteHost(string(before), host.Name)
+	if err == nil {
+		err = writeConfigFile(host.Source, content)
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return exitFailure
+	}
+	fmt.Fprintf(stdout, "Removed %s from %s\n", host.Name, host.Source)
+	return exitOK
+}
+
+func runCheck(args []string, stdout, stderr io.Writer) int {
+	flags, configPath := newFlagSet("check", stderr)
+	strict := flags.Bool("strict", false, "fail on warnings too")
+	if _, err := parseArgs(flags, args); err != nil {
+		return exitUsage
+	}
+
+	config, err := loadConfig(*configPath)
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return exitFailure
+	}
+
+	problems := config.Check()
+	for _, problem := range problems {
+		fmt.Fprintln(stdout, problem)
+	}
+	if len(problems) == 0 {
+		fmt.Fprintf(stdout, "%s: %d hosts, no problems found\n", config.Path, len(config.GetHosts()))
+	}
+	if parser.HasErrors(problems) || (*strict && len(problems) > 0) {
+		return exitFailure
+	}
+	return exitOK
+}
+
+func runExport(args []string, stdout, stderr io.Writer) int {
+	flags, configPath := newFlagSet("export", stderr)
+	format := flags.String("format", "ini", "inventory format: ini or yaml")
+	group := flags.String("group", "", "only export hosts in this group")
+	tag := flags.String("tag", "", "only export hosts with this tag")
+	positional, err := parseArgs(flags, args)
+	if err != nil {
+		return exitUsage
+	}
+	if len(positional) != 1 || positional[0] != "ansible" {
+		fmt.Fprintln(stderr, "Usage: ohmyssh export ansible [-format ini|yaml] [-group g] [-tag t]")
+		return exitUsage
+	}
+	if *format != "ini" && *format != "yaml" {
+		fmt.Fprintf(stderr, "Error: unsupported inventory format %q, use ini or yaml\n", *format)
+		return exitUsage
+	}
+
+	config, err := loadConfig(*configPath)
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return exitFailure
+	}
+	inventory := config.Inventory(filterHosts(config.GetHosts(), *group, *tag))
+	data, err := ansible.Export(inventory.Hosts, *format)
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return exitFailure
+	}
+	stdout.Write(data)
+	return exitOK
+}
+
+// runImport previews the Host blocks an import would add, reports aliases
+// that conflict with the config and asks before writing, unless -yes or
+// -dry-run is given.
+func runImport(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	flags, configPath := newFlagSet("import", stderr)
+	group := flags.String("group", "", "put imported hosts below this ohmyssh group")
+	dryRun := flags.Bool("dry-run", false, "only show what would be added")
+	yes := flags.Bool("yes", false, "add the hosts without asking")
+	positional, err := parseArgs(flags, args)
+	if err != nil {
+		return exitUsage
+	}
+	if len(positional) != 2 {
+		fmt.Fprintf(stderr, "Usage: ohmyssh import <%s> <file> [-group g] [-dry-run] [-yes]\n", strings.Join(importer.Formats, "|"))
+		return exitUsage
+	}
+	if !importer.ValidFormat(positional[0]) {
+		fmt.Fprintf(stderr, "Error: unsupported import format %q, use %s\n", positional[0], strings.Join(importer.Formats, ", "))
+		return exitUsage
+	}
+
+	data, err := ioutil.ReadFile(parser.ExpandHome(positional[1]))
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: failed to read %s: %v\n", positional[1], err)
+		return exitFailure
+	}
+	entries, err := importer.Parse(positional[0], data)
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return exitFailure
+	}
+	importer.SetGroup(entries, *group)
+
+	path := parser.ExpandHome(*configPath)
+	before, err := ioutil.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		fmt.Fprintf(stderr, "Error: failed to read config: %v\n", err)
+		return exitFailure
+	}
+	var existing []parser.Host
+	if err == nil {
+		// Aliases from included files count as taken too
+		config, loadErr := loadConfig(path)
+		if loadErr != nil {
+			fmt.Fprintf(stderr, "Error: %v\n", loadErr)
+			return exitFailure
+		}
+		existing = config.GetHosts()
+	}
+
+	plan := importer.NewPlan(entries, existing)
+	preview, err := plan.Preview()
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return exitFailure
+	}
+	if preview != "" {
+		fmt.Fprintln(stdout, preview)
+	}
+	for _, conflict := range plan.Conflicts {
+		alias := conflict.Entry.Alias
+		if source := conflict.Entry.Source; source != "" && source != alias {
+			alias += " (" + source + ")"
+		}
+		fmt.Fprintf(stdout, "Skipping %s: %s\n", alias, conflict.Reason)
+	}
+	if len(plan.Add) == 0 {
+		fmt.Fprintf(stdout, "Nothing to import from %s\n", positional[1])
+		return exitOK
+	}
+	if *dryRun {
+		fmt.Fprintf(stdout, "Would add %d hosts to %s\n", len(plan.Add), path)
+		return exitOK
+	}
+	if !*yes {
+		fmt.Fprintf(stdout, "Add %d hosts to %s? [y/N] ", len(plan.Add), path)
+		answer, _ := bufio.NewReader(stdin).ReadString('\n')
+		if answer = strings.ToLower(strings.TrimSpace(answer)); answer != "y" && answer != "yes" {
+			fmt.Fprintln(stdout, "Import cancelled")
+			return exitFailure
+		}
+	}
+
+	content, err := plan.Apply(string(before))
+	if err == nil {
+		err = writeOrCreateConfig(path, content)
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return exitFailure
+	}
+	fmt.Fprintf(stdout, "Added %d hosts to %s\n", len(plan.Add), path)
+	return exitOK
+}
+
+func runHistory(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("history", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	host := flags.String("host", "", "only show connections to this alias")
+	limit := flags.Int("n", 20, "number of connections to show, 0 for all")
+	stats := flags.Bool("stats", false, "show per-host statistics instead of single connections")
+	if _, err := parseArgs(flags, args); err != nil {
+		return exitUsage
+	}
+
+	connections, err := state.ReadHistory(state.HistoryPath())
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return exitFailure
+	}
+	if *host != "" {
+		var matching []state.Connection
+		for _, c := range connections {
+			if c.Alias == *host {
+				matching = append(matching, c)
+			}
+		}
+		connections = matching
+	}
+
+	w := tabwriter.NewWriter(stdout, 0, 0, 2, ' ', 0)
+	if *stats {
+		fmt.Fprintln(w, "HOST\tHOSTNAME\tCONNECTIONS\tFAILURES\tFAILURE RATE\tLAST CONNECTED\tTOTAL TIME")
+		for _, s := range state.Summarize(connections) {
+			fmt.Fprintf(w, "%s\t%s\t%d\t%d\t%.0f%%\t%s\t%s\n", s.Alias, s.Hostname, s.Connections, s.Failures,
+				s.FailureRate()*100, s.LastConnected.Local().Format("2006-01-02 15:04"), formatDuration(s.TotalTime))
+		}
+		return flushOrFail(w, stderr)
+	}
+
+	// Newest first
+	fmt.Fprintln(w, "START\tDURATION\tHOST\tHOSTNAME\tSTATUS")
+	for i, shown := len(connections)-1, 0; i >= 0 && (*limit <= 0 || shown < *limit); i, shown = i-1, shown+1 {
+		c := connections[i]
+		status := fmt.Sprintf("exit %d", c.ExitCode)
+		if c.Error != "" {
+			status = "error: " + c.Error
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", c.Start.Local().Format("2006-01-02 15:04:05"),
+			formatDuration(c.Duration()), c.Alias, c.Hostname, status)
+	}
+	return flushOrFail(w, stderr)
+}
+
+func runDiscover(args []string, stdout, stderr io.Writer) int {
+	flags, configPath := newFlagSet("discover", stderr)
+	knownHostsPath := flags.String("known-hosts", knownhosts.DefaultPath(), "known_hosts file to read")
+	if _, err := parseArgs(flags, args); err != nil {
+		return exitUsage
+	}
+
+	config, err := loadConfig(*configPath)
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return exitFailure
+	}
+	discovery, err := discoverHosts(config, parser.ExpandHome(*knownHostsPath))
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return exitFailure
+	}
+
+	w := tabwriter.NewWriter(stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "HOST\tPORT\tALSO KNOWN AS\tKEY TYPES\tLINE")
+	for _, c := range discovery.Candidates {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%d\n", c.Host, c.Port, strings.Join(c.Names, ","),
+			strings.Join(c.KeyTypes, ","), c.Line)
+	}
+	if code := flushOrFail(w, stderr); code != exitOK {
+		return code
+	}
+	if discovery.Hashed > 0 {
+		fmt.Fprintf(stderr, "%d hashed entries cannot be listed\n", discovery.Hashed)
+	}
+	return exitOK
+}
+
+// runKeys lists the keys in ~/.ssh and those referenced by IdentityFile. It
+// fails when an IdentityFile is missing or has unsafe permissions.
+func runKeys(args []string, stdout, stderr io.Writer) int {
+	flags, configPath := newFlagSet("keys", stderr)
+	dir := flags.String("dir", sshDir(), "directory to scan for keys")
+	if _, err := parseArgs(flags, args); err != nil {
+		return exitUsage
+	}
+
+	config, err := loadConfig(*configPath)
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return exitFailure
+	}
+	report, err := buildKeyReport(config, parser.ExpandHome(*dir))
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return exitFailure
+	}
+
+	w := tabwriter.NewWriter(stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "KEY\tTYPE\tBITS\tFINGERPRINT\tPASSPHRASE\tCOMMENT\tHOSTS")
+	for _, key := range report.keys {
+		passphrase := "no"
+		if key.Encrypted {
+			passphrase = "yes"
+		} else if key.Path == "" {
+			passphrase = "-"
+		}
+		name := key.Path
+		if name == "" {
+			name = key.PublicPath
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", name, key.Type, bitsLabel(key.Bits), key.Fingerprint,
+			passphrase, key.Comment, strings.Join(report.keyHosts(key), ","))
+	}
+	if code := flushOrFail(w, stderr); code != exitOK {
+		return code
+	}
+
+	for _, key := range report.keys {
+		if key.UnsafePermissions() {
+			fmt.Fprintf(stdout, "warning: %s: permissions %04o are too open\n", key.Path, key.Mode)
+		}
+	}
+	for _, problem := range report.problems {
+		fmt.Fprintf(stdout, "error: %s: IdentityFile %s: %v\n", problem.host, problem.path, problem.err)
+	}
+	if len(report.problems) > 0 {
+		return exitFailure
+	}
+	return exitOK
+}
+
+// runProbe runs the SSH handshake with a host and compares its host key with
+// known_hosts. It fails when the host cannot be probed or its key changed.
+func runProbe(args []string, stdout, stderr io.Writer) int {
+	flags, configPath := newFlagSet("probe", stderr)
+	positional, err := parseArgs(flags, args)
+	if err != nil {
+		return exitUsage
+	}
+	if len(positional) != 1 {
+		fmt.Fprintln(stderr, "Usage: ohmyssh probe <alias>")
+		return exitUsage
+	}
+
+	config, err := loadConfig(*configPath)
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return exitFailure
+	}
+	host, ok := findHost(config, positional[0])
+	if !ok {
+		fmt.Fprintf(stderr, "Error: %v: %s\n", parser.ErrHostNotFound, positional[0])
+		return exitFailure
+	}
+	result := inspectHostKey(config, host)
+	if result.err != nil {
+		fmt.Fprintf(stderr, "Error: %s: %v\n", host.Name, result.err)
+		return exitFailure
+	}
+
+	w := tabwriter.NewWriter(stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "Address:\t%s\n", result.handshake.Address)
+	fmt.Fprintf(w, "Version:\t%s\n", result.handshake.Version)
+	fmt.Fprintf(w, "Host key:\t%s %s\n", result.handshake.KeyType, result.handshake.Fingerprint)
+	fmt.Fprintf(w, "known_hosts:\t%s\n", result.check.Status)
+	if result.check.Status == knownhosts.KeyChanged {
+		fmt.Fprintf(w, "Stored key:\t%s\n", result.check.Stored)
+	}
+	fmt.Fprintf(w, "Host key algorithms:\t%s\n", strings.Join(result.handshake.HostKeyAlgorithms, ","))
+	if code := flushOrFail(w, stderr); code != exitOK {
+		return code
+	}
+	if result.check.Status == knownhosts.KeyChanged || result.check.Status == knownhosts.KeyRevoked {
+		return exitFailure
+	}
+	return exitOK
+}
+
+// runTunnel lists, starts or stops the tunnels saved in the state. Tunnels
+// are started in the background like in the TUI, so the command returns once
+// they are up.
+func runTunnel(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("tunnel", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	positional, err := parseArgs(flags, args)
+	if err != nil {
+		return exitUsage
+	}
+	if len(positional) == 0 || (positional[0] != "list" && positional[0] != "start" && positional[0] != "stop") {
+		fmt.Fprintln(stderr, "Usage: ohmyssh tunnel list|start|stop [alias...]")
+		return exitUsage
+	}
+
+	appState, err := state.Load(state.DefaultPath())
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return exitFailure
+	}
+	hosts := make(map[string]bool)
+	for _, alias := range positional[1:] {
+		hosts[alias] = true
+	}
+	var tunnels []tunnel.Tunnel
+	for _, t := range savedTunnels(appState) {
+		if len(hosts) == 0 || hosts[t.Host] {
+			tunnels = append(tunnels, t)
+			delete(hosts, t.Host)
+		}
+	}
+	for alias := range hosts {
+		fmt.Fprintf(stderr, "Error: no tunnels for %s\n", alias)
+		return exitFailure
+	}
+
+	supervisor := tunnelSupervisor()
+	if positional[0] == "list" {
+		w := tabwriter.NewWriter(stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "HOST\tTYPE\tFORWARD\tSTATUS\tPID\tLISTENING ON")
+		for _, t := range tunnels {
+			status := supervisor.Status(t)
+			label, pid := "stopped", "-"
+			switch {
+			case status.Running:
+				label, pid = "running", strconv.Itoa(status.PID)
+			case status.PortInUse:
+				label = "port taken"
+			}
+			listen := t.ListenAddress()
+			if listen == "" {
+				listen = t.Host
+			}
+			fmt.Fprintf(w, "%s\t-%s\t%s\t%s\t%s\t%s\n", t.Host, t.Kind, t.Spec, label, pid, listen)
+		}
+		return flushOrFail(w, stderr)
+	}
+
+	code := exitOK
+	for _, t := range tunnels {
+		if positional[0] == "stop" {
+			if err := supervisor.Stop(t); err != nil {
+				fmt.Fprintf(stderr, "Error: %s: %v\n", t.ID(), err)
+				code = exitFailure
+				continue
+			}
+			fmt.Fprintf(stdout, "Stopped %s\n", t.ID())
+			continue
+		}
+		if supervisor.Status(t).Running {
+			fmt.Fprintf(stdout, "Already running %s\n", t.ID())
+			continue
+		}
+		pid, err := supervisor.Start(t)
+		if err != nil {
+			fmt.Fprintf(stderr, "Error: %s: %v\n", t.ID(), err)
+			code = exitFailure
+			continue
+		}
+		fmt.Fprintf(stdout, "Started %s (pid %d)\n", t.ID(), pid)
+	}
+	return code
+}
+
+func flushOrFail(w *tabwriter.Writer, stderr io.Writer) int {
+	if err := w.Flush(); err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return exitFailure
+	}
+	return exitOK
+}
@@ -0,0 +1,341 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/pozgo/OhMySSH/pkg/launch"
+	"github.com/pozgo/OhMySSH/pkg/parser"
+	"github.com/pozgo/OhMySSH/pkg/settings"
+)
+
+const cliFixture = `# ohmyssh: group=prod/db tags=postgres
+Host db1
+    HostName db1.example.com
+    User postgres
+
+# ohmyssh: group=prod/web
+Host web1
+    HostName web1.example.com
+
+# ohmyssh: group=prod/web
+Host web2
+    HostName web2.example.com
+    Port 2222
+
+Host *
+    ServerAliveInterval 60
+`
+
+// writeCLIConfig writes content as an SSH config in a temporary home, so
+// that nothing the commands write ends up in the real one.
+func writeCLIConfig(t *testing.T, content string) string {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_STATE_HOME", filepath.Join(home, "state"))
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, "config"))
+	path := filepath.Join(home, ".ssh", "config")
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		t.Fatalf("Failed to create .ssh: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	return path
+}
+
+// run runs the CLI and returns its exit code, stdout and stderr.
+func run(args ...string) (int, string, string) {
+	var stdout, stderr bytes.Buffer
+	code := runCLI(args, &stdout, &stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+func TestCLIUnknownCommand(t *testing.T) {
+	code, stdout, stderr := run("frobnicate")
+	if code != exitUsage || stdout != "" || !strings.Contains(stderr, `Unknown command "frobnicate"`) {
+		t.Errorf("Unexpected result %d, %q, %q", code, stdout, stderr)
+	}
+}
+
+func TestCLIList(t *testing.T) {
+	path := writeCLIConfig(t, cliFixture)
+
+	code, stdout, stderr := run("list", "-config", path)
+	if code != exitOK || stderr != "" {
+		t.Fatalf("list failed with %d: %s", code, stderr)
+	}
+	for _, want := range []string{"ALIAS", "db1.example.com", "web2", "2222", "prod/web"} {
+		if !strings.Contains(stdout, want) {
+			t.Errorf("Expected %q in the list:\n%s", want, stdout)
+		}
+	}
+
+	code, stdout, _ = run("list", "-group", "prod/web", "-output", "json", "-config", path)
+	var inventory parser.Inventory
+	if err := json.Unmarshal([]byte(stdout), &inventory); code != exitOK || err != nil {
+		t.Fatalf("Expected JSON, got %d, %v:\n%s", code, err, stdout)
+	}
+	var aliases []string
+	for _, host := range inventory.Hosts {
+		aliases = append(aliases, host.Alias)
+	}
+	if !reflect.DeepEqual(aliases, []string{"web1", "web2"}) {
+		t.Errorf("Expected the prod/web hosts, got %v", aliases)
+	}
+
+	if code, stdout, stderr := run("list", "-output", "xml", "-config", path); code != exitUsage || stdout != "" || !strings.Contains(stderr, "unsupported output format") {
+		t.Errorf("Unexpected result for -output xml: %d, %q, %q", code, stdout, stderr)
+	}
+	if code, _, stderr := run("list", "-config", filepath.Join(t.TempDir(), "missing")); code != exitFailure || !strings.Contains(stderr, "failed to load SSH config") {
+		t.Errorf("Unexpected result for a missing config: %d, %q", code, stderr)
+	}
+}
+
+func TestCLIShow(t *testing.T) {
+	path := writeCLIConfig(t, cliFixture)
+
+	// Flags may follow the alias
+	code, stdout, stderr := run("show", "db1", "-config", path)
+	if code != exitOK || stderr != "" {
+		t.Fatalf("show failed with %d: %s", code, stderr)
+	}
+	for _, want := range []string{"HostName:  db1.example.com", "User:      postgres", "Group:     prod/db", "Source:    " + path + ":2"} {
+		if !strings.Contains(stdout, want) {
+			t.Errorf("Expected %q in:\n%s", want, stdout)
+		}
+	}
+
+	code, stdout, _ = run("show", "web2", "-output", "yaml", "-config", path)
+	if code != exitOK || !strings.Contains(stdout, "schema_version: 1") || !strings.Contains(stdout, "port: 2222") {
+		t.Errorf("Unexpected YAML with %d:\n%s", code, stdout)
+	}
+
+	// show wants the exact alias, no partial match
+	if code, stdout, stderr := run("show", "db", "-config", path); code != exitFailure || stdout != "" || !strings.Contains(stderr, "host not found: db") {
+		t.Errorf("Unexpected result for an unknown host: %d, %q, %q", code, stdout, stderr)
+	}
+	if code, _, stderr := run("show", "-config", path); code != exitUsage || !strings.Contains(stderr, "Usage: ohmyssh show") {
+		t.Errorf("Unexpected result without an alias: %d, %q", code, stderr)
+	}
+	if code, _, _ := run("show", "db1", "-nope"); code != exitUsage {
+		t.Errorf("Expected exit code %d for an unknown flag, got %d", exitUsage, code)
+	}
+}
+
+func TestCLIAddRemove(t *testing.T) {
+	path := writeCLIConfig(t, cliFixture)
+
+	code, stdout, stderr := run("add", "web3", "-hostname", "10.0.0.3", "-user", "deploy", "-config", path, "-group", "prod/web", "-tags", "nginx, tls")
+	if code != exitOK || !strings.Contains(stdout, "Added web3 to "+path) {
+		t.Fatalf("add failed with %d: %s%s", code, stdout, stderr)
+	}
+	data, _ := os.ReadFile(path)
+	want := "# ohmyssh: group=prod/web tags=nginx,tls\nHost web3\n    HostName 10.0.0.3\n    User deploy\n\nHost *\n"
+	if !strings.Contains(string(data), want) {
+		t.Errorf("Expected web3 before Host *, got:\n%s", data)
+	}
+
+	if code, _, stderr := run("add", "web3", "-config", path); code != exitFailure || !strings.Contains(stderr, "host already exists: web3") {
+		t.Errorf("Unexpected result for an existing alias: %d, %q", code, stderr)
+	}
+	if code, _, stderr := run("add", "web4", "-port", "0", "-config", path); code != exitUsage || !strings.Contains(stderr, `invalid port "0"`) {
+		t.Errorf("Unexpected result for port 0: %d, %q", code, stderr)
+	}
+	if code, _, stderr := run("add", "web4", "-hostname", "web4\nProxyCommand evil", "-config", path); code != exitFailure || !strings.Contains(stderr, "control characters") {
+		t.Errorf("Unexpected result for a newline in a value: %d, %q", code, stderr)
+	}
+	if code, _, _ := run("add", "-config", path); code != exitUsage {
+		t.Errorf("Expected exit code %d without an alias, got %d", exitUsage, code)
+	}
+
+	code, stdout, _ = run("rm", "web3", "-config", path)
+	if code != exitOK || !strings.Contains(stdout, "Removed web3 from "+path) {
+		t.Fatalf("rm failed with %d: %s", code, stdout)
+	}
+	if data, _ := os.ReadFile(path); string(data) != cliFixture {
+		t.Errorf("Expected the original config back, got:\n%s", data)
+	}
+	if code, _, stderr := run("rm", "web3", "-config", path); code != exitFailure || !strings.Contains(stderr, "host not found: web3") {
+		t.Errorf("Unexpected result for a removed host: %d, %q", code, stderr)
+	}
+}
+
+func TestCLIAddCreatesConfig(t *testing.T) {
+	writeCLIConfig(t, "")
+	path := filepath.Join(t.TempDir(), "new", "config")
+	if code, _, stderr := run("add", "db1", "-hostname", "db1.example.com", "-config", path); code != exitOK {
+		t.Fatalf("add failed with %d: %s", code, stderr)
+	}
+	info, err := os.Stat(path)
+	if err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("Expected a private config to be created, got %v, %v", info, err)
+	}
+}
+
+func TestCLICheck(t *testing.T) {
+	path := writeCLIConfig(t, cliFixture)
+	code, stdout, _ := run("check", "-config", path)
+	if code != exitOK || !strings.Contains(stdout, path+": 4 hosts, no problems found") {
+		t.Errorf("Unexpected result for a clean config: %d, %q", code, stdout)
+	}
+
+	// Host * above other hosts is only a warning
+	warnings := writeCLIConfig(t, "Host *\n    User root\n\nHost db1\n    HostName db1.example.com\n")
+	if code, stdout, _ := run("check", "-config", warnings); code != exitOK || !strings.Contains(stdout, "warning: *: options in Host *") {
+		t.Errorf("Unexpected result for warnings: %d, %q", code, stdout)
+	}
+	if code, _, _ := run("check", "-strict", "-config", warnings); code != exitFailure {
+		t.Errorf("Expected -strict to fail on warnings, got %d", code)
+	}
+
+	errors := writeCLIConfig(t, "Host db1\n    Port abc\n")
+	if code, stdout, _ := run("check", "-config", errors); code != exitFailure || !strings.Contains(stdout, `error: db1: invalid port "abc"`) {
+		t.Errorf("Unexpected result for errors: %d, %q", code, stdout)
+	}
+}
+
+func TestCLIExport(t *testing.T) {
+	path := writeCLIConfig(t, cliFixture)
+	code, stdout, stderr := run("export", "ansible", "-config", path)
+	if code != exitOK || stderr != "" {
+		t.Fatalf("export failed with %d: %s", code, stderr)
+	}
+	for _, want := range []string{"[prod_web]", "web2 ansible_host=web2.example.com ansible_port=2222"} {
+		if !strings.Contains(stdout, want) {
+			t.Errorf("Expected %q in:\n%s", want, stdout)
+		}
+	}
+	if strings.Contains(stdout, "*") {
+		t.Errorf("Expected wildcard blocks to be skipped:\n%s", stdout)
+	}
+
+	if code, _, _ := run("export", "ansible", "-format", "yaml", "-tag", "postgres", "-config", path); code != exitOK {
+		t.Errorf("Expected YAML export to work, got %d", code)
+	}
+	if code, _, stderr := run("export", "ansible", "-format", "xml", "-config", path); code != exitUsage || !strings.Contains(stderr, "unsupported inventory format") {
+		t.Errorf("Unexpected result for -format xml: %d, %q", code, stderr)
+	}
+	if code, _, _ := run("export", "puppet", "-config", path); code != exitUsage {
+		t.Errorf("Expected exit code %d for an unknown target, got %d", exitUsage, code)
+	}
+}
+
+func TestCLIImport(t *testing.T) {
+	path := writeCLIConfig(t, cliFixture)
+	csv := filepath.Join(t.TempDir(), "hosts.csv")
+	data := "Label,Hostname,Port,Username\nbuild,build.example.com,22,ci\nweb1,10.0.0.9,22,\nbroken,broken.example.com,abc,\n"
+	if err := os.WriteFile(csv, []byte(data), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	code, stdout, _ := run("import", "csv", csv, "-dry-run", "-config", path)
+	if code != exitOK || !strings.Contains(stdout, "Host build\n    HostName build.example.com\n    User ci") {
+		t.Fatalf("Unexpected preview with %d:\n%s", code, stdout)
+	}
+	for _, want := range []string{"Skipping web1 (row 3): already defined in " + path + ":7", `Skipping broken (row 4): invalid port "abc"`, "Would add 1 hosts"} {
+		if !strings.Contains(stdout, want) {
+			t.Errorf("Expected %q in:\n%s", want, stdout)
+		}
+	}
+	if before, _ := os.ReadFile(path); string(before) != cliFixture {
+		t.Error("Expected -dry-run to leave the config alone")
+	}
+
+	if code, stdout, _ := run("import", "csv", csv, "-yes", "-group", "ci", "-config", path); code != exitOK || !strings.Contains(stdout, "Added 1 hosts to "+path) {
+		t.Fatalf("Unexpected import with %d:\n%s", code, stdout)
+	}
+	after, _ := os.ReadFile(path)
+	if !strings.Contains(string(after), "# ohmyssh: group=ci\nHost build\n") {
+		t.Errorf("Expected build to be added in group ci:\n%s", after)
+	}
+	if code, stdout, _ := run("import", "csv", csv, "-yes", "-config", path); code != exitOK || !strings.Contains(stdout, "Nothing to import") {
+		t.Errorf("Expected nothing left to import, got %d:\n%s", code, stdout)
+	}
+
+	if code, _, stderr := run("import", "winscp", csv, "-config", path); code != exitUsage || !strings.Contains(stderr, "unsupported import format") {
+		t.Errorf("Unexpected result for an unknown format: %d, %q", code, stderr)
+	}
+	if code, _, _ := run("import", "csv", filepath.Join(t.TempDir(), "missing.csv"), "-config", path); code != exitFailure {
+		t.Errorf("Expected exit code %d for a missing file, got %d", exitFailure, code)
+	}
+}
+
+func TestCLIConnectMatching(t *testing.T) {
+	path := writeCLIConfig(t, cliFixture)
+
+	code, stdout, stderr := run("connect", "web", "-config", path)
+	if code != exitFailure || stdout != "" || !strings.Contains(stderr, `"web" matches several hosts:`) || !strings.Contains(stderr, "  web1\n  web2\n") {
+		t.Errorf("Unexpected result for an ambiguous name: %d, %q, %q", code, stdout, stderr)
+	}
+	if code, _, stderr := run("connect", "mail", "-config", path); code != exitFailure || !strings.Contains(stderr, "host not found: mail") {
+		t.Errorf("Unexpected result for an unknown host: %d, %q", code, stderr)
+	}
+	if code, _, stderr := run("connect", "db1", "-port", "0", "-config", path); code != exitUsage || !strings.Contains(stderr, `invalid port "0"`) {
+		t.Errorf("Unexpected result for port 0: %d, %q", code, stderr)
+	}
+	if code, _, _ := run("connect", "-config", path); code != exitUsage {
+		t.Errorf("Expected exit code %d without an alias, got %d", exitUsage, code)
+	}
+}
+
+func TestMatchHosts(t *testing.T) {
+	hosts := []parser.Host{
+		{Name: "db1", Hostname: "10.0.0.5"},
+		{Name: "db10", Hostname: "10.0.0.6"},
+		{Name: "web-prod", Hostname: "www.example.com"},
+		{Name: "web-staging", Hostname: "staging.example.com"},
+	}
+	tests := map[string][]string{
+		"db1":     {"db1"},         // exact alias beats the prefix match of db10
+		"DB":      {"db1", "db10"}, // ambiguous prefix
+		"staging": {"web-staging"}, // substring of the alias
+		"www":     {"web-prod"},    // substring of the hostname
+		"wbprd":   {"web-prod"},    // characters in order
+		"10.0.0":  {"db1", "db10"}, // hostnames are substrings too
+		"mail":    nil,
+	}
+	for query, want := range tests {
+		var got []string
+		for _, host := range matchHosts(hosts, query) {
+			got = append(got, host.Name)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("matchHosts(%q) = %v, want %v", query, got, want)
+		}
+	}
+}
+
+func TestParseArgs(t *testing.T) {
+	flags, configPath := newFlagSet("test", &bytes.Buffer{})
+	output := outputFlag(flags)
+	positional, err := parseArgs(flags, []string{"import", "-config", "/tmp/config", "csv", "-output", "json", "hosts.csv"})
+	if err != nil {
+		t.Fatalf("parseArgs failed: %v", err)
+	}
+	if !reflect.DeepEqual(positional, []string{"import", "csv", "hosts.csv"}) || *configPath != "/tmp/config" || *output != "json" {
+		t.Errorf("Unexpected result %v, %s, %s", positional, *configPath, *output)
+	}
+}
+
+func TestClientCommandExpandsHostname(t *testing.T) {
+	path := writeCLIConfig(t, "Host api.prod\n    User deploy\n\nHost *.prod\n    HostName %h.example.com\n    Port 2222\n")
+	config, err := loadConfig(path)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	appSettings := settings.Settings{Clients: map[string]string{"tpl": "myssh {user}@{hostname}:{port}"}}
+	command, err := clientCommand(config, appSettings, config.GetHosts()[0], launch.Options{Client: "tpl"})
+	if err != nil {
+		t.Fatalf("clientCommand failed: %v", err)
+	}
+	if expected := []string{"myssh", "deploy@api.prod.example.com:2222"}; !reflect.DeepEqual(command, expected) {
+		t.Errorf("Expected %v, got %v", expected, command)
+	}
+}
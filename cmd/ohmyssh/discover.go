@@ -0,0 +1,180 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/pozgo/OhMySSH/pkg/knownhosts"
+	"github.com/pozgo/OhMySSH/pkg/parser"
+	"github.com/pozgo/OhMySSH/pkg/revision"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// discoverHosts lists the entries of the known_hosts file at path that no
+// Host block covers yet.
+func discoverHosts(config *parser.SSHConfig, path string) (knownhosts.Discovery, error) {
+	entries, err := knownhosts.ReadFile(path)
+	if err != nil {
+		return knownhosts.Discovery{}, fmt.Errorf("failed to read known_hosts: %v", err)
+	}
+	return knownhosts.Discover(entries, config.Covers), nil
+}
+
+// suggestedAlias names a promoted host after its address, with the port
+// appended when it is not the default so that both can be promoted.
+func suggestedAlias(candidate knownhosts.Candidate) string {
+	if candidate.Port == "22" {
+		return candidate.Host
+	}
+	return candidate.Host + "-" + candidate.Port
+}
+
+func (m model) openDiscover() (tea.Model, tea.Cmd) {
+	discovery, err := discoverHosts(m.sshConfig, knownhosts.DefaultPath())
+	if err != nil {
+		m.setError(err)
+		return m, nil
+	}
+	m.discovery = discovery
+	m.discoverIdx = 0
+	m.currentMode = modeDiscover
+	return m, nil
+}
+
+func (m model) handleDiscoverKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	candidates := m.discovery.Candidates
+	switch msg.String() {
+	case "esc", "q", "D":
+		m.currentMode = modeNormal
+	case "up", "k":
+		if m.discoverIdx > 0 {
+			m.discoverIdx--
+		}
+	case "down", "j":
+		if m.discoverIdx < len(candidates)-1 {
+			m.discoverIdx++
+		}
+	case "enter", "a":
+		if m.discoverIdx < len(candidates) {
+			return m.openPromoteForm(candidates[m.discoverIdx])
+		}
+	}
+	return m, nil
+}
+
+// openPromoteForm asks for the alias and login details of a discovered host
+// before it is added to the main config.
+func (m model) openPromoteForm(candidate knownhosts.Candidate) (tea.Model, tea.Cmd) {
+	port := ""
+	if candidate.Port != "22" {
+		port = candidate.Port
+	}
+	m.form = newForm(formPromoteHost, "Add "+candidate.Host+" to SSH config", "",
+		newFormField("Alias", "alias", suggestedAlias(candidate)),
+		newFormField("HostName", "HostName", candidate.Host),
+		newFormField("User", "User", ""),
+		newFormField("Port", "Port", port),
+		newFormField("Group", "group", ""),
+	)
+	m.currentMode = modeForm
+	return m, nil
+}
+
+func (m model) submitPromote() (tea.Model, tea.Cmd) {
+	alias := strings.TrimSpace(m.form.value("alias"))
+	var err error
+	if _, exists := m.hostByName(alias); exists {
+		err = fmt.Errorf("%w: %s", parser.ErrHostExists, alias)
+	}
+	if port := m.form.value("Port"); err == nil && port != "" {
+		if n, convErr := strconv.Atoi(port); convErr != nil || n < 1 || n > 65535 {
+			err = fmt.Errorf("invalid port %q", port)
+		}
+	}
+
+	var before, content string
+	if err == nil {
+		before, err = m.readConfigFile(m.sshConfig.Path)
+	}
+	if err == nil {
+		content, err = parser.AddHost(before, alias, []parser.Directive{
+			{Key: "HostName", Value: m.form.value("HostName")},
+			{Key: "User", Value: m.form.value("User")},
+			{Key: "Port", Value: m.form.value("Port")},
+		})
+	}
+	if group := strings.Trim(m.form.value("group"), "/ "); err == nil && group != "" {
+		content, err = parser.SetHostMeta(content, alias, parser.Meta{Group: group})
+	}
+	status := fmt.Sprintf("Added %s from known_hosts", alias)
+	if err == nil {
+		err = m.applyConfigChanges(status, []revision.FileChange{{Path: m.sshConfig.Path, Before: before, After: content}})
+	}
+	if err != nil {
+		m.form.err = err
+		return m, nil
+	}
+
+	m.selectHost(alias)
+	m.setStatus(status)
+	m.currentMode = modeNormal
+	return m, nil
+}
+
+func (m model) renderDiscover() string {
+	candidates := m.discovery.Candidates
+
+	leftWidth := int(float64(m.width) * 0.45)
+	rightWidth := m.width - leftWidth
+	height := m.height - 1
+
+	var items []string
+	if len(candidates) == 0 {
+		items = append(items, "Every host in known_hosts has a Host entry")
+	} else {
+		items = append(items, lipgloss.NewStyle().Bold(true).Render(fmt.Sprintf("%-30s %5s", "HOST", "PORT")))
+	}
+	visible := height - 6
+	start := scrollWindow(m.discoverIdx, len(candidates), visible)
+	for i := start; i < len(candidates) && i < start+visible; i++ {
+		line := fmt.Sprintf("%-30s %5s", truncate(candidates[i].Host, 30), candidates[i].Port)
+		style := lipgloss.NewStyle().MaxWidth(leftWidth - 5)
+		if i == m.discoverIdx {
+			style = style.Bold(true).Foreground(lipgloss.Color("15")).Background(lipgloss.Color("62"))
+		}
+		items = append(items, style.Render(line))
+	}
+
+	var details []string
+	if m.discoverIdx < len(candidates) {
+		candidate := candidates[m.discoverIdx]
+		details = append(details,
+			fmt.Sprintf("Host: %s", candidate.Host),
+			fmt.Sprintf("Port: %s", candidate.Port),
+		)
+		if len(candidate.Names) > 0 {
+			details = append(details, fmt.Sprintf("Also known as: %s", strings.Join(candidate.Names, ", ")))
+		}
+		details = append(details,
+			fmt.Sprintf("Key types: %s", strings.Join(candidate.KeyTypes, ", ")),
+			fmt.Sprintf("known_hosts line: %d", candidate.Line),
+		)
+	}
+	note := lipgloss.NewStyle().Foreground(lipgloss.Color("241"))
+	details = append(details, "")
+	if m.discovery.Hashed > 0 {
+		details = append(details, note.Render(fmt.Sprintf("%d hashed entries cannot be listed (HashKnownHosts)", m.discovery.Hashed)))
+	}
+	if m.discovery.Markers > 0 {
+		details = append(details, note.Render(fmt.Sprintf("%d @cert-authority/@revoked entries skipped", m.discovery.Markers)))
+	}
+
+	list := renderPanel(fmt.Sprintf("🔭 KNOWN HOSTS WITHOUT ENTRY (%d)", len(candidates)), lipgloss.Color("62"), leftWidth, height, items)
+	detail := renderPanel("🔑 DETAILS", lipgloss.Color("214"), rightWidth, height, details)
+	helpBar := m.renderHelpBar("↑↓: select host", "enter/a: add to SSH config", "esc/q: back")
+
+	return lipgloss.JoinVertical(lipgloss.Left, lipgloss.JoinHorizontal(lipgloss.Top, list, detail), helpBar)
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// favoritesGroup is the group path of the pinned section at the top of the
+// server list. It cannot clash with a real group, which never has a NUL.
+const favoritesGroup = "\x00favorites"
+
+type sortMode int
+
+const (
+	sortFile sortMode = iota
+	sortName
+	sortRecent
+	sortFrequent
+)
+
+var sortModeNames = []string{"file", "name", "recent", "frequent"}
+
+func (s sortMode) String() string {
+	return sortModeNames[s]
+}
+
+func parseSortMode(name string) sortMode {
+	for i, n := range sortModeNames {
+		if n == name {
+			return sortMode(i)
+		}
+	}
+	return sortFile
+}
+
+func (m model) isFavorite(name string) bool {
+	return m.state.IsFavorite(name)
+}
+
+// hostLess orders two hosts by the current sort mode. Ties, and everything in
+// file order mode, keep the order of the config files.
+func (m model) hostLess(a, b int) bool {
+	first, second := m.hosts[a], m.hosts[b]
+	statsA, statsB := m.state.Stats(first.Name), m.state.Stats(second.Name)
+	switch m.sortMode {
+	case sortName:
+		if !strings.EqualFold(first.Name, second.Name) {
+			return strings.ToLower(first.Name) < strings.ToLower(second.Name)
+		}
+	case sortRecent:
+		if !statsA.LastConnected.Equal(statsB.LastConnected) {
+			return statsA.LastConnected.After(statsB.LastConnected)
+		}
+	case sortFrequent:
+		if statsA.Connections != statsB.Connections {
+			return statsA.Connections > statsB.Connections
+		}
+	}
+	return a < b
+}
+
+// sortNode orders a group's hosts and, recursively, its subgroups. Groups are
+// ranked by their most recent or most used host in the usage based modes.
+func (m model) sortNode(node *groupNode) {
+	sort.SliceStable(node.hosts, func(i, j int) bool {
+		return m.hostLess(node.hosts[i], node.hosts[j])
+	})
+	for _, c := range node.children {
+		m.sortNode(c)
+	}
+
+	switch m.sortMode {
+	case sortName:
+		sort.SliceStable(node.children, func(i, j int) bool {
+			return strings.ToLower(node.children[i].name) < strings.ToLower(node.children[j].name)
+		})
+	case sortRecent:
+		sort.SliceStable(node.children, func(i, j int) bool {
+			return m.lastUsed(node.children[i]).After(m.lastUsed(node.children[j]))
+		})
+	case sortFrequent:
+		sort.SliceStable(node.children, func(i, j int) bool {
+			return m.timesUsed(node.children[i]) > m.timesUsed(node.children[j])
+		})
+	}
+}
+
+func (m model) lastUsed(node *groupNode) time.Time {
+	var last time.Time
+	for _, idx := range node.hosts {
+		if t := m.state.Stats(m.hosts[idx].Name).LastConnected; t.After(last) {
+			last = t
+		}
+	}
+	for _, c := range node.children {
+		if t := m.lastUsed(c); t.After(last) {
+			last = t
+		}
+	}
+	return last
+}
+
+func (m model) timesUsed(node *groupNode) int {
+	total := 0
+	for _, idx := range node.hosts {
+		total += m.state.Stats(m.hosts[idx].Name).Connections
+	}
+	for _, c := range node.children {
+		total += m.timesUsed(c)
+	}
+	return total
+}
+
+// favoriteRows returns the pinned section shown above the groups, or nothing
+// when no favorite matches the filter.
+func (m model) favoriteRows() []listRow {
+	var favorites []int
+	for i, host := range m.hosts {
+		if m.isFavorite(host.Name) && m.hostMatchesFilter(host) {
+			favorites = append(favorites, i)
+		}
+	}
+	if len(favorites) == 0 {
+		return nil
+	}
+	sort.SliceStable(favorites, func(i, j int) bool {
+		return m.hostLess(favorites[i], favorites[j])
+	})
+
+	rows := []listRow{{group: favoritesGroup, label: "Favorites", hostIdx: -1, count: len(favorites)}}
+	if m.collapsed[favoritesGroup] && !m.filterActive() {
+		return rows
+	}
+	for _, idx := range favorites {
+		rows = append(rows, listRow{label: m.hosts[idx].Name, depth: 1, hostIdx: idx, parent: favoritesGroup})
+	}
+	return rows
+}
+
+// toggleFavorite pins or unpins the selected host.
+func (m model) toggleFavorite() (tea.Model, tea.Cmd) {
+	host, ok := m.currentHost()
+	if !ok {
+		return m, nil
+	}
+	pinned := m.state.ToggleFavorite(host.Name)
+	if err := m.state.Save(); err != nil {
+		m.setError(err)
+		return m, nil
+	}
+	if pinned {
+		m.setStatus("Pinned " + host.Name)
+	} else {
+		m.setStatus("Unpinned " + host.Name)
+	}
+	m.selectHost(host.Name)
+	return m, nil
+}
+
+// cycleSort switches to the next sort mode and remembers it.
+func (m model) cycleSort() (tea.Model, tea.Cmd) {
+	selected, hadHost := m.currentHost()
+	m.sortMode = (m.sortMode + 1) % sortMode(len(sortModeNames))
+	m.state.SortMode = m.sortMode.String()
+	if err := m.state.Save(); err != nil {
+		m.setError(err)
+	}
+	if hadHost {
+		m.selectHost(selected.Name)
+	}
+	return m, nil
+}
+
+// sortStatusItem is the clickable sort entry of the status bar.
+func (m model) sortStatusItem() string {
+	return "s: sort by " + m.sortMode.String()
+}
+
+// statusBarItemAt returns the status bar item under column x.
+func (m model) statusBarItemAt(x int) string {
+	pos := 1 // left padding
+	for _, item := range m.statusBarItems() {
+		width := lipgloss.Width(item)
+		if x >= pos && x < pos+width {
+			return item
+		}
+		pos += width + lipgloss.Width(statusBarSeparator)
+	}
+	return ""
+}
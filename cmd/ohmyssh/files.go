@@ -0,0 +1,411 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/pozgo/OhMySSH/pkg/parser"
+	"github.com/pozgo/OhMySSH/pkg/transfer"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// filesView browses the directories of a host over sftp. Files are copied
+// to and from it with scp, one transfer at a time; run tells the messages
+// of the current transfer apart from those of a cancelled one.
+type filesView struct {
+	host     string
+	listing  transfer.Listing
+	loading  bool
+	idx      int
+	active   *transfer.Transfer
+	progress transfer.Progress
+	started  time.Time
+	cancel   context.CancelFunc
+	run      int
+}
+
+// listingMsg delivers a remote directory listing.
+type listingMsg struct {
+	host    string
+	listing transfer.Listing
+	err     error
+}
+
+// transferMsg delivers the progress of a transfer, and its outcome once
+// done is set.
+type transferMsg struct {
+	run      int
+	progress transfer.Progress
+	updates  <-chan transferMsg
+	done     bool
+	err      error
+}
+
+// openFiles shows the login directory of the selected host.
+func (m model) openFiles() (tea.Model, tea.Cmd) {
+	host, ok := m.currentHost()
+	if !ok {
+		return m, nil
+	}
+	if m.files.host != host.Name {
+		m.files = filesView{host: host.Name, run: m.files.run}
+	}
+	m.currentMode = modeFiles
+	if m.files.listing.Dir != "" {
+		return m, nil
+	}
+	return m.listDir("")
+}
+
+// listDir loads dir of the current host in the background; "" is the login
+// directory.
+func (m model) listDir(dir string) (tea.Model, tea.Cmd) {
+	host := m.files.host
+	m.files.loading = true
+	return m, func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		listing, err := transfer.NewClient().List(ctx, host, dir)
+		return listingMsg{host: host, listing: listing, err: err}
+	}
+}
+
+func (m model) finishListing(msg listingMsg) (tea.Model, tea.Cmd) {
+	if msg.host != m.files.host {
+		return m, nil
+	}
+	m.files.loading = false
+	if msg.err != nil {
+		m.setError(fmt.Errorf("failed to list %s: %v", msg.host, msg.err))
+		return m, nil
+	}
+	// Stay on the same entry when the directory was only refreshed
+	selected := ""
+	if entry, ok := m.selectedEntry(); ok && msg.listing.Dir == m.files.listing.Dir {
+		selected = entry.Name
+	}
+	m.files.listing = msg.listing
+	m.files.idx = 0
+	for i, entry := range msg.listing.Entries {
+		if entry.Name == selected {
+			m.files.idx = i
+		}
+	}
+	return m, nil
+}
+
+func (m model) selectedEntry() (transfer.Entry, bool) {
+	entries := m.files.listing.Entries
+	if m.files.idx < len(entries) {
+		return entries[m.files.idx], true
+	}
+	return transfer.Entry{}, false
+}
+
+func (m model) handleFilesKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	m.status = ""
+	entries := m.files.listing.Entries
+	switch msg.String() {
+	case "esc", "q":
+		if m.files.active != nil {
+			m.files.cancel()
+			return m, nil
+		}
+		m.currentMode = modeNormal
+	case "up", "k":
+		if m.files.idx > 0 {
+			m.files.idx--
+		}
+	case "down", "j":
+		if m.files.idx < len(entries)-1 {
+			m.files.idx++
+		}
+	case "pgup":
+		m.files.idx = max(m.files.idx-m.filesPageSize(), 0)
+	case "pgdown":
+		m.files.idx = max(min(m.files.idx+m.filesPageSize(), len(entries)-1), 0)
+	case "enter", "right", "l":
+		entry, ok := m.selectedEntry()
+		if !ok || m.files.loading {
+			return m, nil
+		}
+		if entry.IsDir() || entry.IsLink() {
+			return m.listDir(path.Join(m.files.listing.Dir, entry.Name))
+		}
+		return m.openTransferForm(transfer.Download)
+	case "left", "h", "backspace":
+		if dir := m.files.listing.Dir; dir != "" && dir != "/" && !m.files.loading {
+			return m.listDir(path.Dir(dir))
+		}
+	case "r":
+		if !m.files.loading {
+			return m.listDir(m.files.listing.Dir)
+		}
+	case "d":
+		if _, ok := m.selectedEntry(); ok {
+			return m.openTransferForm(transfer.Download)
+		}
+	case "u":
+		return m.openTransferForm(transfer.Upload)
+	}
+	return m, nil
+}
+
+// openTransferForm asks for both ends of a transfer, filled in from the
+// selected entry and the current directories.
+func (m model) openTransferForm(direction transfer.Direction) (tea.Model, tea.Cmd) {
+	if m.files.active != nil {
+		m.setError(fmt.Errorf("a transfer is still running, esc cancels it"))
+		return m, nil
+	}
+	dir := m.files.listing.Dir
+	if dir == "" {
+		dir = "."
+	}
+	workDir, err := os.Getwd()
+	if err != nil {
+		workDir = "."
+	}
+
+	if direction == transfer.Download {
+		remotePath := dir
+		if entry, ok := m.selectedEntry(); ok {
+			remotePath = path.Join(dir, entry.Name)
+		}
+		m.form = newForm(formDownload, "⬇️ Download from "+m.files.host, m.files.host,
+			newFormField("Remote path", "remote", remotePath),
+			newFormField("Save to", "local", workDir),
+		)
+		m.form.help = "directories are copied with their contents | enter: download | esc: cancel"
+	} else {
+		local := newFormField("Local path", "local", "")
+		local.input.Placeholder = "~/notes.txt"
+		m.form = newForm(formUpload, "⬆️ Upload to "+m.files.host, m.files.host,
+			local,
+			newFormField("Remote directory", "remote", dir),
+		)
+		m.form.help = "directories are copied with their contents | enter: upload | esc: cancel"
+	}
+	m.form.returnTo = modeFiles
+	m.currentMode = modeForm
+	return m, nil
+}
+
+func (m model) submitTransfer() (tea.Model, tea.Cmd) {
+	t := transfer.Transfer{
+		Host:   m.form.target,
+		Local:  parser.ExpandHome(m.form.value("local")),
+		Remote: m.form.value("remote"),
+	}
+	if m.form.kind == formDownload {
+		t.Direction = transfer.Download
+		if entry, ok := m.selectedEntry(); ok && path.Join(m.files.listing.Dir, entry.Name) == t.Remote && !entry.IsDir() {
+			t.Size = entry.Size
+		}
+	}
+	if t.Local == "" || t.Remote == "" {
+		m.form.err = fmt.Errorf("both paths are required")
+		return m, nil
+	}
+	if t.Direction == transfer.Upload {
+		if _, err := os.Stat(t.Local); err != nil {
+			m.form.err = err
+			return m, nil
+		}
+	}
+	m.currentMode = modeFiles
+	return m.startTransfer(t)
+}
+
+// startTransfer runs scp in the background. Progress is sent as it is
+// measured, but only the latest is kept when the view falls behind.
+func (m model) startTransfer(t transfer.Transfer) (tea.Model, tea.Cmd) {
+	ctx, cancel := context.WithCancel(context.Background())
+	m.files.run++
+	m.files.active = &t
+	m.files.progress = transfer.Progress{Total: t.Size}
+	m.files.started = time.Now()
+	m.files.cancel = cancel
+
+	run := m.files.run
+	updates := make(chan transferMsg, 1)
+	go func() {
+		defer close(updates)
+		defer cancel()
+		err := transfer.NewClient().Copy(ctx, t, func(p transfer.Progress) {
+			select {
+			case updates <- transferMsg{run: run, progress: p}:
+			default:
+			}
+		})
+		updates <- transferMsg{run: run, done: true, err: err}
+	}()
+	return m, waitForTransfer(updates)
+}
+
+func waitForTransfer(updates <-chan transferMsg) tea.Cmd {
+	return func() tea.Msg {
+		msg, ok := <-updates
+		if !ok {
+			return nil
+		}
+		msg.updates = updates
+		return msg
+	}
+}
+
+func (m model) applyTransfer(msg transferMsg) (tea.Model, tea.Cmd) {
+	if msg.run != m.files.run || m.files.active == nil {
+		return m, nil
+	}
+	if !msg.done {
+		m.files.progress = msg.progress
+		return m, waitForTransfer(msg.updates)
+	}
+
+	t := *m.files.active
+	m.files.active = nil
+	if errors.Is(msg.err, context.Canceled) {
+		m.setStatus("Cancelled " + t.Describe())
+		return m, nil
+	}
+	if msg.err != nil {
+		m.setError(fmt.Errorf("failed to %s %s: %v", t.Direction, t.Describe(), msg.err))
+		return m, nil
+	}
+	copied := fmt.Sprintf("Copied %s in %s", t.Describe(), formatDuration(time.Since(m.files.started)))
+	if m.files.progress.Total > 0 {
+		copied += fmt.Sprintf(" (%s)", formatSize(m.files.progress.Total))
+	}
+	m.setStatus(copied)
+	if t.Direction == transfer.Upload && m.currentMode == modeFiles && !m.files.loading {
+		return m.listDir(m.files.listing.Dir)
+	}
+	return m, nil
+}
+
+// formatSize writes a byte count the way ls -h would.
+func formatSize(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	value := float64(n) / unit
+	for _, suffix := range []string{"K", "M", "G", "T"} {
+		if value < unit {
+			return fmt.Sprintf("%.1f %s", value, suffix)
+		}
+		value /= unit
+	}
+	return fmt.Sprintf("%.1f P", value)
+}
+
+// transferProgress describes the running transfer, with a bar when its
+// size is known.
+func (m model) transferProgress(width int) []string {
+	t := m.files.active
+	p := m.files.progress
+	icon := "⬆️"
+	if t.Direction == transfer.Download {
+		icon = "⬇️"
+	}
+	since := "since " + m.files.started.Format("15:04:05")
+	if p.Total <= 0 {
+		return []string{fmt.Sprintf("%s %s copied, %s", icon, formatSize(p.Done), since)}
+	}
+	percent := int(p.Done * 100 / p.Total)
+	barWidth := min(width-2, 40)
+	filled := barWidth * percent / 100
+	return []string{
+		strings.Repeat("█", filled) + strings.Repeat("░", barWidth-filled),
+		fmt.Sprintf("%s %d%%, %s of %s, %s", icon, percent, formatSize(p.Done), formatSize(p.Total), since),
+	}
+}
+
+// filesPageSize is how many entries fit in the list below the path, leaving
+// room for the status line.
+func (m model) filesPageSize() int {
+	if page := m.height - 11; page > 1 {
+		return page
+	}
+	return 1
+}
+
+func (m model) renderFiles() string {
+	leftWidth := int(float64(m.width) * 0.6)
+	rightWidth := m.width - leftWidth
+	height := m.height - 1
+	dirStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("39"))
+	linkStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("212"))
+
+	dir := m.files.listing.Dir
+	if m.files.loading {
+		dir += " (loading...)"
+	}
+	items := []string{lipgloss.NewStyle().Bold(true).Render("📂 " + truncate(dir, leftWidth-10)), ""}
+	entries := m.files.listing.Entries
+	if len(entries) == 0 && !m.files.loading {
+		items = append(items, "Empty directory, press u to upload")
+	}
+	visible := m.filesPageSize()
+	start := scrollWindow(m.files.idx, len(entries), visible)
+	for i := start; i < len(entries) && i < start+visible; i++ {
+		entry := entries[i]
+		name, size := entry.Name, formatSize(entry.Size)
+		style := lipgloss.NewStyle().MaxWidth(leftWidth - 5)
+		switch {
+		case entry.IsDir():
+			name, size = name+"/", ""
+			style = style.Inherit(dirStyle)
+		case entry.IsLink():
+			name += "@"
+			style = style.Inherit(linkStyle)
+		}
+		line := fmt.Sprintf("%-*s %8s", max(leftWidth-20, 10), truncate(name, max(leftWidth-20, 10)), size)
+		if i == m.files.idx {
+			style = style.Bold(true).Foreground(lipgloss.Color("15")).Background(lipgloss.Color("62"))
+		}
+		items = append(items, style.Render(line))
+	}
+	if m.status != "" {
+		statusStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("46"))
+		if m.statusIsError {
+			statusStyle = statusStyle.Foreground(lipgloss.Color("196"))
+		}
+		items = append(items, "", statusStyle.Render(truncate(m.status, leftWidth-6)))
+	}
+
+	var details []string
+	if entry, ok := m.selectedEntry(); ok {
+		details = append(details,
+			fmt.Sprintf("Name: %s", entry.Name),
+			fmt.Sprintf("Mode: %s", entry.Mode),
+			fmt.Sprintf("Size: %s (%d bytes)", formatSize(entry.Size), entry.Size),
+			fmt.Sprintf("Modified: %s", entry.ModTime),
+		)
+	}
+	if t := m.files.active; t != nil {
+		details = append(details, "",
+			lipgloss.NewStyle().Bold(true).Render("Transfer"),
+			truncate(t.Describe(), rightWidth-6),
+		)
+		details = append(details, m.transferProgress(rightWidth-6)...)
+	}
+
+	list := renderPanel(fmt.Sprintf("🗂️ FILES ON %s", m.files.host), lipgloss.Color("62"), leftWidth, height, items)
+	detail := renderPanel("📄 DETAILS", lipgloss.Color("214"), rightWidth, height, details)
+	var helpBar string
+	if m.files.active != nil {
+		helpBar = m.renderHelpBar("enter: open", "h: up", "r: refresh", "esc: cancel transfer")
+	} else {
+		helpBar = m.renderHelpBar("enter: open", "h: up", "d: download", "u: upload", "r: refresh", "esc/q: back")
+	}
+	return lipgloss.JoinVertical(lipgloss.Left, lipgloss.JoinHorizontal(lipgloss.Top, list, detail), helpBar)
+}
@@ -0,0 +1,244 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pozgo/OhMySSH/pkg/knownhosts"
+
+	"github.com/charmbracelet/bubbles/textarea"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+type formKind int
+
+const (
+	formEditHost formKind = iota
+	formDuplicateHost
+	formRenameHost
+	formPromoteHost
+	formAddAgentKey
+	formGenerateKey
+	formAddTunnel
+	formUpload
+	formDownload
+	formConnectOptions
+)
+
+type formField struct {
+	label     string
+	key       string
+	input     textinput.Model
+	multiline bool
+	area      textarea.Model
+}
+
+// form is a small multi-field prompt rendered as a dialog over the main view.
+// The kind decides what happens on submit and target names the host it acts on.
+type form struct {
+	kind   formKind
+	title  string
+	target string
+	fields []formField
+	focus  int
+	err    error
+	help   string // replaces the default key hints when set
+	// returnTo is the mode esc leads back to, the server list by default
+	returnTo mode
+}
+
+func newFormField(label, key, value string) formField {
+	input := textinput.New()
+	input.Prompt = ""
+	input.CharLimit = 256
+	input.SetValue(value)
+	return formField{label: label, key: key, input: input}
+}
+
+// newMultilineFormField creates a field backed by a textarea, where enter
+// inserts a newline instead of submitting the form.
+func newMultilineFormField(label, key, value string) formField {
+	area := textarea.New()
+	area.ShowLineNumbers = false
+	area.Prompt = ""
+	area.SetHeight(4)
+	area.SetValue(value)
+	return formField{label: label, key: key, multiline: true, area: area}
+}
+
+func newForm(kind formKind, title, target string, fields ...formField) form {
+	f := form{kind: kind, title: title, target: target, fields: fields}
+	f.focusField(0)
+	return f
+}
+
+func (f *form) focusField(idx int) {
+	if len(f.fields) == 0 {
+		return
+	}
+	idx = (idx + len(f.fields)) % len(f.fields)
+	for i := range f.fields {
+		f.fields[i].input.Blur()
+		f.fields[i].area.Blur()
+	}
+	f.focus = idx
+	if f.fields[idx].multiline {
+		f.fields[idx].area.Focus()
+		return
+	}
+	f.fields[idx].input.Focus()
+	f.fields[idx].input.CursorEnd()
+}
+
+// focusedMultiline reports whether the focused field takes newlines.
+func (f form) focusedMultiline() bool {
+	return len(f.fields) > 0 && f.fields[f.focus].multiline
+}
+
+func (f form) hasMultiline() bool {
+	for _, field := range f.fields {
+		if field.multiline {
+			return true
+		}
+	}
+	return false
+}
+
+// value returns the trimmed value of the field with the given key.
+func (f form) value(key string) string {
+	for _, field := range f.fields {
+		if field.key == key {
+			if field.multiline {
+				return strings.TrimSpace(field.area.Value())
+			}
+			return strings.TrimSpace(field.input.Value())
+		}
+	}
+	return ""
+}
+
+func (f form) update(msg tea.Msg) (form, tea.Cmd) {
+	if keyMsg, ok := msg.(tea.KeyMsg); ok {
+		switch keyMsg.String() {
+		case "tab":
+			f.focusField(f.focus + 1)
+			return f, nil
+		case "shift+tab":
+			f.focusField(f.focus - 1)
+			return f, nil
+		case "down":
+			if !f.focusedMultiline() {
+				f.focusField(f.focus + 1)
+				return f, nil
+			}
+		case "up":
+			if !f.focusedMultiline() {
+				f.focusField(f.focus - 1)
+				return f, nil
+			}
+		}
+	}
+
+	if len(f.fields) == 0 {
+		return f, nil
+	}
+	var cmd tea.Cmd
+	if f.focusedMultiline() {
+		f.fields[f.focus].area, cmd = f.fields[f.focus].area.Update(msg)
+	} else {
+		f.fields[f.focus].input, cmd = f.fields[f.focus].input.Update(msg)
+	}
+	return f, cmd
+}
+
+func (f form) view(width int) string {
+	labelWidth := 0
+	for _, field := range f.fields {
+		if len(field.label) > labelWidth {
+			labelWidth = len(field.label)
+		}
+	}
+
+	titleStyle := lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("205"))
+	labelStyle := lipgloss.NewStyle().Width(labelWidth + 2)
+	focusedLabelStyle := labelStyle.Foreground(lipgloss.Color("205")).Bold(true)
+
+	lines := []string{titleStyle.Render(f.title), ""}
+	for i, field := range f.fields {
+		style := labelStyle
+		marker := "  "
+		if i == f.focus {
+			style = focusedLabelStyle
+			marker = "▸ "
+		}
+		if field.multiline {
+			area := field.area
+			area.SetWidth(width*2/3 - labelWidth - 12)
+			lines = append(lines, marker+style.Render(field.label+":"))
+			for _, line := range strings.Split(area.View(), "\n") {
+				lines = append(lines, strings.Repeat(" ", labelWidth+4)+line)
+			}
+			continue
+		}
+		// Without a width the input shows only the first rune of a placeholder
+		input := field.input
+		input.Width = width*2/3 - labelWidth - 12
+		lines = append(lines, marker+style.Render(field.label+":")+input.View())
+	}
+
+	if f.err != nil {
+		lines = append(lines, "", lipgloss.NewStyle().Foreground(lipgloss.Color("196")).Render(fmt.Sprintf("Error: %v", f.err)))
+	}
+
+	help := "tab/↑↓: next field | enter: save | esc: cancel"
+	if f.hasMultiline() {
+		help = "tab: next field | enter: save (newline in notes) | ctrl+s: save | esc: cancel"
+	}
+	if f.help != "" {
+		help = f.help
+	}
+	lines = append(lines, "", lipgloss.NewStyle().Foreground(lipgloss.Color("241")).Render(help))
+
+	return dialogStyle(width, lipgloss.Color("205")).Render(strings.Join(lines, "\n"))
+}
+
+type confirmKind int
+
+const (
+	confirmDeleteHost confirmKind = iota
+	confirmRemoveKnownHosts
+	confirmDeleteTunnel
+)
+
+// confirmation is a yes/no question asked before a destructive action.
+// Either answer leads back to returnTo, the server list by default.
+type confirmation struct {
+	kind     confirmKind
+	target   string
+	message  string
+	entries  []knownhosts.Entry // known_hosts entries to remove
+	returnTo mode
+}
+
+func (c confirmation) view(width int) string {
+	lines := []string{
+		lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("196")).Render(c.message),
+		"",
+		lipgloss.NewStyle().Foreground(lipgloss.Color("241")).Render("y: yes | n/esc: no"),
+	}
+	return dialogStyle(width, lipgloss.Color("196")).Render(strings.Join(lines, "\n"))
+}
+
+func dialogStyle(width int, color lipgloss.Color) lipgloss.Style {
+	dialogWidth := width * 2 / 3
+	if dialogWidth < 40 {
+		dialogWidth = width - 4
+	}
+	return lipgloss.NewStyle().
+		Width(dialogWidth).
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(color).
+		Padding(1, 2)
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/pozgo/OhMySSH/pkg/knownhosts"
+	"github.com/pozgo/OhMySSH/pkg/parser"
+	"github.com/pozgo/OhMySSH/pkg/probe"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// handshakeResult is what the SSH server of a host revealed before login,
+// with its host key compared to known_hosts.
+type handshakeResult struct {
+	pending   bool
+	handshake probe.Handshake
+	check     knownhosts.KeyCheck
+	err       error
+}
+
+// handshakeMsg delivers the result of probing a host's SSH server.
+type handshakeMsg struct {
+	alias  string
+	result handshakeResult
+}
+
+// inspectHostKey runs the SSH handshake with host and compares the host key
+// with the entries known_hosts stores under the name ssh looks up.
+func inspectHostKey(config *parser.SSHConfig, host parser.Host) handshakeResult {
+	target, ok := probeTarget(config, host)
+	if !ok {
+		return handshakeResult{err: errors.New("behind a proxy, cannot be probed directly")}
+	}
+	entries, err := knownhosts.ReadFile(knownhosts.DefaultPath())
+	if err != nil {
+		return handshakeResult{err: fmt.Errorf("failed to read known_hosts: %v", err)}
+	}
+	name, port := hostKeyTarget(config, host)
+	stored := knownhosts.Lookup(entries, name, port)
+	var preferred []string
+	for _, entry := range stored {
+		if entry.Marker == "" {
+			preferred = append(preferred, entry.KeyType)
+		}
+	}
+
+	handshake, err := probe.ProbeHandshake(context.Background(), target.Address, probeTimeout, preferred)
+	if err != nil {
+		return handshakeResult{handshake: handshake, err: err}
+	}
+	offered := knownhosts.Entry{KeyType: handshake.KeyType, Key: handshake.Key}
+	check := knownhosts.Compare(stored, []knownhosts.Entry{offered})[0]
+	return handshakeResult{handshake: handshake, check: check}
+}
+
+func (m model) probeHostKey() (tea.Model, tea.Cmd) {
+	host, ok := m.currentHost()
+	if !ok {
+		return m, nil
+	}
+	m.handshakes[host.Name] = handshakeResult{pending: true}
+	config := m.sshConfig
+	return m, func() tea.Msg {
+		return handshakeMsg{alias: host.Name, result: inspectHostKey(config, host)}
+	}
+}
+
+// handshakeDetails describes the last probe of host for the details panel.
+func (m model) handshakeDetails(host parser.Host) []string {
+	result, ok := m.handshakes[host.Name]
+	switch {
+	case !ok:
+		return nil
+	case result.pending:
+		return []string{"SSH: probing..."}
+	case result.err != nil:
+		return []string{fmt.Sprintf("SSH: %v", result.err)}
+	}
+	return []string{
+		fmt.Sprintf("SSH: %s", result.handshake.Version),
+		fmt.Sprintf("Host key: %s %s", shortKeyType(result.handshake.KeyType), result.handshake.Fingerprint),
+		fmt.Sprintf("known_hosts: %s", keyCheckLabel(result.check)),
+		fmt.Sprintf("Host key algorithms: %s", strings.Join(result.handshake.HostKeyAlgorithms, ", ")),
+	}
+}
+
+// keyCheckLabel explains how the offered key compares to known_hosts.
+func keyCheckLabel(check knownhosts.KeyCheck) string {
+	switch check.Status {
+	case knownhosts.KeyMatch:
+		return "✅ match"
+	case knownhosts.KeyChanged:
+		return fmt.Sprintf("⚠️ CHANGED, stored %s", check.Stored)
+	case knownhosts.KeyRevoked:
+		return "⚠️ REVOKED"
+	default:
+		return "not stored yet"
+	}
+}
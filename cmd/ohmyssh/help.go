@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+type keyBinding struct {
+	keys        string
+	description string
+}
+
+// serverListKeys documents the bindings available from the server list.
+var serverListKeys = []keyBinding{
+	{"↑/k ↓/j", "navigate servers"},
+	{"enter", "connect to selected server, or expand/collapse a group"},
+	{"alt+enter", "choose where to open the session: this terminal, tmux, screen or a new terminal"},
+	{"o", "connect with another client, user, port, -o options, -v or agent forwarding"},
+	{"space", "mark/unmark selected host, or all hosts of a group (also shift+click)"},
+	{"←/h →/l", "collapse/expand group"},
+	{"/", "filter (group:<path>, tag:<name>, owner:<name> or free text)"},
+	{"g", "scope list to selected group (again to clear)"},
+	{"esc", "clear filter, scope and marks"},
+	{"r", "reload hosts and re-check which ones are reachable"},
+	{"f", "pin/unpin selected host as a favorite"},
+	{"s", "cycle sort: file order, name, most recent, most frequent"},
+	{"e", "edit whole SSH config (vim mode)"},
+	{"E", "edit selected host, its group, tags, owner and notes"},
+	{"c", "duplicate selected host"},
+	{"R", "rename selected host (updates ProxyJump references)"},
+	{"x/delete", "delete selected host"},
+	{"u", "undo last config change"},
+	{"ctrl+r", "redo last undone change"},
+	{"U", "show change history"},
+	{"H", "show connection history and statistics"},
+	{"D", "discover hosts in known_hosts that have no Host entry"},
+	{"K", "show, remove and re-check the known_hosts keys of selected host"},
+	{"P", "probe SSH version and host key of selected host without logging in"},
+	{"I", "list SSH keys, the hosts using them and IdentityFile problems"},
+	{"A", "list the keys loaded in ssh-agent"},
+	{"G", "generate a key, install it on selected host and use it"},
+	{"T", "manage port forwarding tunnels running in the background"},
+	{"!", "run a command on the marked hosts, or selected host, and show its output"},
+	{"t", "browse files on selected host, upload and download"},
+	{"?", "show this help"},
+	{"q/ctrl+c", "quit"},
+}
+
+func (m model) renderHelp() string {
+	keyStyle := lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("205")).Width(14)
+
+	lines := []string{
+		lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("62")).Render("⌨️  KEYBOARD SHORTCUTS"),
+		"",
+	}
+	for _, binding := range serverListKeys {
+		lines = append(lines, fmt.Sprintf("%s %s", keyStyle.Render(binding.keys), binding.description))
+	}
+	lines = append(lines, "", lipgloss.NewStyle().Foreground(lipgloss.Color("241")).Render("press any key to return"))
+
+	return m.renderDialog(dialogStyle(m.width, lipgloss.Color("62")).Render(strings.Join(lines, "\n")))
+}
+
+// renderDialog centers a dialog box on an otherwise empty screen.
+func (m model) renderDialog(dialog string) string {
+	return lipgloss.Place(m.width, m.height, lipgloss.Center, lipgloss.Center, dialog)
+}
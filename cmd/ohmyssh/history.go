@@ -0,0 +1,178 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os/exec"
+	"time"
+
+	"github.com/pozgo/OhMySSH/pkg/parser"
+	"github.com/pozgo/OhMySSH/pkg/state"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// maxRecentSessions is how many sessions of a host the history screen lists.
+const maxRecentSessions = 15
+
+// recordConnection appends a finished ssh session to the connection log and
+// counts it in the usage stats. The logged hostname is the one config
+// resolves the alias to. Failing to log never stops the user from
+// connecting, so errors are only reported.
+func recordConnection(config *parser.SSHConfig, host parser.Host, start, end time.Time, runErr error) {
+	c := state.Connection{
+		Alias:    host.Name,
+		Hostname: config.ResolveHostname(host.Patterns()[0]),
+		Start:    start,
+		End:      end,
+	}
+	var exitErr *exec.ExitError
+	if errors.As(runErr, &exitErr) {
+		c.ExitCode = exitErr.ExitCode()
+	} else if runErr != nil {
+		c.Error = runErr.Error()
+	}
+	if err := state.AppendConnection(state.HistoryPath(), c); err != nil {
+		log.Printf("Warning: %v", err)
+	}
+
+	// Reload rather than reuse the TUI's copy, which may be stale by now
+	appState, err := state.Load(state.DefaultPath())
+	if err == nil {
+		appState.RecordConnection(host.Name, start)
+		err = appState.Save()
+	}
+	if err != nil {
+		log.Printf("Warning: %v", err)
+	}
+}
+
+func (m model) openHistory() (tea.Model, tea.Cmd) {
+	connections, err := state.ReadHistory(state.HistoryPath())
+	if err != nil {
+		m.setError(err)
+		return m, nil
+	}
+	m.history = connections
+	m.historyIdx = 0
+	m.currentMode = modeHistory
+	return m, nil
+}
+
+func (m model) handleHistoryKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	summaries := state.Summarize(m.history)
+	switch msg.String() {
+	case "esc", "q", "H":
+		m.currentMode = modeNormal
+	case "up", "k":
+		if m.historyIdx > 0 {
+			m.historyIdx--
+		}
+	case "down", "j":
+		if m.historyIdx < len(summaries)-1 {
+			m.historyIdx++
+		}
+	case "enter":
+		// Jump to the host in the server list
+		if m.historyIdx < len(summaries) {
+			m.currentMode = modeNormal
+			m.selectHost(summaries[m.historyIdx].Alias)
+		}
+	}
+	return m, nil
+}
+
+func (m model) renderHistory() string {
+	summaries := state.Summarize(m.history)
+
+	leftWidth := int(float64(m.width) * 0.45)
+	rightWidth := m.width - leftWidth
+	height := m.height - 1
+
+	var items []string
+	if len(summaries) == 0 {
+		items = append(items, "No connections recorded yet")
+	} else {
+		items = append(items, lipgloss.NewStyle().Bold(true).Render(
+			fmt.Sprintf("%-20s %6s %6s  %s", "HOST", "COUNT", "FAIL", "LAST CONNECTED")))
+	}
+	visible := height - 6
+	start := scrollWindow(m.historyIdx, len(summaries), visible)
+	for i := start; i < len(summaries) && i < start+visible; i++ {
+		summary := summaries[i]
+		line := fmt.Sprintf("%-20s %6d %5.0f%%  %s", truncate(summary.Alias, 20), summary.Connections,
+			summary.FailureRate()*100, summary.LastConnected.Local().Format("2006-01-02 15:04"))
+
+		style := lipgloss.NewStyle().MaxWidth(leftWidth - 5)
+		if _, ok := m.hostByName(summary.Alias); !ok {
+			// No longer in the SSH config
+			style = style.Foreground(lipgloss.Color("241"))
+		}
+		if i == m.historyIdx {
+			style = style.Bold(true).Foreground(lipgloss.Color("15")).Background(lipgloss.Color("62"))
+		}
+		items = append(items, style.Render(line))
+	}
+
+	var details []string
+	if m.historyIdx < len(summaries) {
+		summary := summaries[m.historyIdx]
+		details = append(details,
+			fmt.Sprintf("Host: %s", summary.Alias),
+			fmt.Sprintf("Hostname: %s", summary.Hostname),
+			fmt.Sprintf("Connections: %d", summary.Connections),
+			fmt.Sprintf("Failures: %d (%.0f%%)", summary.Failures, summary.FailureRate()*100),
+			fmt.Sprintf("Total time: %s", formatDuration(summary.TotalTime)),
+			"",
+			lipgloss.NewStyle().Bold(true).Render("Recent sessions"),
+		)
+		details = append(details, m.recentSessions(summary.Alias)...)
+	}
+
+	list := renderPanel("📈 CONNECTION HISTORY", lipgloss.Color("62"), leftWidth, height, items)
+	detail := renderPanel("🕘 SESSIONS", lipgloss.Color("214"), rightWidth, height, details)
+	helpBar := m.renderHelpBar("↑↓: select host", "enter: go to host", "esc/q: back")
+
+	return lipgloss.JoinVertical(lipgloss.Left, lipgloss.JoinHorizontal(lipgloss.Top, list, detail), helpBar)
+}
+
+// recentSessions lists the latest sessions of a host, newest first.
+func (m model) recentSessions(alias string) []string {
+	failedStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("196"))
+
+	var lines []string
+	for i := len(m.history) - 1; i >= 0 && len(lines) < maxRecentSessions; i-- {
+		c := m.history[i]
+		if c.Alias != alias {
+			continue
+		}
+		status := fmt.Sprintf("exit %d", c.ExitCode)
+		if c.Error != "" {
+			status = c.Error
+		}
+		line := fmt.Sprintf("%s  %8s  %s", c.Start.Local().Format("2006-01-02 15:04"), formatDuration(c.Duration()), status)
+		if c.Failed() {
+			line = failedStyle.Render(line)
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// formatDuration rounds a duration to whole seconds for display.
+func formatDuration(d time.Duration) string {
+	if d < 0 {
+		d = 0
+	}
+	return d.Round(time.Second).String()
+}
+
+func truncate(s string, width int) string {
+	runes := []rune(s)
+	if len(runes) <= width {
+		return s
+	}
+	return string(runes[:width-1]) + "…"
+}
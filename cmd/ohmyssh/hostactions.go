@@ -0,0 +1,310 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/pozgo/OhMySSH/pkg/parser"
+	"github.com/pozgo/OhMySSH/pkg/revision"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func (m model) openEditHostForm() (tea.Model, tea.Cmd) {
+	host, ok := m.currentHost()
+	if !ok {
+		return m, nil
+	}
+
+	m.form = newForm(formEditHost, "Edit host "+host.Name, host.Name,
+		newFormField("HostName", "HostName", host.Hostname),
+		newFormField("User", "User", host.User),
+		newFormField("Port", "Port", host.Port),
+		newFormField("IdentityFile", "IdentityFile", host.Options["identityfile"]),
+		newFormField("ProxyJump", "ProxyJump", host.Options["proxyjump"]),
+		newFormField("Group", "group", host.Meta.Group),
+		newFormField("Tags", "tags", strings.Join(host.Meta.Tags, ", ")),
+		newFormField("Owner", "owner", host.Meta.Owner),
+		newMultilineFormField("Notes", "notes", host.Meta.Notes),
+	)
+	m.currentMode = modeForm
+	return m, nil
+}
+
+func (m model) openDuplicateHostForm() (tea.Model, tea.Cmd) {
+	host, ok := m.currentHost()
+	if !ok {
+		return m, nil
+	}
+
+	m.form = newForm(formDuplicateHost, "Duplicate host "+host.Name, host.Name,
+		newFormField("New alias", "alias", host.Name+"-copy"),
+	)
+	m.currentMode = modeForm
+	return m, nil
+}
+
+func (m model) openRenameHostForm() (tea.Model, tea.Cmd) {
+	host, ok := m.currentHost()
+	if !ok {
+		return m, nil
+	}
+
+	m.form = newForm(formRenameHost, "Rename host "+host.Name, host.Name,
+		newFormField("New alias", "alias", host.Patterns()[0]),
+	)
+	m.currentMode = modeForm
+	return m, nil
+}
+
+func (m model) confirmDeleteHost() (tea.Model, tea.Cmd) {
+	host, ok := m.currentHost()
+	if !ok {
+		return m, nil
+	}
+
+	m.confirm = confirmation{
+		kind:    confirmDeleteHost,
+		target:  host.Name,
+		message: fmt.Sprintf("Delete host %q from %s?", host.Name, host.Source),
+	}
+	m.currentMode = modeConfirm
+	return m, nil
+}
+
+func (m model) handleFormKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc":
+		m.currentMode = m.form.returnTo
+		return m, nil
+	case "ctrl+s":
+		return m.submitForm()
+	case "enter":
+		if !m.form.focusedMultiline() {
+			return m.submitForm()
+		}
+	}
+
+	var cmd tea.Cmd
+	m.form, cmd = m.form.update(msg)
+	return m, cmd
+}
+
+func (m model) submitForm() (tea.Model, tea.Cmd) {
+	if m.form.kind == formPromoteHost {
+		return m.submitPromote()
+	}
+	if m.form.kind == formAddAgentKey {
+		return m.submitAgentKey()
+	}
+	if m.form.kind == formGenerateKey {
+		return m.submitGenerateKey()
+	}
+	if m.form.kind == formAddTunnel {
+		return m.submitAddTunnel()
+	}
+	if m.form.kind == formUpload || m.form.kind == formDownload {
+		return m.submitTransfer()
+	}
+	if m.form.kind == formConnectOptions {
+		return m.submitConnectOptions()
+	}
+	host, ok := m.hostByName(m.form.target)
+	if !ok {
+		m.form.err = fmt.Errorf("%w: %s", parser.ErrHostNotFound, m.form.target)
+		return m, nil
+	}
+	before, err := m.readConfigFile(host.Source)
+	if err != nil {
+		m.form.err = err
+		return m, nil
+	}
+
+	var (
+		content    string
+		status     string
+		selectName = host.Name
+		changes    []revision.FileChange
+	)
+
+	switch m.form.kind {
+	case formEditHost:
+		if port := m.form.value("Port"); port != "" {
+			if n, convErr := strconv.Atoi(port); convErr != nil || n < 1 || n > 65535 {
+				err = fmt.Errorf("invalid port %q", port)
+				break
+			}
+		}
+		content, err = parser.UpdateHost(before, host.Name, m.editHostChanges(host))
+		if meta := m.editHostMeta(host); err == nil && !reflect.DeepEqual(meta, host.Annotations()) {
+			content, err = parser.SetHostMeta(content, host.Name, meta)
+		}
+		status = fmt.Sprintf("Updated host %s", host.Name)
+	case formDuplicateHost:
+		selectName = m.form.value("alias")
+		if _, exists := m.hostByName(selectName); exists {
+			err = fmt.Errorf("%w: %s", parser.ErrHostExists, selectName)
+			break
+		}
+		content, err = parser.DuplicateHost(before, host.Name, selectName)
+		status = fmt.Sprintf("Duplicated %s as %s", host.Name, selectName)
+	case formRenameHost:
+		// Only the alias, the first pattern, is renamed
+		patterns := host.Patterns()
+		alias := m.form.value("alias")
+		if alias == patterns[0] {
+			m.currentMode = modeNormal
+			return m, nil
+		}
+		selectName = strings.Join(append([]string{alias}, patterns[1:]...), " ")
+		for _, name := range []string{alias, selectName} {
+			if _, exists := m.hostByName(name); exists {
+				err = fmt.Errorf("%w: %s", parser.ErrHostExists, name)
+			}
+		}
+		if err != nil {
+			break
+		}
+		content, err = parser.RenameHost(before, host.Name, alias)
+		status = fmt.Sprintf("Renamed %s to %s", patterns[0], alias)
+		if err == nil {
+			changes, err = m.renameReferencesElsewhere(host.Source, patterns[0], alias)
+		}
+	}
+
+	if err == nil {
+		rev := revision.Revision{
+			Label:   status,
+			Changes: append([]revision.FileChange{{Path: host.Source, Before: before, After: content}}, changes...),
+		}
+		// Favorites, usage stats and tunnels are keyed by alias
+		if m.form.kind == formRenameHost {
+			rev.Renames = []revision.Rename{{From: host.Name, To: selectName}}
+		}
+		err = m.applyRevision(rev)
+	}
+	if err != nil {
+		m.form.err = err
+		return m, nil
+	}
+
+	m.selectHost(selectName)
+	if !m.statusIsError {
+		m.setStatus(status)
+	}
+	m.currentMode = modeNormal
+	return m, nil
+}
+
+// renameReferencesElsewhere updates ProxyJump hops in every loaded config file
+// other than skip.
+func (m model) renameReferencesElsewhere(skip, oldName, newName string) ([]revision.FileChange, error) {
+	var changes []revision.FileChange
+	for _, path := range m.sshConfig.Files() {
+		if path == skip {
+			continue
+		}
+		before, err := m.readConfigFile(path)
+		if err != nil {
+			return nil, err
+		}
+		if after := parser.RenameJumpReferences(before, oldName, newName); after != before {
+			changes = append(changes, revision.FileChange{Path: path, Before: before, After: after})
+		}
+	}
+	return changes, nil
+}
+
+// editHostChanges collects the directives whose value differs from the host.
+func (m model) editHostChanges(host parser.Host) []parser.Directive {
+	current := map[string]string{
+		"HostName":     host.Hostname,
+		"User":         host.User,
+		"Port":         host.Port,
+		"IdentityFile": host.Options["identityfile"],
+		"ProxyJump":    host.Options["proxyjump"],
+	}
+
+	var changes []parser.Directive
+	for _, field := range m.form.fields {
+		original, isDirective := current[field.key]
+		if value := m.form.value(field.key); isDirective && value != original {
+			changes = append(changes, parser.Directive{Key: field.key, Value: value})
+		}
+	}
+	return changes
+}
+
+// editHostMeta builds the host's annotations from the edit form, keeping any
+// extra annotation keys the form does not show. A group inherited from the
+// Include file is left to the file name unless it was changed, so that
+// moving the host to another file still regroups it.
+func (m model) editHostMeta(host parser.Host) parser.Meta {
+	meta := parser.Meta{
+		Group: m.form.value("group"),
+		Owner: m.form.value("owner"),
+		Notes: m.form.value("notes"),
+		Extra: host.Meta.Extra,
+	}
+	if host.InheritedGroup && meta.Group == host.Meta.Group {
+		meta.Group = ""
+	}
+	for _, tag := range strings.Split(m.form.value("tags"), ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			meta.Tags = append(meta.Tags, tag)
+		}
+	}
+	return meta
+}
+
+func (m model) handleConfirmKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "y", "Y":
+		m.currentMode = m.confirm.returnTo
+		return m.runConfirmed()
+	case "n", "N", "esc", "q":
+		m.currentMode = m.confirm.returnTo
+		return m, nil
+	}
+	return m, nil
+}
+
+func (m model) runConfirmed() (tea.Model, tea.Cmd) {
+	switch m.confirm.kind {
+	case confirmDeleteHost:
+		status := fmt.Sprintf("Deleted host %s", m.confirm.target)
+		err := m.applyHostEdit(m.confirm.target, status, func(content string) (string, error) {
+			return parser.DeleteHost(content, m.confirm.target)
+		})
+		if err != nil {
+			m.setError(err)
+			return m, nil
+		}
+		m.setStatus(status)
+	case confirmRemoveKnownHosts:
+		return m.removeKnownHosts(m.confirm.entries)
+	case confirmDeleteTunnel:
+		return m.deleteTunnel(m.confirm.target)
+	}
+	return m, nil
+}
+
+// applyHostEdit runs edit on the content of the file that defines the named
+// host and applies the result as a single undoable step.
+func (m *model) applyHostEdit(name, label string, edit func(content string) (string, error)) error {
+	host, ok := m.hostByName(name)
+	if !ok {
+		return fmt.Errorf("%w: %s", parser.ErrHostNotFound, name)
+	}
+	before, err := m.readConfigFile(host.Source)
+	if err != nil {
+		return err
+	}
+	after, err := edit(before)
+	if err != nil {
+		return err
+	}
+	return m.applyConfigChanges(label, []revision.FileChange{{Path: host.Source, Before: before, After: after}})
+}
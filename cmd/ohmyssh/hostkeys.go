@@ -0,0 +1,290 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/pozgo/OhMySSH/pkg/knownhosts"
+	"github.com/pozgo/OhMySSH/pkg/parser"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// keyScanTimeout is passed to ssh-keyscan -T, in seconds.
+const keyScanTimeout = "5"
+
+// knownHostsView is the known_hosts panel of one host: the stored entries
+// and, once fetched, the keys the server currently offers.
+type knownHostsView struct {
+	alias    string
+	host     string
+	port     string
+	jump     string
+	entries  []knownhosts.Entry
+	selected int
+	offered  []knownhosts.Entry
+	scanned  bool
+	scanning bool
+	scanErr  error
+}
+
+// keyScanMsg delivers the result of ssh-keyscan.
+type keyScanMsg struct {
+	host, port string
+	offered    []knownhosts.Entry
+	err        error
+}
+
+// hostKeyTarget returns the name and port ssh looks up in known_hosts for a
+// host: HostKeyAlias if set, otherwise the effective HostName.
+func hostKeyTarget(config *parser.SSHConfig, host parser.Host) (name, port string) {
+	alias := host.Patterns()[0]
+	values := config.Effective(alias)
+	name = values["hostkeyalias"]
+	if name == "" {
+		name = values["hostname"]
+	}
+	if name == "" {
+		name = alias
+	}
+	port = values["port"]
+	if port == "" {
+		port = "22"
+	}
+	return name, port
+}
+
+func (m model) openKnownHosts() (tea.Model, tea.Cmd) {
+	host, ok := m.currentHost()
+	if !ok {
+		return m, nil
+	}
+	name, port := hostKeyTarget(m.sshConfig, host)
+	m.knownHosts = knownHostsView{alias: host.Name, host: name, port: port}
+	if jump := m.sshConfig.Effective(host.Patterns()[0])["proxyjump"]; !strings.EqualFold(jump, "none") {
+		m.knownHosts.jump = jump
+	}
+	if err := m.loadKnownHostEntries(); err != nil {
+		m.setError(err)
+		return m, nil
+	}
+	m.currentMode = modeKnownHosts
+	return m, nil
+}
+
+func (m *model) loadKnownHostEntries() error {
+	entries, err := knownhosts.ReadFile(knownhosts.DefaultPath())
+	if err != nil {
+		return fmt.Errorf("failed to read known_hosts: %v", err)
+	}
+	m.knownHosts.entries = knownhosts.Lookup(entries, m.knownHosts.host, m.knownHosts.port)
+	if m.knownHosts.selected >= len(m.knownHosts.entries) {
+		m.knownHosts.selected = len(m.knownHosts.entries) - 1
+	}
+	if m.knownHosts.selected < 0 {
+		m.knownHosts.selected = 0
+	}
+	return nil
+}
+
+// scanHostKeys asks the server for its host keys with ssh-keyscan. It
+// connects directly, so hosts behind a ProxyJump may not be reachable.
+func scanHostKeys(host, port string) tea.Cmd {
+	return func() tea.Msg {
+		var stdout, stderr bytes.Buffer
+		cmd := exec.Command("ssh-keyscan", "-T", keyScanTimeout, "-p", port, host)
+		cmd.Stdout = &stdout
+		cmd.Stderr = &stderr
+		runErr := cmd.Run()
+
+		offered := knownhosts.Parse(stdout.Bytes())
+		if len(offered) > 0 {
+			return keyScanMsg{host: host, port: port, offered: offered}
+		}
+		// ssh-keyscan exits with 1 and says nothing when the host is down
+		var exitErr *exec.ExitError
+		if runErr != nil && !errors.As(runErr, &exitErr) {
+			return keyScanMsg{host: host, port: port, err: fmt.Errorf("failed to run ssh-keyscan: %v", runErr)}
+		}
+		err := fmt.Errorf("no keys received from %s", knownhosts.JoinHostPort(host, port))
+		if detail := strings.TrimSpace(stderr.String()); detail != "" {
+			err = fmt.Errorf("%v: %s", err, detail)
+		}
+		return keyScanMsg{host: host, port: port, err: err}
+	}
+}
+
+func (m model) handleKnownHostsKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	m.status = ""
+	v := &m.knownHosts
+	switch msg.String() {
+	case "esc", "q", "K":
+		m.currentMode = modeNormal
+	case "up", "k":
+		if v.selected > 0 {
+			v.selected--
+		}
+	case "down", "j":
+		if v.selected < len(v.entries)-1 {
+			v.selected++
+		}
+	case "f":
+		if !v.scanning {
+			v.scanning = true
+			v.scanErr = nil
+			return m, scanHostKeys(v.host, v.port)
+		}
+	case "x", "delete":
+		if v.selected < len(v.entries) {
+			entry := v.entries[v.selected]
+			return m.confirmRemoveKnownHosts([]knownhosts.Entry{entry},
+				fmt.Sprintf("Remove the %s key of %s (line %d) from known_hosts?", entry.KeyType, strings.Join(entry.Hosts, ","), entry.Line))
+		}
+	case "X":
+		// Certificate authorities usually cover many hosts, so keep them
+		var entries []knownhosts.Entry
+		for _, entry := range v.entries {
+			if entry.Marker != knownhosts.MarkerCertAuthority {
+				entries = append(entries, entry)
+			}
+		}
+		if len(entries) > 0 {
+			return m.confirmRemoveKnownHosts(entries,
+				fmt.Sprintf("Remove all %d known_hosts entries of %s?", len(entries), knownhosts.JoinHostPort(v.host, v.port)))
+		}
+	}
+	return m, nil
+}
+
+func (m model) confirmRemoveKnownHosts(entries []knownhosts.Entry, message string) (tea.Model, tea.Cmd) {
+	m.confirm = confirmation{
+		kind:     confirmRemoveKnownHosts,
+		target:   m.knownHosts.alias,
+		message:  message,
+		entries:  entries,
+		returnTo: modeKnownHosts,
+	}
+	m.currentMode = modeConfirm
+	return m, nil
+}
+
+func (m model) removeKnownHosts(entries []knownhosts.Entry) (tea.Model, tea.Cmd) {
+	path := knownhosts.DefaultPath()
+	if err := knownhosts.Remove(path, entries); err != nil {
+		// Show what the file holds now, so that the user can try again
+		if errors.Is(err, knownhosts.ErrChanged) {
+			if loadErr := m.loadKnownHostEntries(); loadErr != nil {
+				err = loadErr
+			}
+		}
+		m.setError(err)
+		return m, nil
+	}
+	if err := m.loadKnownHostEntries(); err != nil {
+		m.setError(err)
+		return m, nil
+	}
+	m.setStatus(fmt.Sprintf("Removed %d known_hosts entries of %s, backup in %s.old", len(entries), m.knownHosts.host, path))
+	return m, nil
+}
+
+// shortKeyType drops the common prefixes of key types so that the
+// fingerprint fits next to it, e.g. "ecdsa-sha2-nistp256" becomes "ecdsa-256".
+func shortKeyType(keyType string) string {
+	keyType = strings.TrimPrefix(keyType, "ssh-")
+	return strings.Replace(keyType, "ecdsa-sha2-nistp", "ecdsa-", 1)
+}
+
+func (m model) renderKnownHosts() string {
+	v := m.knownHosts
+	leftWidth := int(float64(m.width) * 0.5)
+	rightWidth := m.width - leftWidth
+	height := m.height - 1
+	dim := lipgloss.NewStyle().Foreground(lipgloss.Color("241"))
+
+	var items []string
+	if len(v.entries) == 0 {
+		items = append(items, fmt.Sprintf("No keys stored for %s", knownhosts.JoinHostPort(v.host, v.port)))
+	}
+	for i, entry := range v.entries {
+		marker := ""
+		switch entry.Marker {
+		case knownhosts.MarkerCertAuthority:
+			marker = "CA"
+		case knownhosts.MarkerRevoked:
+			marker = "REVOKED"
+		}
+		line := fmt.Sprintf("%-7s %-10s %s", marker, truncate(shortKeyType(entry.KeyType), 10), entry.Fingerprint())
+		style := lipgloss.NewStyle().MaxWidth(leftWidth - 5)
+		if i == v.selected {
+			style = style.Bold(true).Foreground(lipgloss.Color("15")).Background(lipgloss.Color("62"))
+		}
+		items = append(items, style.Render(line))
+	}
+	if v.selected < len(v.entries) {
+		entry := v.entries[v.selected]
+		names := strings.Join(entry.Hosts, ", ")
+		if entry.IsHashed() {
+			names = "(hashed)"
+		}
+		items = append(items, "", dim.Render(fmt.Sprintf("Line %d: %s", entry.Line, names)))
+		if entry.Comment != "" {
+			items = append(items, dim.Render(entry.Comment))
+		}
+	}
+	if m.status != "" {
+		statusStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("46"))
+		if m.statusIsError {
+			statusStyle = statusStyle.Foreground(lipgloss.Color("196"))
+		}
+		items = append(items, "", statusStyle.Render(m.status))
+	}
+
+	var details []string
+	if v.jump != "" {
+		details = append(details, dim.Render(fmt.Sprintf("Behind ProxyJump %s: keys are fetched directly and may not be reachable", v.jump)), "")
+	}
+	switch {
+	case v.scanning:
+		details = append(details, fmt.Sprintf("Fetching keys from %s...", knownhosts.JoinHostPort(v.host, v.port)))
+	case v.scanErr != nil:
+		details = append(details, lipgloss.NewStyle().Foreground(lipgloss.Color("196")).Render(v.scanErr.Error()))
+	case !v.scanned:
+		details = append(details, "Press f to fetch the keys the server offers now and compare them")
+	default:
+		changed := false
+		for _, check := range knownhosts.Compare(v.entries, v.offered) {
+			color := lipgloss.Color("46")
+			switch check.Status {
+			case knownhosts.KeyChanged, knownhosts.KeyRevoked:
+				color = lipgloss.Color("196")
+				changed = true
+			case knownhosts.KeyNew, knownhosts.KeyNotOffered:
+				color = lipgloss.Color("214")
+			}
+			details = append(details,
+				lipgloss.NewStyle().Bold(true).Foreground(color).Render(fmt.Sprintf("%s: %s", check.KeyType, check.Status)))
+			if check.Stored != "" {
+				details = append(details, "  stored:  "+check.Stored)
+			}
+			if check.Offered != "" && check.Offered != check.Stored {
+				details = append(details, "  offered: "+check.Offered)
+			}
+		}
+		if changed {
+			details = append(details, "", lipgloss.NewStyle().Foreground(lipgloss.Color("196")).Render(
+				"The server key changed. If the server was rebuilt, remove the stale entries (X) and connect to accept the new key."))
+		}
+	}
+
+	title := fmt.Sprintf("🔐 KNOWN HOSTS: %s (%s)", v.alias, knownhosts.JoinHostPort(v.host, v.port))
+	list := renderPanel(title, lipgloss.Color("62"), leftWidth, height, items)
+	detail := renderPanel("🌐 SERVER KEYS", lipgloss.Color("214"), rightWidth, height, details)
+	helpBar := m.renderHelpBar("↑↓: select key", "x: remove key", "X: remove all", "f: fetch & compare", "esc/q: back")
+
+	return lipgloss.JoinVertical(lipgloss.Left, lipgloss.JoinHorizontal(lipgloss.Top, list, detail), helpBar)
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pozgo/OhMySSH/pkg/parser"
+)
+
+// jumpDetails shows the route ssh takes to host through its jump hosts, and
+// the hosts that use host as a jump host.
+func (m model) jumpDetails(host parser.Host) []string {
+	var details []string
+	alias := host.Patterns()[0]
+	chain, err := m.sshConfig.JumpChain(alias)
+	if err != nil {
+		details = append(details, "Route: ⚠️ "+err.Error())
+	} else if len(chain) > 1 {
+		route := []string{"you"}
+		for _, hop := range chain {
+			route = append(route, hop.String())
+		}
+		details = append(details, "Route: "+strings.Join(route, " → "))
+		for _, hop := range chain[:len(chain)-1] {
+			if hop.Missing() {
+				details = append(details, fmt.Sprintf("  ⚠️ jump host %s has no Host entry", hop.Name))
+			}
+		}
+	}
+	if users := m.sshConfig.JumpUsers(alias); len(users) > 0 {
+		details = append(details, "Jump host for: "+strings.Join(users, ", "))
+	}
+	return details
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"os/user"
+	"regexp"
+	"strings"
+
+	"github.com/pozgo/OhMySSH/pkg/parser"
+	"github.com/pozgo/OhMySSH/pkg/sshkeys"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// installKeyScript appends the public key read from stdin to the remote
+// authorized_keys unless it is already there, like ssh-copy-id. It runs
+// under sh whatever the login shell is, so it must not contain single quotes.
+const installKeyScript = `umask 077; mkdir -p .ssh && key=$(cat) && f=.ssh/authorized_keys && touch $f && ` +
+	`{ grep -qxF "$key" $f || { [ -n "$(tail -c 1 $f)" ] && echo >> $f; printf "%s\n" "$key" >> $f; }; }`
+
+var unsafeFileChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// keyInstallMsg reports the end of the ssh session that installs a key.
+type keyInstallMsg struct {
+	alias string
+	file  string // IdentityFile value as entered in the form
+	err   error
+}
+
+// defaultKeyComment is the comment ssh-keygen uses: user@hostname.
+func defaultKeyComment() string {
+	name := "user"
+	if current, err := user.Current(); err == nil {
+		name = current.Username
+	}
+	hostname, err := os.Hostname()
+	if err != nil {
+		return name
+	}
+	return name + "@" + hostname
+}
+
+func newPasswordField(label, key string) formField {
+	field := newFormField(label, key, "")
+	field.input.EchoMode = textinput.EchoPassword
+	field.input.EchoCharacter = '•'
+	return field
+}
+
+func (m model) openGenerateKeyForm() (tea.Model, tea.Cmd) {
+	host, ok := m.currentHost()
+	if !ok {
+		return m, nil
+	}
+	file := "~/.ssh/id_ed25519_" + unsafeFileChars.ReplaceAllString(host.Name, "_")
+	m.form = newForm(formGenerateKey, "Generate a key and install it on "+host.Name, host.Name,
+		newFormField("Key file", "file", file),
+		newFormField("Comment", "comment", defaultKeyComment()),
+		newPasswordField("Passphrase", "passphrase"),
+		newPasswordField("Repeat", "repeat"),
+	)
+	m.form.help = "tab/↑↓: next field | enter: generate and install | esc: cancel"
+	m.currentMode = modeForm
+	return m, nil
+}
+
+// submitGenerateKey writes the new key pair and hands the terminal to ssh,
+// which logs in with the host's current settings to install the public key.
+func (m model) submitGenerateKey() (tea.Model, tea.Cmd) {
+	host, ok := m.hostByName(m.form.target)
+	if !ok {
+		m.form.err = fmt.Errorf("%w: %s", parser.ErrHostNotFound, m.form.target)
+		return m, nil
+	}
+	file := m.form.value("file")
+	if file == "" {
+		m.form.err = fmt.Errorf("key file is required")
+		return m, nil
+	}
+	// Passphrases may start or end with spaces, so do not use form.value
+	passphrase := m.form.fields[2].input.Value()
+	if passphrase != m.form.fields[3].input.Value() {
+		m.form.err = fmt.Errorf("passphrases do not match")
+		return m, nil
+	}
+
+	key, err := sshkeys.Generate(parser.ExpandHome(file), m.form.value("comment"), []byte(passphrase))
+	if err != nil {
+		m.form.err = err
+		return m, nil
+	}
+	public, err := ioutil.ReadFile(key.PublicPath)
+	if err != nil {
+		m.form.err = err
+		return m, nil
+	}
+
+	m.currentMode = modeNormal
+	m.setStatus(fmt.Sprintf("Generated %s, installing it on %s...", file, host.Name))
+	cmd := exec.Command("ssh", "--", host.Name, "exec sh -c '"+installKeyScript+"'")
+	cmd.Stdin = strings.NewReader(string(public))
+	alias := host.Name
+	return m, tea.ExecProcess(cmd, func(err error) tea.Msg {
+		return keyInstallMsg{alias: alias, file: file, err: err}
+	})
+}
+
+// finishKeyInstall points the host at the new key once it is installed, so
+// that ssh offers only this key from now on.
+func (m model) finishKeyInstall(msg keyInstallMsg) (tea.Model, tea.Cmd) {
+	if msg.err != nil {
+		m.setError(fmt.Errorf("generated %s but failed to install it on %s: %v", msg.file, msg.alias, msg.err))
+		return m, nil
+	}
+	value := msg.file
+	if strings.ContainsAny(value, " \t") {
+		value = `"` + value + `"`
+	}
+	label := fmt.Sprintf("Set key of %s to %s", msg.alias, msg.file)
+	err := m.applyHostEdit(msg.alias, label, func(content string) (string, error) {
+		return parser.UpdateHost(content, msg.alias, []parser.Directive{
+			{Key: "IdentityFile", Value: value},
+			{Key: "IdentitiesOnly", Value: "yes"},
+		})
+	})
+	if err != nil {
+		m.setError(fmt.Errorf("installed %s on %s but failed to update the config: %v", msg.file, msg.alias, err))
+		return m, nil
+	}
+	m.selectHost(msg.alias)
+	m.setStatus(fmt.Sprintf("Installed %s on %s and set it as its IdentityFile", msg.file, msg.alias))
+	return m, loadAgent(m.sshConfig)
+}
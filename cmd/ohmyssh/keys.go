@@ -0,0 +1,229 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pozgo/OhMySSH/pkg/parser"
+	"github.com/pozgo/OhMySSH/pkg/sshkeys"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// identityProblem is an IdentityFile that ssh could not use.
+type identityProblem struct {
+	host string
+	path string
+	err  error
+}
+
+// keyReport is the key inventory: the keys found, the hosts using each key
+// by path, and IdentityFile settings that point at missing or unsafe files.
+type keyReport struct {
+	keys     []sshkeys.Key
+	refs     map[string][]string
+	problems []identityProblem
+}
+
+// identityPath expands an IdentityFile value to a path. Values using ssh
+// tokens such as %h depend on the connection and cannot be checked.
+func identityPath(value string) (string, bool) {
+	value = strings.Trim(value, `"`)
+	if value == "" || strings.Contains(value, "%") {
+		return "", false
+	}
+	return parser.ExpandHome(value), true
+}
+
+// identityWarning is appended to the IdentityFile line of the details panel
+// when ssh could not use the file.
+func identityWarning(value string) string {
+	path, ok := identityPath(value)
+	if !ok {
+		return ""
+	}
+	if err := sshkeys.CheckIdentityFile(path); err != nil {
+		return " ⚠️ " + err.Error()
+	}
+	return ""
+}
+
+func sshDir() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ".ssh"
+	}
+	return filepath.Join(home, ".ssh")
+}
+
+// buildKeyReport scans dir for keys and matches them with the effective
+// IdentityFile of every host. Keys referenced from outside dir are listed
+// too.
+func buildKeyReport(config *parser.SSHConfig, dir string) (keyReport, error) {
+	keys, err := sshkeys.Scan(dir)
+	if err != nil && !os.IsNotExist(err) {
+		return keyReport{}, fmt.Errorf("failed to scan %s: %v", dir, err)
+	}
+	report := keyReport{keys: keys, refs: make(map[string][]string)}
+	known := make(map[string]bool)
+	for _, key := range keys {
+		known[key.Path] = true
+	}
+
+	for _, host := range config.GetHosts() {
+		if host.IsWildcard() {
+			continue
+		}
+		path, ok := identityPath(config.Effective(host.Patterns()[0])["identityfile"])
+		if !ok {
+			continue
+		}
+		report.refs[path] = append(report.refs[path], host.Name)
+		if err := sshkeys.CheckIdentityFile(path); err != nil {
+			report.problems = append(report.problems, identityProblem{host: host.Name, path: path, err: err})
+		}
+		if !known[path] {
+			known[path] = true
+			if key, err := sshkeys.Inspect(path); err == nil {
+				report.keys = append(report.keys, key)
+			}
+		}
+	}
+	return report, nil
+}
+
+// keyHosts lists the hosts that use a key, sorted.
+func (r keyReport) keyHosts(key sshkeys.Key) []string {
+	hosts := append([]string(nil), r.refs[key.Path]...)
+	sort.Strings(hosts)
+	return hosts
+}
+
+func (m model) openKeys() (tea.Model, tea.Cmd) {
+	report, err := buildKeyReport(m.sshConfig, sshDir())
+	if err != nil {
+		m.setError(err)
+		return m, nil
+	}
+	m.keys = report
+	m.keysIdx = 0
+	m.currentMode = modeKeys
+	return m, nil
+}
+
+func (m model) handleKeysKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc", "q", "I":
+		m.currentMode = modeNormal
+	case "up", "k":
+		if m.keysIdx > 0 {
+			m.keysIdx--
+		}
+	case "down", "j":
+		if m.keysIdx < len(m.keys.keys)-1 {
+			m.keysIdx++
+		}
+	}
+	return m, nil
+}
+
+func (m model) renderKeys() string {
+	keys := m.keys.keys
+	leftWidth := int(float64(m.width) * 0.45)
+	rightWidth := m.width - leftWidth
+	height := m.height - 1
+	warn := lipgloss.NewStyle().Foreground(lipgloss.Color("196"))
+
+	var items []string
+	if len(keys) == 0 {
+		items = append(items, fmt.Sprintf("No keys found in %s", sshDir()))
+	}
+	visible := height - 8 - len(m.keys.problems)
+	start := scrollWindow(m.keysIdx, len(keys), visible)
+	for i := start; i < len(keys) && i < start+visible; i++ {
+		key := keys[i]
+		flags := ""
+		if key.Encrypted {
+			flags += " 🔒"
+		}
+		if key.UnsafePermissions() {
+			flags += " ⚠️"
+		}
+		line := fmt.Sprintf("%-24s %-12s %5s%s", truncate(key.Name(), 24), truncate(shortKeyType(key.Type), 12), bitsLabel(key.Bits), flags)
+		style := lipgloss.NewStyle().MaxWidth(leftWidth - 5)
+		if i == m.keysIdx {
+			style = style.Bold(true).Foreground(lipgloss.Color("15")).Background(lipgloss.Color("62"))
+		}
+		items = append(items, style.Render(line))
+	}
+	if len(m.keys.problems) > 0 {
+		items = append(items, "", lipgloss.NewStyle().Bold(true).Render("⚠️ IdentityFile problems"))
+		for _, problem := range m.keys.problems {
+			items = append(items, warn.Render(fmt.Sprintf("%s: %s %v", problem.host, problem.path, problem.err)))
+		}
+	}
+
+	var details []string
+	if m.keysIdx < len(keys) {
+		key := keys[m.keysIdx]
+		passphrase := "no"
+		if key.Encrypted {
+			passphrase = "yes"
+		}
+		if key.Path != "" {
+			details = append(details, fmt.Sprintf("Private key: %s", key.Path))
+		} else {
+			details = append(details, "Private key: not found")
+		}
+		if key.PublicPath != "" {
+			details = append(details, fmt.Sprintf("Public key: %s", key.PublicPath))
+		}
+		details = append(details,
+			fmt.Sprintf("Type: %s", key.Type),
+			fmt.Sprintf("Bits: %s", bitsLabel(key.Bits)),
+			fmt.Sprintf("Fingerprint: %s", key.Fingerprint),
+		)
+		if key.Comment != "" {
+			details = append(details, fmt.Sprintf("Comment: %s", key.Comment))
+		}
+		if key.Path != "" {
+			details = append(details, fmt.Sprintf("Passphrase: %s", passphrase))
+			permissions := fmt.Sprintf("Permissions: %04o", key.Mode)
+			if key.UnsafePermissions() {
+				details = append(details, warn.Render(permissions+" (too open, ssh will refuse it; chmod 600)"))
+			} else {
+				details = append(details, permissions)
+			}
+		}
+		if m.agent.available && key.Fingerprint != "" {
+			if m.agent.loaded[key.Fingerprint] {
+				details = append(details, "Agent: 🔑 loaded")
+			} else {
+				details = append(details, "Agent: not loaded")
+			}
+		}
+		details = append(details, "", lipgloss.NewStyle().Bold(true).Render("Used by"))
+		hosts := m.keys.keyHosts(key)
+		if len(hosts) == 0 {
+			details = append(details, "no host")
+		}
+		details = append(details, hosts...)
+	}
+
+	list := renderPanel(fmt.Sprintf("🔑 SSH KEYS (%d)", len(keys)), lipgloss.Color("62"), leftWidth, height, items)
+	detail := renderPanel("📄 KEY DETAILS", lipgloss.Color("214"), rightWidth, height, details)
+	helpBar := m.renderHelpBar("↑↓: select key", "🔒 passphrase", "⚠️ unsafe permissions", "esc/q: back")
+
+	return lipgloss.JoinVertical(lipgloss.Left, lipgloss.JoinHorizontal(lipgloss.Top, list, detail), helpBar)
+}
+
+func bitsLabel(bits int) string {
+	if bits == 0 {
+		return "?"
+	}
+	return fmt.Sprintf("%d", bits)
+}
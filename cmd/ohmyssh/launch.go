@@ -0,0 +1,225 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/pozgo/OhMySSH/pkg/launch"
+	"github.com/pozgo/OhMySSH/pkg/parser"
+	"github.com/pozgo/OhMySSH/pkg/settings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// launchMenu asks where to open a session, for one connect only.
+type launchMenu struct {
+	host string
+	idx  int // into launch.Targets
+}
+
+// launchMsg delivers the result of opening a session elsewhere.
+type launchMsg struct {
+	host   string
+	target launch.Target
+	err    error
+}
+
+// defaultTarget is the launch target of the settings, or the current
+// terminal when it is not usable here, e.g. tmux outside of tmux.
+func (m model) defaultTarget() launch.Target {
+	target, err := launch.ParseTarget(m.settings.Launch)
+	if err != nil || m.launcher.Available(target) != nil {
+		return launch.Current
+	}
+	return target
+}
+
+// sessionCommand runs ohmyssh itself to connect, so that sessions opened in
+// other windows print the same banner and are recorded in the history. A
+// failed connection waits for enter, or its window would close at once.
+func sessionCommand(configPath string, host parser.Host, options launch.Options) []string {
+	binary, err := os.Executable()
+	if err != nil {
+		binary = "ohmyssh"
+	}
+	args := []string{binary, "connect", "-hold", "-config", configPath}
+	args = append(args, connectFlags(options)...)
+	return append(args, host.Name)
+}
+
+// connectFlags are the flags of ohmyssh connect that apply options.
+func connectFlags(options launch.Options) []string {
+	var flags []string
+	if options.Client != "" {
+		flags = append(flags, "-client", options.Client)
+	}
+	if options.User != "" {
+		flags = append(flags, "-user", options.User)
+	}
+	if options.Port != "" {
+		flags = append(flags, "-port", options.Port)
+	}
+	for _, option := range options.Options {
+		flags = append(flags, "-o", option)
+	}
+	if options.Verbose > 0 {
+		flags = append(flags, "-verbose", strconv.Itoa(options.Verbose))
+	}
+	if options.ForwardAgent {
+		flags = append(flags, "-forward-agent")
+	}
+	return flags
+}
+
+// hostClient is the client host connects with unless told otherwise: its
+// client= annotation, else the one of the settings, else ssh.
+func hostClient(appSettings settings.Settings, host parser.Host) string {
+	if client := host.Meta.Extra["client"]; client != "" {
+		return client
+	}
+	if appSettings.Client != "" {
+		return appSettings.Client
+	}
+	return launch.SSH
+}
+
+// clientCommand is the command that connects to host with options, built
+// from the effective settings of the host for clients that need them.
+func clientCommand(config *parser.SSHConfig, appSettings settings.Settings, host parser.Host, options launch.Options) ([]string, error) {
+	client := options.Client
+	if client == "" {
+		client = hostClient(appSettings, host)
+	}
+	alias := host.Patterns()[0]
+	effective := config.Effective(alias)
+	endpoint := launch.Endpoint{
+		Alias:    host.Name,
+		HostName: config.ResolveHostname(alias),
+		User:     effective["user"],
+		Port:     effective["port"],
+	}
+	if endpoint.User == "" {
+		endpoint.User = os.Getenv("USER")
+	}
+	if endpoint.Port == "" {
+		endpoint.Port = "22"
+	}
+	return launch.ClientCommand(client, appSettings.Clients, endpoint, options)
+}
+
+func (m model) openLaunchMenu() (tea.Model, tea.Cmd) {
+	host, ok := m.currentHost()
+	if !ok {
+		return m, nil
+	}
+	m.launchMenu = launchMenu{host: host.Name}
+	for i, target := range launch.Targets {
+		if target == m.defaultTarget() {
+			m.launchMenu.idx = i
+		}
+	}
+	m.currentMode = modeLaunch
+	return m, nil
+}
+
+func (m model) handleLaunchKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	key := msg.String()
+	switch key {
+	case "esc", "q":
+		m.currentMode = modeNormal
+	case "up", "k":
+		if m.launchMenu.idx > 0 {
+			m.launchMenu.idx--
+		}
+	case "down", "j":
+		if m.launchMenu.idx < len(launch.Targets)-1 {
+			m.launchMenu.idx++
+		}
+	case "enter":
+		return m.chooseLaunchTarget(m.launchMenu.idx)
+	default:
+		if len(key) == 1 && key[0] >= '1' && int(key[0]-'1') < len(launch.Targets) {
+			return m.chooseLaunchTarget(int(key[0] - '1'))
+		}
+	}
+	return m, nil
+}
+
+func (m model) chooseLaunchTarget(idx int) (tea.Model, tea.Cmd) {
+	target := launch.Targets[idx]
+	if err := m.launcher.Available(target); err != nil {
+		m.launchMenu.idx = idx
+		return m, nil
+	}
+	host, ok := m.hostByName(m.launchMenu.host)
+	m.currentMode = modeNormal
+	if !ok {
+		return m, nil
+	}
+	return m.connectHostIn(host, target, launch.Options{})
+}
+
+// launchSession opens a session to host in target. The current terminal is
+// handed to ssh once the interface quits; other targets open in the
+// background and the interface stays.
+func (m model) launchSession(host parser.Host, target launch.Target, options launch.Options) (tea.Model, tea.Cmd) {
+	m.currentMode = modeNormal
+	if _, err := clientCommand(m.sshConfig, m.settings, host, options); err != nil {
+		m.setError(fmt.Errorf("failed to connect to %s: %v", host.Name, err))
+		return m, nil
+	}
+	if target == launch.Current {
+		m.shouldConnect = true
+		m.selectedHost = host
+		m.connectOptions = options
+		return m, tea.Quit
+	}
+	launcher := m.launcher
+	session := sessionCommand(m.sshConfig.Path, host, options)
+	m.setStatus(fmt.Sprintf("Opening %s in a %s...", host.Name, target.Describe()))
+	return m, func() tea.Msg {
+		err := launcher.Start(target, host.Name, session)
+		return launchMsg{host: host.Name, target: target, err: err}
+	}
+}
+
+func (m model) finishLaunch(msg launchMsg) (tea.Model, tea.Cmd) {
+	if msg.err != nil {
+		m.setError(fmt.Errorf("failed to open %s in a %s: %v", msg.host, msg.target.Describe(), msg.err))
+		return m, nil
+	}
+	m.setStatus(fmt.Sprintf("Opened %s in a %s", msg.host, msg.target.Describe()))
+	return m, nil
+}
+
+func (m model) renderLaunchMenu() string {
+	dim := lipgloss.NewStyle().Foreground(lipgloss.Color("241"))
+	lines := []string{
+		lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("205")).Render("🚀 Open " + m.launchMenu.host + " in"),
+		"",
+	}
+	for i, target := range launch.Targets {
+		line := fmt.Sprintf("%d  %s", i+1, target.Describe())
+		if target == m.defaultTarget() {
+			line += " (default)"
+		}
+		err := m.launcher.Available(target)
+		switch {
+		case i == m.launchMenu.idx && err == nil:
+			line = lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("15")).Background(lipgloss.Color("62")).Render(line)
+		case err != nil:
+			line = dim.Render(fmt.Sprintf("%s: %v", line, err))
+		}
+		if i == m.launchMenu.idx {
+			line = "▸ " + line
+		} else {
+			line = "  " + line
+		}
+		lines = append(lines, line)
+	}
+	lines = append(lines, "", dim.Render("1-5/enter: open | esc: cancel"))
+	return dialogStyle(m.width, lipgloss.Color("205")).Render(strings.Join(lines, "\n"))
+}
@@ -6,11 +6,20 @@ import (
 	"log"
 	"os"
 	"os/exec"
+	"sort"
 	"strings"
+	"time"
 
+	"github.com/pozgo/OhMySSH/pkg/knownhosts"
+	"github.com/pozgo/OhMySSH/pkg/launch"
 	"github.com/pozgo/OhMySSH/pkg/parser"
+	"github.com/pozgo/OhMySSH/pkg/probe"
+	"github.com/pozgo/OhMySSH/pkg/revision"
+	"github.com/pozgo/OhMySSH/pkg/settings"
+	"github.com/pozgo/OhMySSH/pkg/state"
 
 	"github.com/charmbracelet/bubbles/textarea"
+	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 )
@@ -20,6 +29,21 @@ type mode int
 const (
 	modeNormal mode = iota
 	modeEditor
+	modeForm
+	modeConfirm
+	modeHelp
+	modeRevisions
+	modeFilter
+	modeHistory
+	modeDiscover
+	modeKnownHosts
+	modeKeys
+	modeAgent
+	modeTunnels
+	modeCommand
+	modeOutput
+	modeFiles
+	modeLaunch
 )
 
 type vimMode int
@@ -31,21 +55,54 @@ const (
 )
 
 type model struct {
-	width         int
-	height        int
-	sshConfig     *parser.SSHConfig
-	hosts         []parser.Host
-	selectedIdx   int
-	configContent string
-	currentMode   mode
-	err           error
-	textarea      textarea.Model
-	saved         bool
-	vimMode       vimMode
-	commandBuffer string
-	keySequence   string
-	shouldConnect bool
-	selectedHost  parser.Host
+	width          int
+	height         int
+	sshConfig      *parser.SSHConfig
+	hosts          []parser.Host
+	selectedIdx    int
+	configContent  string
+	currentMode    mode
+	err            error
+	textarea       textarea.Model
+	saved          bool
+	vimMode        vimMode
+	commandBuffer  string
+	keySequence    string
+	shouldConnect  bool
+	selectedHost   parser.Host
+	form           form
+	confirm        confirmation
+	status         string
+	statusIsError  bool
+	revisions      *revision.History
+	revisionIdx    int
+	collapsed      map[string]bool
+	filterInput    textinput.Model
+	scope          string
+	state          *state.State
+	sortMode       sortMode
+	history        []state.Connection
+	historyIdx     int
+	discovery      knownhosts.Discovery
+	discoverIdx    int
+	knownHosts     knownHostsView
+	keys           keyReport
+	keysIdx        int
+	agent          agentState
+	reach          map[string]probe.Result
+	prober         *probe.Prober
+	probeRound     int
+	handshakes     map[string]handshakeResult
+	tunnels        tunnelsView
+	prompt         commandPrompt
+	output         commandOutput
+	marked         map[string]bool // hosts marked for a broadcast, by alias
+	files          filesView
+	settings       settings.Settings
+	launcher       *launch.Launcher
+	launchMenu     launchMenu
+	launchTarget   launch.Target  // where the host being connected opens
+	connectOptions launch.Options // and the options it is connected with
 }
 
 func initialModel() model {
@@ -65,7 +122,15 @@ func initialModel() model {
 	ta.SetValue(configContent)
 	ta.Focus()
 
-	return model{
+	filterInput := textinput.New()
+	filterInput.Prompt = "🔍 "
+	filterInput.Placeholder = "name, host, group:prod, tag:db"
+
+	// A broken state file only costs favorites and usage stats
+	appState, stateErr := state.Load(state.DefaultPath())
+	appSettings, settingsErr := settings.Load(settings.DefaultPath())
+
+	m := model{
 		sshConfig:     config,
 		hosts:         hosts,
 		selectedIdx:   0,
@@ -79,11 +144,31 @@ func initialModel() model {
 		keySequence:   "",
 		shouldConnect: false,
 		selectedHost:  parser.Host{},
+		revisions:     revision.NewHistory(maxRevisions),
+		collapsed:     make(map[string]bool),
+		filterInput:   filterInput,
+		state:         appState,
+		sortMode:      parseSortMode(appState.SortMode),
+		reach:         make(map[string]probe.Result),
+		prober:        probe.New(probeTimeout, probeConcurrency),
+		handshakes:    make(map[string]handshakeResult),
+		marked:        make(map[string]bool),
+		settings:      appSettings,
+		launcher:      launch.NewLauncher(appSettings.Terminal),
+	}
+	if stateErr != nil {
+		m.setError(stateErr)
+	}
+	if settingsErr != nil {
+		m.setError(settingsErr)
+	} else if _, err := launch.ParseTarget(appSettings.Launch); err != nil {
+		m.setError(err)
 	}
+	return m
 }
 
 func (m model) Init() tea.Cmd {
-	return nil
+	return tea.Batch(loadAgent(m.sshConfig), m.probeHosts())
 }
 
 func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
@@ -100,6 +185,38 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		if m.currentMode == modeNormal {
 			return m.handleMouseClick(msg)
 		}
+	case keyScanMsg:
+		if msg.host == m.knownHosts.host && msg.port == m.knownHosts.port {
+			m.knownHosts.scanning = false
+			m.knownHosts.scanned = msg.err == nil
+			m.knownHosts.offered = msg.offered
+			m.knownHosts.scanErr = msg.err
+		}
+		return m, nil
+	case agentMsg:
+		m.agent = msg.state
+		return m, nil
+	case keyInstallMsg:
+		return m.finishKeyInstall(msg)
+	case probeMsg:
+		// Results of an earlier round would undo the reset of a refresh
+		if msg.round == m.probeRound {
+			m.reach[msg.result.Name] = msg.result
+		}
+		return m, nil
+	case handshakeMsg:
+		m.handshakes[msg.alias] = msg.result
+		return m, nil
+	case tunnelStartMsg:
+		return m.finishTunnelStart(msg)
+	case broadcastMsg:
+		return m.applyBroadcast(msg)
+	case listingMsg:
+		return m.finishListing(msg)
+	case transferMsg:
+		return m.applyTransfer(msg)
+	case launchMsg:
+		return m.finishLaunch(msg)
 	case tea.WindowSizeMsg:
 		m.width = msg.Width
 		m.height = msg.Height
@@ -109,7 +226,39 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case tea.KeyMsg:
 		if m.currentMode == modeEditor {
 			return m.handleVimKeybindings(msg)
+		} else if m.currentMode == modeForm {
+			return m.handleFormKeys(msg)
+		} else if m.currentMode == modeConfirm {
+			return m.handleConfirmKeys(msg)
+		} else if m.currentMode == modeRevisions {
+			return m.handleRevisionKeys(msg)
+		} else if m.currentMode == modeFilter {
+			return m.handleFilterKeys(msg)
+		} else if m.currentMode == modeHistory {
+			return m.handleHistoryKeys(msg)
+		} else if m.currentMode == modeDiscover {
+			return m.handleDiscoverKeys(msg)
+		} else if m.currentMode == modeKnownHosts {
+			return m.handleKnownHostsKeys(msg)
+		} else if m.currentMode == modeKeys {
+			return m.handleKeysKeys(msg)
+		} else if m.currentMode == modeAgent {
+			return m.handleAgentKeys(msg)
+		} else if m.currentMode == modeTunnels {
+			return m.handleTunnelsKeys(msg)
+		} else if m.currentMode == modeCommand {
+			return m.handleCommandPromptKeys(msg)
+		} else if m.currentMode == modeOutput {
+			return m.handleOutputKeys(msg)
+		} else if m.currentMode == modeFiles {
+			return m.handleFilesKeys(msg)
+		} else if m.currentMode == modeLaunch {
+			return m.handleLaunchKeys(msg)
+		} else if m.currentMode == modeHelp {
+			m.currentMode = modeNormal
+			return m, nil
 		} else {
+			m.status = ""
 			switch msg.String() {
 			case "ctrl+c", "q":
 				return m, tea.Quit
@@ -119,18 +268,45 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				}
 				return m, nil
 			case "down", "j":
-				if m.selectedIdx < len(m.hosts)-1 {
+				if m.selectedIdx < len(m.visibleRows())-1 {
 					m.selectedIdx++
 				}
 				return m, nil
-			case "enter", " ":
+			case " ":
+				// Mark a host, or all hosts of a group, for a broadcast
+				if row, ok := m.currentRow(); ok {
+					return m.toggleMark(row)
+				}
+				return m, nil
+			case "enter":
+				// Expand or collapse a group
+				if row, ok := m.currentRow(); ok && row.isGroup() {
+					m.toggleGroup(row.group)
+					return m, nil
+				}
 				// Connect to selected server
-				if len(m.hosts) > 0 && m.selectedIdx < len(m.hosts) {
-					m.shouldConnect = true
-					m.selectedHost = m.hosts[m.selectedIdx]
-					return m, tea.Quit
+				if host, ok := m.currentHost(); ok {
+					return m.connectHost(host)
 				}
 				return m, nil
+			case "alt+enter":
+				return m.openLaunchMenu()
+			case "o":
+				return m.openConnectOptionsForm()
+			case "left", "h":
+				return m.collapseSelected()
+			case "right", "l":
+				return m.expandSelected()
+			case "/":
+				return m.startFilter()
+			case "g":
+				return m.toggleScope()
+			case "esc":
+				m.filterInput.SetValue("")
+				m.scope = ""
+				m.marked = make(map[string]bool)
+				m.clampSelection()
+				return m, nil
 			case "e":
 				m.currentMode = modeEditor
 				m.vimMode = vimNormal
@@ -139,9 +315,66 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				m.textarea.Focus()
 				m.textarea.SetValue(m.configContent)
 				return m, nil
+			case "E":
+				return m.openEditHostForm()
+			case "c":
+				return m.openDuplicateHostForm()
+			case "R":
+				return m.openRenameHostForm()
+			case "x", "delete":
+				return m.confirmDeleteHost()
+			case "u":
+				return m.undoChange()
+			case "ctrl+r":
+				return m.redoChange()
+			case "U":
+				m.currentMode = modeRevisions
+				m.revisionIdx = 0
+				return m, nil
+			case "f":
+				return m.toggleFavorite()
+			case "s":
+				return m.cycleSort()
+			case "H":
+				return m.openHistory()
+			case "D":
+				return m.openDiscover()
+			case "K":
+				return m.openKnownHosts()
+			case "I":
+				return m.openKeys()
+			case "A":
+				return m.openAgent()
+			case "G":
+				return m.openGenerateKeyForm()
+			case "T":
+				return m.openTunnels()
+			case "!":
+				return m.openCommandPrompt(m.commandTargets())
+			case "t":
+				return m.openFiles()
+			case "r":
+				return m.refreshHosts()
+			case "P":
+				return m.probeHostKey()
+			case "?":
+				m.currentMode = modeHelp
+				return m, nil
 			}
 		}
 	}
+
+	// Let text inputs receive non-key messages such as cursor blinks
+	if m.currentMode == modeForm {
+		var cmd tea.Cmd
+		m.form, cmd = m.form.update(msg)
+		return m, cmd
+	}
+	if m.currentMode == modeFilter {
+		var cmd tea.Cmd
+		m.filterInput, cmd = m.filterInput.Update(msg)
+		return m, cmd
+	}
 	return m, nil
 }
 
@@ -171,8 +404,7 @@ func (m model) handleVimKeybindings(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 				return m, nil
 			}
 			m.saved = true
-			m.sshConfig.Load()
-			m.hosts = m.sshConfig.GetHosts()
+			m.reloadHosts()
 			m.currentMode = modeNormal
 			m.textarea.Blur()
 			m.vimMode = vimNormal
@@ -296,8 +528,7 @@ func (m model) executeVimCommand() (tea.Model, tea.Cmd) {
 			m.err = err
 		} else {
 			m.saved = true
-			m.sshConfig.Load()
-			m.hosts = m.sshConfig.GetHosts()
+			m.reloadHosts()
 		}
 		m.vimMode = vimNormal
 		return m, nil
@@ -316,8 +547,7 @@ func (m model) executeVimCommand() (tea.Model, tea.Cmd) {
 			return m, nil
 		}
 		m.saved = true
-		m.sshConfig.Load()
-		m.hosts = m.sshConfig.GetHosts()
+		m.reloadHosts()
 		m.currentMode = modeNormal
 		m.textarea.Blur()
 		m.vimMode = vimNormal
@@ -344,6 +574,37 @@ func (m model) View() string {
 		return m.renderEditor()
 	}
 
+	switch m.currentMode {
+	case modeForm:
+		return m.renderDialog(m.form.view(m.width))
+	case modeConfirm:
+		return m.renderDialog(m.confirm.view(m.width))
+	case modeHelp:
+		return m.renderHelp()
+	case modeRevisions:
+		return m.renderRevisions()
+	case modeHistory:
+		return m.renderHistory()
+	case modeDiscover:
+		return m.renderDiscover()
+	case modeKnownHosts:
+		return m.renderKnownHosts()
+	case modeKeys:
+		return m.renderKeys()
+	case modeAgent:
+		return m.renderAgent()
+	case modeTunnels:
+		return m.renderTunnels()
+	case modeCommand:
+		return m.renderCommandPrompt()
+	case modeOutput:
+		return m.renderOutput()
+	case modeFiles:
+		return m.renderFiles()
+	case modeLaunch:
+		return m.renderDialog(m.renderLaunchMenu())
+	}
+
 	return m.renderNormalMode()
 }
 
@@ -486,8 +747,46 @@ func (m model) renderServerList(width, height int) string {
 		return lipgloss.JoinVertical(lipgloss.Left, title, content)
 	}
 
+	// Filter and scope are shown above the list
+	headerItems := m.serverListHeader()
+
+	rows := m.visibleRows()
 	var serverItems []string
-	for i, host := range m.hosts {
+	if len(rows) == 0 {
+		serverItems = append(serverItems, "No servers match the filter")
+	}
+	for i, row := range rows {
+		indent := strings.Repeat("  ", row.depth)
+		var label string
+		if row.isGroup() {
+			arrow := "▾"
+			if m.collapsed[row.group] && !m.filterActive() {
+				arrow = "▸"
+			}
+			icon := "📁"
+			if row.isFavorites() {
+				icon = "⭐"
+			}
+			label = fmt.Sprintf("%s %s %s (%d)", arrow, icon, row.label, row.count)
+		}
+		// Mark favorites where they appear outside the pinned section
+		name := row.label
+		if !row.isGroup() && row.parent != favoritesGroup && m.isFavorite(name) {
+			name += " ★"
+		}
+		// Mark hosts whose key is loaded in ssh-agent and badge reachability
+		var badge string
+		var badgeColor lipgloss.Color
+		if !row.isGroup() {
+			if loaded, _ := m.keyInAgent(m.hosts[row.hostIdx]); loaded {
+				name += " 🔑"
+			}
+			badge, badgeColor = m.reachBadge(m.hosts[row.hostIdx])
+		}
+		selectedIcon, icon := "💻 ", "🌐 "
+		if !row.isGroup() && m.marked[row.label] {
+			selectedIcon, icon = "✅ ", "✅ "
+		}
 		if i == m.selectedIdx {
 			// Selected server - bold with highlighted background
 			selectedStyle := lipgloss.NewStyle().
@@ -495,24 +794,38 @@ func (m model) renderServerList(width, height int) string {
 				Foreground(lipgloss.Color("15")).  // Bright white text
 				Background(lipgloss.Color("62")).  // Blue background
 				Padding(0, 1)
-			serverItems = append(serverItems, selectedStyle.Render("💻 "+host.Name))
+			if !row.isGroup() {
+				label = selectedIcon + name
+				if badge != "" {
+					label += " " + badge
+				}
+			}
+			serverItems = append(serverItems, indent+selectedStyle.Render(label))
 		} else {
 			// Unselected servers  
-			serverItems = append(serverItems, "🌐 "+host.Name)
+			if !row.isGroup() {
+				label = icon + name
+				if badge != "" {
+					label += " " + lipgloss.NewStyle().Foreground(badgeColor).Render(badge)
+				}
+			}
+			serverItems = append(serverItems, indent+label)
 		}
 	}
 
 	// Calculate available space for server items (height - borders - padding)
-	availableLines := contentHeight - 2  // 2 for borders+padding
+	availableLines := contentHeight - 2 - len(headerItems)  // 2 for borders+padding
 	if availableLines < 1 {
 		availableLines = 1
 	}
 	
-	// Truncate server list if it's too long
-	if len(serverItems) > availableLines {
-		serverItems = serverItems[:availableLines-1]
-		serverItems = append(serverItems, "... (more servers)")
+	// Scroll the list so the selection stays visible
+	start := scrollWindow(m.selectedIdx, len(serverItems), availableLines)
+	end := start + availableLines
+	if end > len(serverItems) {
+		end = len(serverItems)
 	}
+	serverItems = append(headerItems, serverItems[start:end]...)
 	
 	content := contentStyle.Render(strings.Join(serverItems, "\n"))
 	return lipgloss.JoinVertical(lipgloss.Left, title, content)
@@ -543,14 +856,26 @@ func (m model) renderServerMetadata(width, height int) string {
 		BorderForeground(lipgloss.Color("105")).
 		Padding(1)
 	
-	if len(m.hosts) == 0 || m.selectedIdx >= len(m.hosts) {
+	row, ok := m.currentRow()
+	if !ok {
 		content := contentStyle.Render("No server selected")
 		return lipgloss.JoinVertical(lipgloss.Left, title, content)
 	}
 
-	selected := m.hosts[m.selectedIdx]
-	
 	var details []string
+	if row.isGroup() {
+		if row.isFavorites() {
+			details = append(details, "⭐ Favorites")
+		} else {
+			details = append(details, fmt.Sprintf("Group: %s", row.group))
+		}
+		details = append(details, fmt.Sprintf("Hosts: %d", row.count))
+		content := contentStyle.Render(strings.Join(details, "\n"))
+		return lipgloss.JoinVertical(lipgloss.Left, title, content)
+	}
+
+	selected := m.hosts[row.hostIdx]
+	
 	details = append(details, fmt.Sprintf("Host: %s", selected.Name))
 	
 	if selected.Hostname != "" {
@@ -566,7 +891,61 @@ func (m model) renderServerMetadata(width, height int) string {
 	}
 	
 	for key, value := range selected.Options {
-		details = append(details, fmt.Sprintf("%s: %s", strings.Title(key), value))
+		line := fmt.Sprintf("%s: %s", strings.Title(key), value)
+		if key == "identityfile" {
+			line += identityWarning(value)
+		}
+		details = append(details, line)
+	}
+
+	if !selected.IsWildcard() {
+		details = append(details, m.reachDetail(selected))
+		details = append(details, m.handshakeDetails(selected)...)
+		details = append(details, m.jumpDetails(selected)...)
+	}
+
+	if loaded, known := m.keyInAgent(selected); known {
+		if loaded {
+			details = append(details, "Agent: 🔑 key loaded")
+		} else {
+			details = append(details, "Agent: key not loaded")
+		}
+	}
+
+	if selected.Meta.Group != "" {
+		details = append(details, fmt.Sprintf("Group: %s", selected.Meta.Group))
+	}
+
+	if len(selected.Meta.Tags) > 0 {
+		details = append(details, fmt.Sprintf("Tags: %s", strings.Join(selected.Meta.Tags, ", ")))
+	}
+
+	if selected.Meta.Owner != "" {
+		details = append(details, fmt.Sprintf("Owner: %s", selected.Meta.Owner))
+	}
+
+	extraKeys := make([]string, 0, len(selected.Meta.Extra))
+	for key := range selected.Meta.Extra {
+		extraKeys = append(extraKeys, key)
+	}
+	sort.Strings(extraKeys)
+	for _, key := range extraKeys {
+		details = append(details, fmt.Sprintf("%s: %s", strings.Title(key), selected.Meta.Extra[key]))
+	}
+
+	if selected.Meta.Notes != "" {
+		details = append(details, "Notes:")
+		for _, note := range strings.Split(selected.Meta.Notes, "\n") {
+			details = append(details, "  📝 "+note)
+		}
+	}
+
+	if selected.Source != m.sshConfig.Path {
+		details = append(details, fmt.Sprintf("File: %s:%d", selected.Source, selected.Line))
+	}
+
+	if stats := m.state.Stats(selected.Name); stats.Connections > 0 {
+		details = append(details, fmt.Sprintf("Connections: %d (last %s)", stats.Connections, stats.LastConnected.Local().Format("2006-01-02 15:04")))
 	}
 
 	// Calculate available space for details (height - borders - padding)
@@ -635,59 +1014,151 @@ func (m model) renderStatusBar() string {
 		Width(m.width).
 		Background(lipgloss.Color("240")).
 		Foreground(lipgloss.Color("255")).
-		Padding(0, 1)
+		Padding(0, 1).
+		MaxHeight(1)
+
+	return style.Render(strings.Join(m.statusBarItems(), statusBarSeparator))
+}
 
+const statusBarSeparator = "  |  "
+
+// statusBarItems lists the status bar entries, starting with the status
+// message if there is one.
+func (m model) statusBarItems() []string {
 	var statusItems []string
+	if m.status != "" {
+		color := lipgloss.Color("46")
+		if m.statusIsError {
+			color = lipgloss.Color("196")
+		}
+		statusItems = append(statusItems, lipgloss.NewStyle().
+			Foreground(color).
+			Background(lipgloss.Color("240")).
+			Bold(true).
+			Render(m.status))
+	}
 	statusItems = append(statusItems, "q: quit")
 	statusItems = append(statusItems, "↑↓: navigate")
 	statusItems = append(statusItems, "enter: connect")
 	statusItems = append(statusItems, "e: edit config")
+	statusItems = append(statusItems, "/: filter")
+	statusItems = append(statusItems, m.sortStatusItem())
 	statusItems = append(statusItems, "click: select server/edit config")
-
-	return style.Render(strings.Join(statusItems, "  |  "))
+	statusItems = append(statusItems, "?: help")
+	return statusItems
 }
 
 func (m *model) saveConfig() error {
 	content := m.textarea.Value()
-	
+	before := m.configContent
+
+	err := writeConfigFile(m.sshConfig.Path, content)
+	if err != nil {
+		return err
+	}
+
+	// Update the config content
+	m.configContent = content
+	m.revisions.Record(revision.Revision{
+		Label:   "Editor save",
+		Changes: []revision.FileChange{{Path: m.sshConfig.Path, Before: before, After: content}},
+	})
+
+	return nil
+}
+
+// writeConfigFile backs up a config file and replaces it with content while
+// keeping the file's permissions.
+func writeConfigFile(path, content string) error {
 	// Create backup before saving
-	err := m.createBackup()
+	err := createBackup(path)
 	if err != nil {
 		return fmt.Errorf("failed to create backup: %v", err)
 	}
 	
 	// Get current file info to preserve permissions
-	fileInfo, err := os.Stat(m.sshConfig.Path)
+	fileInfo, err := os.Stat(path)
 	if err != nil {
 		return fmt.Errorf("failed to get file info: %v", err)
 	}
 	
 	// Write file with proper permissions (600 for SSH config)
-	err = ioutil.WriteFile(m.sshConfig.Path, []byte(content), 0600)
+	err = ioutil.WriteFile(path, []byte(content), 0600)
 	if err != nil {
 		return fmt.Errorf("failed to save config: %v", err)
 	}
 	
 	// Restore original file permissions if they were different
 	if fileInfo.Mode() != 0600 {
-		err = os.Chmod(m.sshConfig.Path, fileInfo.Mode())
+		err = os.Chmod(path, fileInfo.Mode())
 		if err != nil {
 			return fmt.Errorf("failed to restore file permissions: %v", err)
 		}
 	}
 	
-	// Update the config content
-	m.configContent = content
-	
 	return nil
 }
 
-func (m *model) createBackup() error {
+// reloadHosts re-parses the SSH config and keeps the selection in range.
+func (m *model) reloadHosts() {
+	m.sshConfig.Load()
+	m.hosts = m.sshConfig.GetHosts()
+	m.clampSelection()
+}
+
+// currentHost returns the host under the cursor in the server list.
+func (m model) currentHost() (parser.Host, bool) {
+	row, ok := m.currentRow()
+	if !ok || row.isGroup() {
+		return parser.Host{}, false
+	}
+	return m.hosts[row.hostIdx], true
+}
+
+func (m model) hostByName(name string) (parser.Host, bool) {
+	for _, host := range m.hosts {
+		if host.Name == name {
+			return host, true
+		}
+	}
+	return parser.Host{}, false
+}
+
+// selectHost moves the cursor to the host with the given alias, if present,
+// expanding the groups above it.
+func (m *model) selectHost(name string) {
+	host, ok := m.hostByName(name)
+	if !ok {
+		return
+	}
+	path := host.Meta.GroupPath()
+	for i := range path {
+		delete(m.collapsed, strings.Join(path[:i+1], "/"))
+	}
+	for i, row := range m.visibleRows() {
+		if !row.isGroup() && m.hosts[row.hostIdx].Name == name {
+			m.selectedIdx = i
+			return
+		}
+	}
+}
+
+func (m *model) setStatus(status string) {
+	m.status = status
+	m.statusIsError = false
+}
+
+func (m *model) setError(err error) {
+	m.status = fmt.Sprintf("Error: %v", err)
+	m.statusIsError = true
+}
+
+func createBackup(path string) error {
 	// Create backup with timestamp
-	backupPath := m.sshConfig.Path + ".backup." + fmt.Sprintf("%d", os.Getpid())
+	backupPath := path + ".backup." + fmt.Sprintf("%d", os.Getpid())
 	
 	// Read original file
-	originalContent, err := ioutil.ReadFile(m.sshConfig.Path)
+	originalContent, err := ioutil.ReadFile(path)
 	if err != nil {
 		return err
 	}
@@ -726,11 +1197,12 @@ func (m *model) deleteCurrentLine() {
 }
 
 func (m model) highlightSelectedServerInConfig() string {
-	if len(m.hosts) == 0 || m.selectedIdx >= len(m.hosts) {
+	selected, ok := m.currentHost()
+	if !ok || selected.Source != m.sshConfig.Path {
 		return m.configContent
 	}
 	
-	selectedHostName := m.hosts[m.selectedIdx].Name
+	selectedHostName := selected.Name
 	lines := strings.Split(m.configContent, "\n")
 	var highlightedLines []string
 	
@@ -744,7 +1216,7 @@ func (m model) highlightSelectedServerInConfig() string {
 			// Extract host name from line
 			parts := strings.Fields(trimmedLine)
 			if len(parts) >= 2 {
-				hostName := parts[1]
+				hostName := strings.Join(parts[1:], " ")
 				if hostName == selectedHostName {
 					inSelectedBlock = true
 					// Highlight the Host line
@@ -790,6 +1262,14 @@ func (m model) handleMouseClick(msg tea.MouseMsg) (tea.Model, tea.Cmd) {
 		return m, nil
 	}
 	
+	// The status bar is the last line; its sort entry cycles the sort mode
+	if msg.Y == m.height-1 {
+		if m.statusBarItemAt(msg.X) == m.sortStatusItem() {
+			return m.cycleSort()
+		}
+		return m, nil
+	}
+
 	// Calculate panel dimensions (30/70 split) - same as in renderNormalMode
 	leftWidth := int(float64(m.width) * 0.3)
 	
@@ -813,11 +1293,17 @@ func (m model) handleMouseClick(msg tea.MouseMsg) (tea.Model, tea.Cmd) {
 		if msg.Y < topHeight {
 			// Calculate which server was clicked based on Y position
 			// Account for border and padding (2 lines for title + spacing)
-			serverLineOffset := 3
-			if msg.Y >= serverLineOffset && len(m.hosts) > 0 {
-				clickedServerIdx := msg.Y - serverLineOffset
-				if clickedServerIdx >= 0 && clickedServerIdx < len(m.hosts) {
+			serverLineOffset := 3 + len(m.serverListHeader())
+			rows := m.visibleRows()
+			if msg.Y >= serverLineOffset && len(rows) > 0 {
+				availableLines := topHeight - 5 - len(m.serverListHeader())
+				clickedServerIdx := scrollWindow(m.selectedIdx, len(rows), availableLines) + msg.Y - serverLineOffset
+				if clickedServerIdx >= 0 && clickedServerIdx < len(rows) {
 					m.selectedIdx = clickedServerIdx
+					// Shift-click marks, like space
+					if msg.Shift {
+						return m.toggleMark(rows[clickedServerIdx])
+					}
 				}
 			}
 		}
@@ -826,7 +1312,10 @@ func (m model) handleMouseClick(msg tea.MouseMsg) (tea.Model, tea.Cmd) {
 	return m, nil
 }
 
-func connectToServer(host parser.Host) {
+// connectToServer runs command, the client connecting to host with options
+// on top of its settings in config, in the foreground and returns once the
+// session ends.
+func connectToServer(config *parser.SSHConfig, host parser.Host, options launch.Options, command []string) error {
 	// Print beautiful connection info
 	fmt.Printf("\n")
 	fmt.Printf("🚀 Connecting to server via OhMySSH...\n")
@@ -835,14 +1324,22 @@ func connectToServer(host parser.Host) {
 	if host.Hostname != "" {
 		fmt.Printf("│ 🌐 Host:   %-29s │\n", host.Hostname)
 	}
-	if host.User != "" {
-		fmt.Printf("│ 👤 User:   %-29s │\n", host.User)
+	user, port := host.User, host.Port
+	if options.User != "" {
+		user = options.User
+	}
+	if options.Port != "" {
+		port = options.Port
 	}
-	if host.Port != "" {
-		fmt.Printf("│ 🔌 Port:   %-29s │\n", host.Port)
+	if user != "" {
+		fmt.Printf("│ 👤 User:   %-29s │\n", user)
+	}
+	if port != "" {
+		fmt.Printf("│ 🔌 Port:   %-29s │\n", port)
 	}
 	fmt.Printf("└─────────────────────────────────────────┘\n")
-	fmt.Printf("Command: ssh %s\n", host.Name)
+	commandLine := launch.CommandLine(command)
+	fmt.Printf("Command: %s\n", commandLine)
 	fmt.Printf("\n")
 
 	// Execute the SSH command through the user's shell to preserve wrappers and environment
@@ -853,19 +1350,22 @@ func connectToServer(host parser.Host) {
 	}
 	
 	// Use -i flag to make it an interactive shell so aliases and functions are loaded
-	cmd := exec.Command(shell, "-i", "-c", "ssh "+host.Name)
+	cmd := exec.Command(shell, "-i", "-c", commandLine)
 	cmd.Stdin = os.Stdin
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
 	
+	start := time.Now()
 	err := cmd.Run()
-	if err != nil {
-		fmt.Printf("SSH connection failed: %v\n", err)
-		os.Exit(1)
-	}
+	recordConnection(config, host, start, time.Now(), err)
+	return err
 }
 
 func main() {
+	if len(os.Args) > 1 {
+		os.Exit(runCLI(os.Args[1:], os.Stdout, os.Stderr))
+	}
+
 	p := tea.NewProgram(initialModel(), tea.WithAltScreen(), tea.WithMouseCellMotion())
 	finalModel, err := p.Run()
 	if err != nil {
@@ -875,6 +1375,13 @@ func main() {
 	
 	// Check if we should connect to a server
 	if m, ok := finalModel.(model); ok && m.shouldConnect {
-		connectToServer(m.selectedHost)
+		command, err := clientCommand(m.sshConfig, m.settings, m.selectedHost, m.connectOptions)
+		if err == nil {
+			err = connectToServer(m.sshConfig, m.selectedHost, m.connectOptions, command)
+		}
+		if err != nil {
+			fmt.Printf("SSH connection failed: %v\n", err)
+			os.Exit(1)
+		}
 	}
 }
\ No newline at end of file
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/pozgo/OhMySSH/pkg/launch"
+	"github.com/pozgo/OhMySSH/pkg/parser"
+	"github.com/pozgo/OhMySSH/pkg/state"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// openConnectOptionsForm asks how to connect to the selected host just this
+// time, starting from the options used last time.
+func (m model) openConnectOptionsForm() (tea.Model, tea.Cmd) {
+	host, ok := m.currentHost()
+	if !ok {
+		return m, nil
+	}
+	last := launch.Options(m.state.LastOverrides(host.Name))
+	effective := m.sshConfig.Effective(host.Patterns()[0])
+
+	client := newFormField("Client", "client", last.Client)
+	client.input.Placeholder = hostClient(m.settings, host)
+	user := newFormField("User", "user", last.User)
+	user.input.Placeholder = effective["user"]
+	if user.input.Placeholder == "" {
+		user.input.Placeholder = os.Getenv("USER")
+	}
+	port := newFormField("Port", "port", last.Port)
+	port.input.Placeholder = effective["port"]
+	if port.input.Placeholder == "" {
+		port.input.Placeholder = "22"
+	}
+	options := newMultilineFormField("-o options", "options", strings.Join(last.Options, "\n"))
+	options.area.Placeholder = "ServerAliveInterval=30"
+	options.area.SetHeight(3)
+	verbose := newFormField("Verbose (0-3)", "verbose", "")
+	if last.Verbose > 0 {
+		verbose.input.SetValue(strconv.Itoa(last.Verbose))
+	}
+	forward := newFormField("Forward agent (y/n)", "forward", "n")
+	if last.ForwardAgent {
+		forward.input.SetValue("y")
+	}
+
+	m.form = newForm(formConnectOptions, "⚙️ Connect to "+host.Name+" with options", host.Name, client, user, port, options, verbose, forward)
+	m.form.help = "empty: as configured | enter: connect (newline in -o options) | ctrl+s: connect | esc: cancel"
+	m.currentMode = modeForm
+	return m, nil
+}
+
+// submitConnectOptions remembers the options for the host and connects
+// with them in the default launch target.
+func (m model) submitConnectOptions() (tea.Model, tea.Cmd) {
+	host, ok := m.hostByName(m.form.target)
+	if !ok {
+		m.form.err = fmt.Errorf("%w: %s", parser.ErrHostNotFound, m.form.target)
+		return m, nil
+	}
+	options := launch.Options{
+		Client: m.form.value("client"),
+		User:   m.form.value("user"),
+		Port:   m.form.value("port"),
+	}
+	for _, line := range strings.Split(m.form.value("options"), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			options.Options = append(options.Options, line)
+		}
+	}
+	if value := m.form.value("verbose"); value != "" {
+		verbose, err := strconv.Atoi(value)
+		if err != nil {
+			m.form.err = fmt.Errorf("invalid verbosity %q, expected 0-%d", value, launch.MaxVerbose)
+			return m, nil
+		}
+		options.Verbose = verbose
+	}
+	switch strings.ToLower(m.form.value("forward")) {
+	case "y", "yes":
+		options.ForwardAgent = true
+	case "", "n", "no":
+	default:
+		m.form.err = fmt.Errorf("forward agent must be y or n")
+		return m, nil
+	}
+	if err := options.Validate(); err != nil {
+		m.form.err = err
+		return m, nil
+	}
+	if _, err := clientCommand(m.sshConfig, m.settings, host, options); err != nil {
+		m.form.err = err
+		return m, nil
+	}
+
+	m.state.RememberOverrides(host.Name, state.Overrides(options))
+	if err := m.state.Save(); err != nil {
+		m.form.err = err
+		return m, nil
+	}
+	m.currentMode = modeNormal
+	return m.connectHostIn(host, m.defaultTarget(), options)
+}
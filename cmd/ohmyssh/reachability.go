@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/pozgo/OhMySSH/pkg/parser"
+	"github.com/pozgo/OhMySSH/pkg/probe"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+const (
+	probeTimeout     = 3 * time.Second
+	probeConcurrency = 16
+)
+
+// probeMsg delivers the reachability of one host, checked in the given
+// round of probeHosts.
+type probeMsg struct {
+	round  int
+	result probe.Result
+}
+
+// probeTarget returns the address ssh connects to for host. Hosts reached
+// through ProxyJump or ProxyCommand cannot be dialled directly.
+func probeTarget(config *parser.SSHConfig, host parser.Host) (probe.Target, bool) {
+	alias := host.Patterns()[0]
+	values := config.Effective(alias)
+	for _, key := range []string{"proxyjump", "proxycommand"} {
+		if value := values[key]; value != "" && !strings.EqualFold(value, "none") {
+			return probe.Target{}, false
+		}
+	}
+	hostname := config.ResolveHostname(alias)
+	port := values["port"]
+	if port == "" {
+		port = "22"
+	}
+	return probe.Target{Name: host.Name, Address: net.JoinHostPort(hostname, port)}, true
+}
+
+// probeHosts checks every host in the background. Results arrive one
+// probeMsg at a time, so badges appear as soon as each host answers. All
+// rounds share the model's prober, so a refresh while a round is still
+// running does not open more than probeConcurrency connections at a time.
+func (m model) probeHosts() tea.Cmd {
+	prober, round := m.prober, m.probeRound
+	var cmds []tea.Cmd
+	for _, host := range m.hosts {
+		if host.IsWildcard() {
+			continue
+		}
+		target, ok := probeTarget(m.sshConfig, host)
+		if !ok {
+			continue
+		}
+		cmds = append(cmds, func() tea.Msg {
+			return probeMsg{round: round, result: prober.Check(context.Background(), target)}
+		})
+	}
+	return tea.Batch(cmds...)
+}
+
+// refreshHosts reloads the config and checks all hosts again.
+func (m model) refreshHosts() (tea.Model, tea.Cmd) {
+	m.reloadHosts()
+	m.reach = make(map[string]probe.Result)
+	m.probeRound++
+	m.setStatus("Reloaded hosts, checking which ones are reachable...")
+	return m, m.probeHosts()
+}
+
+// reachBadge is shown next to a host in the server list: its latency when
+// it accepts connections, down when not, and a dot while it is checked.
+func (m model) reachBadge(host parser.Host) (string, lipgloss.Color) {
+	if _, ok := probeTarget(m.sshConfig, host); !ok || host.IsWildcard() {
+		return "", ""
+	}
+	result, ok := m.reach[host.Name]
+	switch {
+	case !ok:
+		return "·", lipgloss.Color("241")
+	case result.Up:
+		return "● " + formatLatency(result.Latency), lipgloss.Color("46")
+	default:
+		return "○ down", lipgloss.Color("196")
+	}
+}
+
+// reachDetail describes the reachability of host for the details panel.
+func (m model) reachDetail(host parser.Host) string {
+	target, ok := probeTarget(m.sshConfig, host)
+	if !ok {
+		return "Reachable: not checked (behind a proxy)"
+	}
+	result, ok := m.reach[host.Name]
+	switch {
+	case !ok:
+		return fmt.Sprintf("Reachable: checking %s...", target.Address)
+	case result.Up:
+		return fmt.Sprintf("Reachable: yes, %s (%s)", formatLatency(result.Latency), result.Address)
+	default:
+		return fmt.Sprintf("Reachable: no, %s (%s)", probeError(result.Err), result.Address)
+	}
+}
+
+func formatLatency(latency time.Duration) string {
+	if latency < time.Millisecond {
+		return "<1ms"
+	}
+	return fmt.Sprintf("%dms", latency.Milliseconds())
+}
+
+// probeError shortens dial errors to their cause, e.g. "connection refused".
+func probeError(err error) string {
+	var netErr net.Error
+	if errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()) {
+		return "timed out"
+	}
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return "unknown host"
+	}
+	message := err.Error()
+	if i := strings.LastIndex(message, ": "); i >= 0 {
+		message = message[i+2:]
+	}
+	return message
+}
@@ -0,0 +1,499 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/pozgo/OhMySSH/pkg/remote"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// recentShown is how many recent commands the prompt offers.
+const recentShown = 10
+
+// broadcastConcurrency is how many hosts a command runs on at a time.
+const broadcastConcurrency = 10
+
+// commandPrompt asks for a command to run on one or more hosts. ↑ and ↓
+// step through the recent commands kept in the state.
+type commandPrompt struct {
+	hosts  []string
+	input  textinput.Model
+	recent int // index into the recent commands, -1 while typing
+}
+
+// commandOutput is the pane showing the last remote command, with a tab per
+// host and a summary tab in front when it ran on several. run tells updates
+// of the current run apart from those of one that was replaced.
+type commandOutput struct {
+	command string
+	hosts   []string
+	results map[string]remote.Result // partial while the host is running
+	running map[string]bool
+	cancel  context.CancelFunc
+	run     int
+	tab     int
+	offset  int
+}
+
+// broadcastMsg delivers the output that arrived since the last one. closed
+// is set once every host is done.
+type broadcastMsg struct {
+	run     int
+	updates []remote.Update
+	updateC <-chan remote.Update
+	closed  bool
+}
+
+// commandTargets are the hosts a command runs on: the marked ones, or else
+// the selected host unless it is a wildcard block.
+func (m model) commandTargets() []string {
+	if marked := m.markedHosts(); len(marked) > 0 {
+		return marked
+	}
+	if host, ok := m.currentHost(); ok && !host.IsWildcard() {
+		return []string{host.Name}
+	}
+	return nil
+}
+
+func (m model) openCommandPrompt(hosts []string) (tea.Model, tea.Cmd) {
+	if len(hosts) == 0 {
+		return m, nil
+	}
+	input := textinput.New()
+	input.Prompt = "$ "
+	input.Placeholder = "uptime"
+	input.CharLimit = 1024
+	input.Focus()
+	m.prompt = commandPrompt{hosts: hosts, input: input, recent: -1}
+	m.currentMode = modeCommand
+	return m, textinput.Blink
+}
+
+func (m model) handleCommandPromptKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc":
+		m.currentMode = modeNormal
+		return m, nil
+	case "enter":
+		command := strings.TrimSpace(m.prompt.input.Value())
+		if command == "" {
+			return m, nil
+		}
+		return m.runRemoteCommand(m.prompt.hosts, command)
+	case "up":
+		if m.prompt.recent < len(m.state.Commands)-1 && m.prompt.recent < recentShown-1 {
+			m.prompt.recent++
+			m.prompt.input.SetValue(m.state.Commands[m.prompt.recent])
+			m.prompt.input.CursorEnd()
+		}
+		return m, nil
+	case "down":
+		if m.prompt.recent >= 0 {
+			m.prompt.recent--
+			value := ""
+			if m.prompt.recent >= 0 {
+				value = m.state.Commands[m.prompt.recent]
+			}
+			m.prompt.input.SetValue(value)
+			m.prompt.input.CursorEnd()
+		}
+		return m, nil
+	}
+
+	var cmd tea.Cmd
+	m.prompt.input, cmd = m.prompt.input.Update(msg)
+	m.prompt.recent = -1
+	return m, cmd
+}
+
+// runRemoteCommand remembers the command and runs it on all hosts in the
+// background, showing the output pane meanwhile.
+func (m model) runRemoteCommand(hosts []string, command string) (tea.Model, tea.Cmd) {
+	m.state.RememberCommand(command)
+	if err := m.state.Save(); err != nil {
+		m.setError(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	output := commandOutput{
+		command: command,
+		hosts:   hosts,
+		results: make(map[string]remote.Result, len(hosts)),
+		running: make(map[string]bool, len(hosts)),
+		cancel:  cancel,
+		run:     m.output.run + 1,
+	}
+	for _, host := range hosts {
+		output.results[host] = remote.Result{Host: host, Command: command, ExitCode: -1, Started: time.Now()}
+		output.running[host] = true
+	}
+	m.output = output
+	m.currentMode = modeOutput
+
+	updates := make(chan remote.Update, 64)
+	go func() {
+		defer cancel()
+		remote.NewRunner().Broadcast(ctx, hosts, command, broadcastConcurrency, updates)
+	}()
+	return m, waitForUpdates(output.run, updates)
+}
+
+// waitForUpdates waits for output and then takes everything else that is
+// already there, so that chatty hosts do not cause a redraw per line.
+func waitForUpdates(run int, updates <-chan remote.Update) tea.Cmd {
+	return func() tea.Msg {
+		msg := broadcastMsg{run: run, updateC: updates}
+		update, ok := <-updates
+		if !ok {
+			msg.closed = true
+			return msg
+		}
+		msg.updates = append(msg.updates, update)
+		for len(msg.updates) < 256 {
+			select {
+			case update, ok := <-updates:
+				if !ok {
+					msg.closed = true
+					return msg
+				}
+				msg.updates = append(msg.updates, update)
+			default:
+				return msg
+			}
+		}
+		return msg
+	}
+}
+
+func (m model) applyBroadcast(msg broadcastMsg) (tea.Model, tea.Cmd) {
+	if msg.run != m.output.run {
+		return m, nil
+	}
+	for _, update := range msg.updates {
+		result := m.output.results[update.Host]
+		if update.Done {
+			result = update.Result
+			delete(m.output.running, update.Host)
+		} else {
+			result.Stdout += update.Stdout
+			result.Stderr += update.Stderr
+		}
+		m.output.results[update.Host] = result
+	}
+	if msg.closed {
+		return m, nil
+	}
+	return m, waitForUpdates(msg.run, msg.updateC)
+}
+
+// tabs names the tabs of the output pane; "" is the summary.
+func (o commandOutput) tabs() []string {
+	if len(o.hosts) == 1 {
+		return o.hosts
+	}
+	return append([]string{""}, o.hosts...)
+}
+
+// failedHosts are the hosts the command did not succeed on.
+func (o commandOutput) failedHosts() []string {
+	var failed []string
+	for _, host := range o.hosts {
+		if !o.running[host] && !o.results[host].OK() {
+			failed = append(failed, host)
+		}
+	}
+	return failed
+}
+
+func (m model) handleOutputKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	lines := len(m.outputLines())
+	page := m.outputPageSize()
+	running := len(m.output.running) > 0
+	switch msg.String() {
+	case "esc", "q", "ctrl+c":
+		if running {
+			m.output.cancel()
+			return m, nil
+		}
+		m.currentMode = modeNormal
+	case "tab", "right", "l":
+		m.output.tab = (m.output.tab + 1) % len(m.output.tabs())
+		m.output.offset = 0
+		return m, nil
+	case "shift+tab", "left", "h":
+		m.output.tab = (m.output.tab + len(m.output.tabs()) - 1) % len(m.output.tabs())
+		m.output.offset = 0
+		return m, nil
+	case "up", "k":
+		m.output.offset--
+	case "down", "j":
+		m.output.offset++
+	case "pgup", "b":
+		m.output.offset -= page
+	case "pgdown", "f":
+		m.output.offset += page
+	case "g", "home":
+		m.output.offset = 0
+	case "G", "end":
+		m.output.offset = lines
+	case "r":
+		if !running {
+			return m.runRemoteCommand(m.output.hosts, m.output.command)
+		}
+	case "F":
+		if failed := m.output.failedHosts(); !running && len(failed) > 0 {
+			return m.runRemoteCommand(failed, m.output.command)
+		}
+	case "!":
+		if !running {
+			return m.openCommandPrompt(m.output.hosts)
+		}
+	}
+	if m.output.offset > lines-page {
+		m.output.offset = lines - page
+	}
+	if m.output.offset < 0 {
+		m.output.offset = 0
+	}
+	return m, nil
+}
+
+// ansiSequence matches terminal escape sequences, such as colors, which
+// would garble the output pane.
+var ansiSequence = regexp.MustCompile(`\x1b(\[[0-9;?]*[ -/]*[@-~]|\][^\x07\x1b]*(\x07|\x1b\\)|[@-Z\\-_])`)
+
+// displayLines cleans captured output for the pane and wraps it to width.
+func displayLines(output string, width int) []string {
+	output = ansiSequence.ReplaceAllString(output, "")
+	output = strings.ReplaceAll(output, "\r\n", "\n")
+	output = strings.ReplaceAll(output, "\t", "    ")
+	var lines []string
+	for _, line := range strings.Split(strings.TrimRight(output, "\n"), "\n") {
+		runes := []rune(strings.Map(func(r rune) rune {
+			if r < 0x20 || r == 0x7f {
+				return -1
+			}
+			return r
+		}, line))
+		for len(runes) > width {
+			lines = append(lines, string(runes[:width]))
+			runes = runes[width:]
+		}
+		lines = append(lines, string(runes))
+	}
+	return lines
+}
+
+// outputLines is the scrollable content of the current tab: the per-host
+// summary, or stdout and then stderr in red.
+func (m model) outputLines() []string {
+	host := m.output.tabs()[m.output.tab]
+	if host == "" {
+		return m.summaryLines()
+	}
+	result := m.output.results[host]
+	width := m.width - 6
+	if width < 10 {
+		width = 10
+	}
+	var lines []string
+	if result.Stdout != "" {
+		lines = append(lines, displayLines(result.Stdout, width)...)
+	}
+	if result.Stderr != "" {
+		errStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("196"))
+		if len(lines) > 0 {
+			lines = append(lines, "")
+		}
+		lines = append(lines, lipgloss.NewStyle().Bold(true).Render("stderr"))
+		for _, line := range displayLines(result.Stderr, width) {
+			lines = append(lines, errStyle.Render(line))
+		}
+	}
+	if result.Truncated {
+		lines = append(lines, "", fmt.Sprintf("(output cut at %d KiB)", remote.MaxOutput/1024))
+	}
+	return lines
+}
+
+// summaryLines lists how the command went on each host.
+func (m model) summaryLines() []string {
+	width := 0
+	for _, host := range m.output.hosts {
+		if len(host) > width {
+			width = len(host)
+		}
+	}
+	var lines []string
+	for _, host := range m.output.hosts {
+		icon, text, color := m.resultStatus(host)
+		lines = append(lines, lipgloss.NewStyle().Foreground(color).Render(fmt.Sprintf("%s %-*s  %s", icon, width, host, text)))
+	}
+	return lines
+}
+
+// resultStatus describes how the command went on host.
+func (m model) resultStatus(host string) (icon, text string, color lipgloss.Color) {
+	result := m.output.results[host]
+	switch {
+	case m.output.running[host]:
+		return "⏳", "running", lipgloss.Color("214")
+	case result.Err != nil:
+		return "✗", result.Err.Error(), lipgloss.Color("196")
+	case result.ExitCode == 0:
+		return "✓", fmt.Sprintf("exit 0 in %s", formatLatency(result.Duration)), lipgloss.Color("42")
+	case result.ExitCode == remote.SSHFailure:
+		return "✗", fmt.Sprintf("exit %d: ssh failed, see stderr (keys or agent are needed, passwords cannot be asked)", result.ExitCode), lipgloss.Color("196")
+	default:
+		return "✗", fmt.Sprintf("exit %d in %s", result.ExitCode, formatLatency(result.Duration)), lipgloss.Color("196")
+	}
+}
+
+// outputSummary is the first line of the output pane: how the run went on
+// the host of the current tab, or on all hosts.
+func (m model) outputSummary() string {
+	host := m.output.tabs()[m.output.tab]
+	if host != "" {
+		icon, text, color := m.resultStatus(host)
+		if m.output.running[host] {
+			text = fmt.Sprintf("running since %s...", m.output.results[host].Started.Format("15:04:05"))
+		}
+		return lipgloss.NewStyle().Foreground(color).Render(icon + " " + text)
+	}
+	failed := len(m.output.failedHosts())
+	running := len(m.output.running)
+	ok := len(m.output.hosts) - failed - running
+	color := lipgloss.Color("42")
+	switch {
+	case running > 0:
+		color = lipgloss.Color("214")
+	case failed > 0:
+		color = lipgloss.Color("196")
+	}
+	return lipgloss.NewStyle().Foreground(color).Render(
+		fmt.Sprintf("%d hosts: %d ok, %d failed, %d running", len(m.output.hosts), ok, failed, running))
+}
+
+// renderOutputTabs draws the tab bar, scrolled so the current tab shows.
+func (m model) renderOutputTabs() string {
+	var labels []string
+	for _, host := range m.output.tabs() {
+		if host == "" {
+			labels = append(labels, " Summary ")
+			continue
+		}
+		icon, _, _ := m.resultStatus(host)
+		labels = append(labels, fmt.Sprintf(" %s %s ", icon, host))
+	}
+
+	// Drop tabs on the left until the current one fits
+	fits := func(from, to int) bool {
+		width := 0
+		for _, label := range labels[from : to+1] {
+			width += lipgloss.Width(label) + 1
+		}
+		return width <= m.width-4
+	}
+	start := 0
+	for start < m.output.tab && !fits(start, m.output.tab) {
+		start++
+	}
+	var tabs []string
+	for i := start; i < len(labels) && fits(start, i); i++ {
+		style := lipgloss.NewStyle().Foreground(lipgloss.Color("250"))
+		if i == m.output.tab {
+			style = style.Bold(true).Foreground(lipgloss.Color("15")).Background(lipgloss.Color("62"))
+		}
+		tabs = append(tabs, style.Render(labels[i]))
+	}
+	return strings.Join(tabs, " ")
+}
+
+// outputPageSize is how many output lines fit in the pane below the summary
+// line and the tab bar, leaving room for the position indicator.
+func (m model) outputPageSize() int {
+	page := m.height - 9
+	if len(m.output.hosts) > 1 {
+		page -= 2
+	}
+	if page > 1 {
+		return page
+	}
+	return 1
+}
+
+func (m model) renderOutput() string {
+	height := m.height - 1
+	lines := m.outputLines()
+	visible := m.outputPageSize()
+	end := m.output.offset + visible
+	if end > len(lines) {
+		end = len(lines)
+	}
+
+	var items []string
+	if len(m.output.hosts) > 1 {
+		items = append(items, m.renderOutputTabs(), "")
+	}
+	items = append(items, m.outputSummary(), "")
+	items = append(items, lines[m.output.offset:end]...)
+	if len(lines) > visible {
+		items = append(items, lipgloss.NewStyle().Foreground(lipgloss.Color("241")).Render(
+			fmt.Sprintf("lines %d-%d of %d", m.output.offset+1, end, len(lines))))
+	}
+
+	target := m.output.hosts[0]
+	if len(m.output.hosts) > 1 {
+		target = fmt.Sprintf("%d hosts", len(m.output.hosts))
+	}
+	title := fmt.Sprintf("💻 %s $ %s", target, truncate(m.output.command, m.width-20))
+	panel := renderPanel(title, lipgloss.Color("62"), m.width, height, items)
+	var helpBar string
+	switch {
+	case len(m.output.running) > 0:
+		helpBar = m.renderHelpBar("tab: switch host", "esc: cancel")
+	case len(m.output.hosts) > 1:
+		helpBar = m.renderHelpBar("tab/←→: switch host", "↑↓/pgup/pgdn: scroll", "r: run again", "F: again on failed", "!: new command", "esc/q: back")
+	default:
+		helpBar = m.renderHelpBar("↑↓/pgup/pgdn: scroll", "r: run again", "!: new command", "esc/q: back")
+	}
+	return lipgloss.JoinVertical(lipgloss.Left, panel, helpBar)
+}
+
+func (m model) renderCommandPrompt() string {
+	dim := lipgloss.NewStyle().Foreground(lipgloss.Color("241"))
+	target := m.prompt.hosts[0]
+	if len(m.prompt.hosts) > 1 {
+		target = fmt.Sprintf("%d hosts", len(m.prompt.hosts))
+	}
+	lines := []string{
+		lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("205")).Render("💻 Run a command on " + target),
+	}
+	if len(m.prompt.hosts) > 1 {
+		lines = append(lines, dim.Render(truncate(strings.Join(m.prompt.hosts, ", "), m.width/2)))
+	}
+	lines = append(lines, "", m.prompt.input.View())
+	if len(m.state.Commands) > 0 {
+		lines = append(lines, "", lipgloss.NewStyle().Bold(true).Render("Recent"))
+		for i, command := range m.state.Commands {
+			if i >= recentShown {
+				break
+			}
+			if i == m.prompt.recent {
+				lines = append(lines, lipgloss.NewStyle().Foreground(lipgloss.Color("205")).Render("▸ "+command))
+			} else {
+				lines = append(lines, dim.Render("  "+command))
+			}
+		}
+	}
+	lines = append(lines, "", dim.Render("enter: run | ↑↓: recent commands | esc: cancel"))
+	return m.renderDialog(dialogStyle(m.width, lipgloss.Color("205")).Render(strings.Join(lines, "\n")))
+}
@@ -0,0 +1,296 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/pozgo/OhMySSH/pkg/revision"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// maxRevisions bounds the undo history kept for a session.
+const maxRevisions = 100
+
+type revisionRow struct {
+	rev     revision.Revision
+	undone  bool
+	current bool
+}
+
+// readConfigFile returns the current content of the main config or of a file
+// pulled in through Include.
+func (m model) readConfigFile(path string) (string, error) {
+	if path == "" || path == m.sshConfig.Path {
+		return m.configContent, nil
+	}
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return string(content), nil
+}
+
+// checkConfigFiles makes sure each file on disk still has the given content,
+// so that undo and redo never discard a change made outside this step, e.g.
+// by ohmyssh add from another shell.
+func checkConfigFiles(expected map[string]string) error {
+	for path, content := range expected {
+		current, err := ioutil.ReadFile(path)
+		if err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to read %s: %v", path, err)
+		}
+		if string(current) != content {
+			return fmt.Errorf("%s has changed since this step was made", path)
+		}
+	}
+	return nil
+}
+
+// renameHostData moves the favorites, stats, overrides and tunnels kept for
+// an alias to its new name.
+func (m *model) renameHostData(oldName, newName string) {
+	if err := m.moveTunnels(oldName, newName); err != nil {
+		m.setError(err)
+	}
+	m.state.RenameHost(oldName, newName)
+	if err := m.state.Save(); err != nil {
+		m.setError(err)
+	}
+}
+
+// replaceConfigFiles writes the given content to each file and reloads the
+// server list without touching the undo history.
+func (m *model) replaceConfigFiles(contents map[string]string) error {
+	for path, content := range contents {
+		if err := writeConfigFile(path, content); err != nil {
+			return err
+		}
+		if path == m.sshConfig.Path {
+			m.configContent = content
+		}
+	}
+	m.reloadHosts()
+	return nil
+}
+
+// applyConfigChanges writes the After content of each change, reloads the
+// server list and records the step for undo.
+func (m *model) applyConfigChanges(label string, changes []revision.FileChange) error {
+	return m.applyRevision(revision.Revision{Label: label, Changes: changes})
+}
+
+// applyRevision writes the After content of each change of rev, moves the
+// data of renamed hosts, reloads the server list and records rev for undo.
+func (m *model) applyRevision(rev revision.Revision) error {
+	contents := make(map[string]string, len(rev.Changes))
+	for _, change := range rev.Changes {
+		if change.Before != change.After {
+			contents[change.Path] = change.After
+		}
+	}
+	if err := m.replaceConfigFiles(contents); err != nil {
+		return err
+	}
+	for _, rename := range rev.Renames {
+		m.renameHostData(rename.From, rename.To)
+	}
+	m.revisions.Record(rev)
+	return nil
+}
+
+func (m model) undoChange() (tea.Model, tea.Cmd) {
+	rev, ok := m.revisions.Undo()
+	if !ok {
+		m.setStatus("Nothing to undo")
+		return m, nil
+	}
+	expected := make(map[string]string, len(rev.Changes))
+	contents := make(map[string]string, len(rev.Changes))
+	for _, change := range rev.Changes {
+		expected[change.Path] = change.After
+		contents[change.Path] = change.Before
+	}
+	err := checkConfigFiles(expected)
+	if err == nil {
+		err = m.replaceConfigFiles(contents)
+	}
+	if err != nil {
+		m.revisions.Redo()
+		m.setError(fmt.Errorf("cannot undo %q: %v", rev.Label, err))
+		return m, nil
+	}
+	for i := len(rev.Renames) - 1; i >= 0; i-- {
+		m.renameHostData(rev.Renames[i].To, rev.Renames[i].From)
+	}
+	if !m.statusIsError {
+		m.setStatus("Undid: " + rev.Label)
+	}
+	return m, nil
+}
+
+func (m model) redoChange() (tea.Model, tea.Cmd) {
+	rev, ok := m.revisions.Redo()
+	if !ok {
+		m.setStatus("Nothing to redo")
+		return m, nil
+	}
+	expected := make(map[string]string, len(rev.Changes))
+	contents := make(map[string]string, len(rev.Changes))
+	for _, change := range rev.Changes {
+		expected[change.Path] = change.Before
+		contents[change.Path] = change.After
+	}
+	err := checkConfigFiles(expected)
+	if err == nil {
+		err = m.replaceConfigFiles(contents)
+	}
+	if err != nil {
+		m.revisions.Undo()
+		m.setError(fmt.Errorf("cannot redo %q: %v", rev.Label, err))
+		return m, nil
+	}
+	for _, rename := range rev.Renames {
+		m.renameHostData(rename.From, rename.To)
+	}
+	if !m.statusIsError {
+		m.setStatus("Redid: " + rev.Label)
+	}
+	return m, nil
+}
+
+// revisionRows lists the history newest first: steps that can be redone on
+// top, followed by the applied steps with the current state marked.
+func (m model) revisionRows() []revisionRow {
+	var rows []revisionRow
+	undone := m.revisions.Undone()
+	for i := len(undone) - 1; i >= 0; i-- {
+		rows = append(rows, revisionRow{rev: undone[i], undone: true})
+	}
+	applied := m.revisions.Applied()
+	for i := len(applied) - 1; i >= 0; i-- {
+		rows = append(rows, revisionRow{rev: applied[i], current: i == len(applied)-1})
+	}
+	return rows
+}
+
+func (m model) handleRevisionKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	rows := m.revisionRows()
+	switch msg.String() {
+	case "esc", "q", "U":
+		m.currentMode = modeNormal
+		return m, nil
+	case "up", "k":
+		if m.revisionIdx > 0 {
+			m.revisionIdx--
+		}
+	case "down", "j":
+		if m.revisionIdx < len(rows)-1 {
+			m.revisionIdx++
+		}
+	case "u":
+		return m.undoChange()
+	case "ctrl+r":
+		return m.redoChange()
+	}
+	return m, nil
+}
+
+func (m model) renderRevisions() string {
+	rows := m.revisionRows()
+	if m.revisionIdx >= len(rows) {
+		m.revisionIdx = len(rows) - 1
+	}
+
+	leftWidth := int(float64(m.width) * 0.35)
+	rightWidth := m.width - leftWidth
+	height := m.height - 1
+
+	var items []string
+	if len(rows) == 0 {
+		items = append(items, "No changes made in this session")
+	}
+	for i, row := range rows {
+		inserted, deleted := 0, 0
+		for _, change := range row.rev.Changes {
+			i, d := revision.Stat(revision.Diff(change.Before, change.After))
+			inserted += i
+			deleted += d
+		}
+		marker := "  "
+		if row.current {
+			marker = "● "
+		} else if row.undone {
+			marker = "↷ "
+		}
+		line := fmt.Sprintf("%s%s %s (+%d -%d)", marker, row.rev.Time.Format("15:04:05"), row.rev.Label, inserted, deleted)
+
+		style := lipgloss.NewStyle().MaxWidth(leftWidth - 5)
+		if row.undone {
+			style = style.Foreground(lipgloss.Color("241"))
+		}
+		if i == m.revisionIdx {
+			style = style.Bold(true).Foreground(lipgloss.Color("15")).Background(lipgloss.Color("62"))
+		}
+		items = append(items, style.Render(line))
+	}
+
+	var diffLines []string
+	if m.revisionIdx >= 0 && m.revisionIdx < len(rows) {
+		for _, change := range rows[m.revisionIdx].rev.Changes {
+			diffLines = append(diffLines, lipgloss.NewStyle().Bold(true).Render(change.Path), "")
+			diffLines = append(diffLines, renderDiff(revision.Diff(change.Before, change.After), 2)...)
+			diffLines = append(diffLines, "")
+		}
+	}
+
+	list := renderPanel("🕘 CHANGE HISTORY", lipgloss.Color("62"), leftWidth, height, items)
+	diff := renderPanel("🔀 CHANGES", lipgloss.Color("214"), rightWidth, height, diffLines)
+	helpBar := m.renderHelpBar("↑↓: select step", "u: undo", "ctrl+r: redo", "esc/q: back")
+
+	return lipgloss.JoinVertical(lipgloss.Left, lipgloss.JoinHorizontal(lipgloss.Top, list, diff), helpBar)
+}
+
+// renderDiff colors a diff and collapses unchanged runs to context lines
+// around each change.
+func renderDiff(diff []revision.DiffLine, context int) []string {
+	insertStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("46"))
+	deleteStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("196"))
+	equalStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("245"))
+
+	near := make([]bool, len(diff))
+	for i, line := range diff {
+		if line.Op == revision.OpEqual {
+			continue
+		}
+		for j := i - context; j <= i+context; j++ {
+			if j >= 0 && j < len(diff) {
+				near[j] = true
+			}
+		}
+	}
+
+	var lines []string
+	skipped := false
+	for i, line := range diff {
+		if !near[i] {
+			if !skipped {
+				lines = append(lines, equalStyle.Render("  …"))
+				skipped = true
+			}
+			continue
+		}
+		skipped = false
+		switch line.Op {
+		case revision.OpInsert:
+			lines = append(lines, insertStyle.Render("+ "+line.Text))
+		case revision.OpDelete:
+			lines = append(lines, deleteStyle.Render("- "+line.Text))
+		default:
+			lines = append(lines, equalStyle.Render("  "+line.Text))
+		}
+	}
+	return lines
+}
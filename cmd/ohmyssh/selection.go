@@ -0,0 +1,67 @@
+package main
+
+import (
+	"fmt"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// markedHosts returns the marked hosts that still exist, in config order.
+func (m model) markedHosts() []string {
+	var names []string
+	for _, host := range m.hosts {
+		if m.marked[host.Name] && !host.IsWildcard() {
+			names = append(names, host.Name)
+		}
+	}
+	return names
+}
+
+// rowHosts returns the hosts a row stands for: the host itself, or every
+// host below a group that passes the filter, collapsed or not. Wildcard
+// blocks are left out, since there is nothing to connect to.
+func (m model) rowHosts(row listRow) []string {
+	if !row.isGroup() {
+		if host := m.hosts[row.hostIdx]; !host.IsWildcard() {
+			return []string{host.Name}
+		}
+		return nil
+	}
+	var names []string
+	for _, host := range m.hosts {
+		if !m.hostMatchesFilter(host) || host.IsWildcard() {
+			continue
+		}
+		if row.isFavorites() && m.isFavorite(host.Name) || !row.isFavorites() && inGroup(host.Meta.Group, row.group) {
+			names = append(names, host.Name)
+		}
+	}
+	return names
+}
+
+// toggleMark marks or unmarks the host at row. On a group row it marks all
+// hosts below it, or unmarks them if they are all marked already.
+func (m model) toggleMark(row listRow) (tea.Model, tea.Cmd) {
+	names := m.rowHosts(row)
+	all := len(names) > 0
+	for _, name := range names {
+		all = all && m.marked[name]
+	}
+	for _, name := range names {
+		if all {
+			delete(m.marked, name)
+		} else {
+			m.marked[name] = true
+		}
+	}
+	return m, nil
+}
+
+// markedHeader is the server list line telling how many hosts are marked.
+func (m model) markedHeader() string {
+	count := len(m.markedHosts())
+	if count == 0 {
+		return ""
+	}
+	return fmt.Sprintf("✅ %d marked, !: run", count)
+}
@@ -0,0 +1,303 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/pozgo/OhMySSH/pkg/parser"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// listRow is one line of the server list: either a group header or a host.
+type listRow struct {
+	group   string // full group path, set for group rows
+	label   string
+	depth   int
+	hostIdx int    // index into model.hosts, -1 for group rows
+	count   int    // number of hosts below a group row
+	parent  string // group path a host row is listed under
+}
+
+func (r listRow) isGroup() bool {
+	return r.hostIdx < 0
+}
+
+func (r listRow) isFavorites() bool {
+	return r.group == favoritesGroup
+}
+
+type groupNode struct {
+	name     string
+	path     string
+	children []*groupNode
+	hosts    []int
+}
+
+func (n *groupNode) child(name string) *groupNode {
+	for _, c := range n.children {
+		if c.name == name {
+			return c
+		}
+	}
+	path := name
+	if n.path != "" {
+		path = n.path + "/" + name
+	}
+	c := &groupNode{name: name, path: path}
+	n.children = append(n.children, c)
+	return c
+}
+
+func (n *groupNode) size() int {
+	total := len(n.hosts)
+	for _, c := range n.children {
+		total += c.size()
+	}
+	return total
+}
+
+// visibleRows builds the server list: pinned favorites, then a tree of
+// groups followed by ungrouped hosts, ordered by the sort mode. Collapsed
+// groups hide their hosts unless a filter is active.
+func (m model) visibleRows() []listRow {
+	root := &groupNode{}
+	for i, host := range m.hosts {
+		if !m.hostMatchesFilter(host) {
+			continue
+		}
+		node := root
+		for _, part := range host.Meta.GroupPath() {
+			node = node.child(part)
+		}
+		node.hosts = append(node.hosts, i)
+	}
+	m.sortNode(root)
+
+	rows := m.favoriteRows()
+	var walk func(node *groupNode, depth int)
+	walk = func(node *groupNode, depth int) {
+		for _, c := range node.children {
+			rows = append(rows, listRow{group: c.path, label: c.name, depth: depth, hostIdx: -1, count: c.size()})
+			if !m.collapsed[c.path] || m.filterActive() {
+				walk(c, depth+1)
+			}
+		}
+		for _, idx := range node.hosts {
+			rows = append(rows, listRow{label: m.hosts[idx].Name, depth: depth, hostIdx: idx, parent: node.path})
+		}
+	}
+	walk(root, 0)
+	return rows
+}
+
+func (m model) filterActive() bool {
+	return strings.TrimSpace(m.filterInput.Value()) != "" || m.scope != ""
+}
+
+// hostMatchesFilter applies the group scope and the filter text. Every term
+// of the filter has to match; group:<path>, tag:<name> and owner:<name>
+// narrow the search, anything else is matched against the host's fields,
+// notes and annotations.
+func (m model) hostMatchesFilter(host parser.Host) bool {
+	if m.scope != "" && !inGroup(host.Meta.Group, m.scope) {
+		return false
+	}
+
+	text := hostSearchText(host)
+	for _, term := range strings.Fields(strings.ToLower(m.filterInput.Value())) {
+		switch {
+		case strings.HasPrefix(term, "group:"):
+			if !inGroup(strings.ToLower(host.Meta.Group), strings.TrimPrefix(term, "group:")) {
+				return false
+			}
+		case strings.HasPrefix(term, "tag:"):
+			if !hasTag(host, strings.TrimPrefix(term, "tag:")) {
+				return false
+			}
+		case strings.HasPrefix(term, "owner:"):
+			if !strings.Contains(strings.ToLower(host.Meta.Owner), strings.TrimPrefix(term, "owner:")) {
+				return false
+			}
+		default:
+			if !strings.Contains(text, term) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// hostSearchText is the lowercase text the filter searches in.
+func hostSearchText(host parser.Host) string {
+	fields := []string{host.Name, host.Hostname, host.User, host.Meta.Group, host.Meta.Owner, host.Meta.Notes}
+	fields = append(fields, host.Meta.Tags...)
+	for _, value := range host.Meta.Extra {
+		fields = append(fields, value)
+	}
+	return strings.ToLower(strings.Join(fields, " "))
+}
+
+// inGroup reports whether group equals scope or is nested below it.
+func inGroup(group, scope string) bool {
+	return group == scope || strings.HasPrefix(group, scope+"/")
+}
+
+func hasTag(host parser.Host, tag string) bool {
+	for _, t := range host.Meta.Tags {
+		if strings.EqualFold(t, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// currentRow returns the row under the cursor.
+func (m model) currentRow() (listRow, bool) {
+	rows := m.visibleRows()
+	if m.selectedIdx < 0 || m.selectedIdx >= len(rows) {
+		return listRow{}, false
+	}
+	return rows[m.selectedIdx], true
+}
+
+// clampSelection keeps the cursor within the visible rows.
+func (m *model) clampSelection() {
+	rows := m.visibleRows()
+	if m.selectedIdx >= len(rows) {
+		m.selectedIdx = len(rows) - 1
+	}
+	if m.selectedIdx < 0 {
+		m.selectedIdx = 0
+	}
+}
+
+// selectedGroup returns the group of the selected row: the group itself for a
+// group row, or the host's group.
+func (m model) selectedGroup() string {
+	row, ok := m.currentRow()
+	if !ok {
+		return ""
+	}
+	if row.isFavorites() {
+		return ""
+	}
+	if row.isGroup() {
+		return row.group
+	}
+	return m.hosts[row.hostIdx].Meta.Group
+}
+
+func (m *model) toggleGroup(path string) {
+	m.collapsed[path] = !m.collapsed[path]
+	m.clampSelection()
+}
+
+// collapseSelected collapses the selected group, or jumps from a host to the
+// header of its group.
+func (m model) collapseSelected() (tea.Model, tea.Cmd) {
+	row, ok := m.currentRow()
+	if !ok {
+		return m, nil
+	}
+	if row.isGroup() && !m.collapsed[row.group] {
+		m.toggleGroup(row.group)
+		return m, nil
+	}
+
+	var parent string
+	if row.isGroup() {
+		if idx := strings.LastIndex(row.group, "/"); idx >= 0 {
+			parent = row.group[:idx]
+		}
+	} else {
+		parent = row.parent
+	}
+	if parent == "" {
+		return m, nil
+	}
+	for i, r := range m.visibleRows() {
+		if r.isGroup() && r.group == parent {
+			m.selectedIdx = i
+			break
+		}
+	}
+	return m, nil
+}
+
+func (m model) expandSelected() (tea.Model, tea.Cmd) {
+	if row, ok := m.currentRow(); ok && row.isGroup() && m.collapsed[row.group] {
+		m.toggleGroup(row.group)
+	}
+	return m, nil
+}
+
+// toggleScope limits the list to the selected group, or clears the scope.
+func (m model) toggleScope() (tea.Model, tea.Cmd) {
+	if m.scope != "" {
+		m.scope = ""
+		m.clampSelection()
+		return m, nil
+	}
+	if group := m.selectedGroup(); group != "" {
+		m.scope = group
+		m.selectedIdx = 0
+	}
+	return m, nil
+}
+
+func (m model) startFilter() (tea.Model, tea.Cmd) {
+	m.currentMode = modeFilter
+	m.filterInput.Focus()
+	m.filterInput.CursorEnd()
+	return m, nil
+}
+
+func (m model) handleFilterKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc":
+		m.filterInput.SetValue("")
+		m.filterInput.Blur()
+		m.currentMode = modeNormal
+		m.clampSelection()
+		return m, nil
+	case "enter":
+		m.filterInput.Blur()
+		m.currentMode = modeNormal
+		return m, nil
+	case "up", "down":
+		m.currentMode = modeNormal
+		m.filterInput.Blur()
+		return m.Update(msg)
+	}
+
+	var cmd tea.Cmd
+	m.filterInput, cmd = m.filterInput.Update(msg)
+	m.selectedIdx = 0
+	m.selectFirstHost()
+	return m, cmd
+}
+
+// selectFirstHost moves the cursor to the first host row at or below it.
+func (m *model) selectFirstHost() {
+	for i, row := range m.visibleRows() {
+		if i >= m.selectedIdx && !row.isGroup() {
+			m.selectedIdx = i
+			return
+		}
+	}
+}
+
+// serverListHeader returns the filter and scope lines shown above the list.
+func (m model) serverListHeader() []string {
+	var lines []string
+	if m.currentMode == modeFilter || m.filterInput.Value() != "" {
+		lines = append(lines, m.filterInput.View())
+	}
+	if m.scope != "" {
+		lines = append(lines, "📁 scope: "+m.scope)
+	}
+	if marked := m.markedHeader(); marked != "" {
+		lines = append(lines, marked)
+	}
+	return lines
+}
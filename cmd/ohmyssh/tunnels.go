@@ -0,0 +1,349 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pozgo/OhMySSH/pkg/parser"
+	"github.com/pozgo/OhMySSH/pkg/state"
+	"github.com/pozgo/OhMySSH/pkg/tunnel"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// tunnelsView lists the tunnels saved in the state with what the supervisor
+// knows about their ssh processes.
+type tunnelsView struct {
+	statuses map[string]tunnel.Status // by tunnel ID
+	starting map[string]bool
+	idx      int
+}
+
+// tunnelStartMsg delivers the result of starting a tunnel.
+type tunnelStartMsg struct {
+	tunnel tunnel.Tunnel
+	pid    int
+	err    error
+}
+
+// tunnelSupervisor keeps the pid files and logs of tunnels next to the
+// state, so that every ohmyssh process sees the same tunnels.
+func tunnelSupervisor() *tunnel.Supervisor {
+	return tunnel.NewSupervisor(filepath.Join(state.Dir(), "tunnels"))
+}
+
+// savedTunnels returns the tunnels kept in s.
+func savedTunnels(s *state.State) []tunnel.Tunnel {
+	tunnels := make([]tunnel.Tunnel, 0, len(s.Tunnels))
+	for _, t := range s.Tunnels {
+		tunnels = append(tunnels, tunnel.Tunnel{Host: t.Host, Kind: tunnel.Kind(t.Kind), Spec: t.Spec})
+	}
+	return tunnels
+}
+
+// tunnelRecord is t as kept in the state.
+func tunnelRecord(t tunnel.Tunnel) state.Tunnel {
+	return state.Tunnel{Host: t.Host, Kind: string(t.Kind), Spec: t.Spec}
+}
+
+func (m *model) refreshTunnels() {
+	supervisor := tunnelSupervisor()
+	m.tunnels.statuses = make(map[string]tunnel.Status, len(m.state.Tunnels))
+	for _, t := range savedTunnels(m.state) {
+		m.tunnels.statuses[t.ID()] = supervisor.Status(t)
+	}
+	if m.tunnels.idx >= len(m.state.Tunnels) {
+		m.tunnels.idx = len(m.state.Tunnels) - 1
+	}
+	if m.tunnels.idx < 0 {
+		m.tunnels.idx = 0
+	}
+}
+
+// openTunnels shows all tunnels, starting at the first one of the selected
+// host.
+func (m model) openTunnels() (tea.Model, tea.Cmd) {
+	m.tunnels.idx = 0
+	if m.tunnels.starting == nil {
+		m.tunnels.starting = make(map[string]bool)
+	}
+	if host, ok := m.currentHost(); ok {
+		for i, t := range savedTunnels(m.state) {
+			if t.Host == host.Name {
+				m.tunnels.idx = i
+				break
+			}
+		}
+	}
+	m.refreshTunnels()
+	m.currentMode = modeTunnels
+	return m, nil
+}
+
+func (m model) selectedTunnel() (tunnel.Tunnel, bool) {
+	if m.tunnels.idx < len(m.state.Tunnels) {
+		return savedTunnels(m.state)[m.tunnels.idx], true
+	}
+	return tunnel.Tunnel{}, false
+}
+
+func (m model) handleTunnelsKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	m.status = ""
+	switch msg.String() {
+	case "esc", "q", "T":
+		m.currentMode = modeNormal
+	case "up", "k":
+		if m.tunnels.idx > 0 {
+			m.tunnels.idx--
+		}
+	case "down", "j":
+		if m.tunnels.idx < len(m.state.Tunnels)-1 {
+			m.tunnels.idx++
+		}
+	case "a":
+		return m.openAddTunnelForm()
+	case "enter", " ", "s":
+		return m.toggleTunnel()
+	case "x", "delete":
+		if t, ok := m.selectedTunnel(); ok {
+			m.confirm = confirmation{
+				kind:     confirmDeleteTunnel,
+				target:   t.ID(),
+				message:  fmt.Sprintf("Delete tunnel %s?", t.ID()),
+				returnTo: modeTunnels,
+			}
+			m.currentMode = modeConfirm
+		}
+	case "r":
+		m.refreshTunnels()
+	}
+	return m, nil
+}
+
+func (m model) openAddTunnelForm() (tea.Model, tea.Cmd) {
+	hostName := ""
+	if t, ok := m.selectedTunnel(); ok {
+		hostName = t.Host
+	}
+	if host, ok := m.currentHost(); ok {
+		hostName = host.Name
+	}
+	forward := newFormField("Forward", "spec", "")
+	forward.input.Placeholder = "5432:localhost:5432"
+	m.form = newForm(formAddTunnel, "🚇 Add a tunnel", "",
+		newFormField("Host", "host", hostName),
+		newFormField("Type (L, R or D)", "kind", "L"),
+		forward,
+	)
+	m.form.help = "L: local port → host side | R: host port → here | D: SOCKS | enter: save | esc: cancel"
+	m.form.returnTo = modeTunnels
+	m.currentMode = modeForm
+	return m, nil
+}
+
+func (m model) submitAddTunnel() (tea.Model, tea.Cmd) {
+	hostName := m.form.value("host")
+	if hostName == "" {
+		m.form.err = fmt.Errorf("host is required")
+		return m, nil
+	}
+	if _, ok := m.hostByName(hostName); !ok {
+		m.form.err = fmt.Errorf("%w: %s", parser.ErrHostNotFound, hostName)
+		return m, nil
+	}
+	kind, err := tunnel.ParseKind(m.form.value("kind"))
+	if err != nil {
+		m.form.err = err
+		return m, nil
+	}
+	t, err := tunnel.New(hostName, kind, m.form.value("spec"))
+	if err != nil {
+		m.form.err = err
+		return m, nil
+	}
+	if !m.state.AddTunnel(tunnelRecord(t)) {
+		m.form.err = fmt.Errorf("tunnel %s already exists", t.ID())
+		return m, nil
+	}
+	if err := m.state.Save(); err != nil {
+		m.state.RemoveTunnel(tunnelRecord(t))
+		m.form.err = err
+		return m, nil
+	}
+	m.tunnels.idx = len(m.state.Tunnels) - 1
+	m.refreshTunnels()
+	m.setStatus(fmt.Sprintf("Added tunnel %s, press enter to start it", t.ID()))
+	m.currentMode = modeTunnels
+	return m, nil
+}
+
+// toggleTunnel stops the selected tunnel if it runs and starts it otherwise.
+// Starting waits for ssh to come up, so it runs as a command.
+func (m model) toggleTunnel() (tea.Model, tea.Cmd) {
+	t, ok := m.selectedTunnel()
+	if !ok || m.tunnels.starting[t.ID()] {
+		return m, nil
+	}
+	if m.tunnels.statuses[t.ID()].Running {
+		if err := tunnelSupervisor().Stop(t); err != nil {
+			m.setError(err)
+		} else {
+			m.setStatus("Stopped tunnel " + t.ID())
+		}
+		m.refreshTunnels()
+		return m, nil
+	}
+
+	m.tunnels.starting[t.ID()] = true
+	m.setStatus("Starting tunnel " + t.ID() + "...")
+	return m, func() tea.Msg {
+		pid, err := tunnelSupervisor().Start(t)
+		return tunnelStartMsg{tunnel: t, pid: pid, err: err}
+	}
+}
+
+func (m model) finishTunnelStart(msg tunnelStartMsg) (tea.Model, tea.Cmd) {
+	delete(m.tunnels.starting, msg.tunnel.ID())
+	if msg.err != nil {
+		m.setError(fmt.Errorf("failed to start tunnel %s: %v", msg.tunnel.ID(), msg.err))
+	} else {
+		m.setStatus(fmt.Sprintf("Started tunnel %s (pid %d)", msg.tunnel.ID(), msg.pid))
+	}
+	m.refreshTunnels()
+	return m, nil
+}
+
+// deleteTunnel stops a tunnel and forgets its definition.
+func (m model) deleteTunnel(id string) (tea.Model, tea.Cmd) {
+	for _, t := range savedTunnels(m.state) {
+		if t.ID() != id {
+			continue
+		}
+		if err := tunnelSupervisor().Stop(t); err != nil {
+			m.setError(err)
+			return m, nil
+		}
+		m.state.RemoveTunnel(tunnelRecord(t))
+		if err := m.state.Save(); err != nil {
+			m.setError(err)
+			return m, nil
+		}
+		m.setStatus("Deleted tunnel " + id)
+	}
+	m.refreshTunnels()
+	return m, nil
+}
+
+// moveTunnels keeps running tunnels of a renamed host tracked. It is called
+// before the state itself follows the rename.
+func (m model) moveTunnels(oldName, newName string) error {
+	supervisor := tunnelSupervisor()
+	for _, t := range savedTunnels(m.state) {
+		if t.Host != oldName {
+			continue
+		}
+		renamed := t
+		renamed.Host = newName
+		if err := supervisor.Move(t, renamed); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// tunnelBadge is the state of a tunnel in the list.
+func (m model) tunnelBadge(t tunnel.Tunnel) (string, lipgloss.Color) {
+	status := m.tunnels.statuses[t.ID()]
+	switch {
+	case m.tunnels.starting[t.ID()]:
+		return "◌ starting", lipgloss.Color("214")
+	case status.Running:
+		return "● running", lipgloss.Color("42")
+	case status.PortInUse:
+		return "⚠ port taken", lipgloss.Color("196")
+	default:
+		return "○ stopped", lipgloss.Color("241")
+	}
+}
+
+func (m model) renderTunnels() string {
+	tunnels := savedTunnels(m.state)
+	leftWidth := int(float64(m.width) * 0.5)
+	rightWidth := m.width - leftWidth
+	height := m.height - 1
+
+	var items []string
+	if len(tunnels) == 0 {
+		items = append(items, "No tunnels yet, press a to add one")
+	}
+	running := 0
+	visible := height - 6
+	start := scrollWindow(m.tunnels.idx, len(tunnels), visible)
+	for i, t := range tunnels {
+		if m.tunnels.statuses[t.ID()].Running {
+			running++
+		}
+		if i < start || i >= start+visible {
+			continue
+		}
+		badge, color := m.tunnelBadge(t)
+		line := fmt.Sprintf("%-12s -%s %-26s %s", truncate(t.Host, 12), t.Kind, truncate(t.Spec, 26), badge)
+		style := lipgloss.NewStyle().MaxWidth(leftWidth - 5)
+		if i == m.tunnels.idx {
+			style = style.Bold(true).Foreground(lipgloss.Color("15")).Background(lipgloss.Color("62"))
+		} else {
+			style = style.Foreground(color)
+		}
+		items = append(items, style.Render(line))
+	}
+	if m.status != "" {
+		statusStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("46"))
+		if m.statusIsError {
+			statusStyle = statusStyle.Foreground(lipgloss.Color("196"))
+		}
+		items = append(items, "", statusStyle.Render(m.status))
+	}
+
+	var details []string
+	if t, ok := m.selectedTunnel(); ok {
+		status := m.tunnels.statuses[t.ID()]
+		warn := lipgloss.NewStyle().Foreground(lipgloss.Color("196"))
+		details = append(details,
+			t.Describe(),
+			"",
+			fmt.Sprintf("Host: %s", t.Host),
+			fmt.Sprintf("Command: ssh %s", strings.Join(t.Args(), " ")),
+		)
+		if _, ok := m.hostByName(t.Host); !ok {
+			details = append(details, warn.Render(fmt.Sprintf("⚠️ %s is not in the SSH config", t.Host)))
+		}
+		if status.Running {
+			details = append(details,
+				fmt.Sprintf("PID: %d", status.PID),
+				fmt.Sprintf("Up for: %s", formatDuration(time.Since(status.Started))),
+			)
+		}
+		if address := t.ListenAddress(); address != "" {
+			switch {
+			case status.Running:
+				details = append(details, fmt.Sprintf("Listening on: %s", address))
+			case status.PortInUse:
+				details = append(details, warn.Render(fmt.Sprintf("Port: %s is taken by another program", address)))
+			default:
+				details = append(details, fmt.Sprintf("Port: %s is free", address))
+			}
+		}
+		if status.LastError != "" {
+			details = append(details, "", warn.Render("Last error: "+status.LastError))
+		}
+		details = append(details, "", fmt.Sprintf("Log: %s", tunnelSupervisor().LogPath(t)))
+	}
+
+	list := renderPanel(fmt.Sprintf("🚇 TUNNELS (%d running of %d)", running, len(tunnels)), lipgloss.Color("62"), leftWidth, height, items)
+	detail := renderPanel("📄 TUNNEL DETAILS", lipgloss.Color("214"), rightWidth, height, details)
+	helpBar := m.renderHelpBar("a: add", "enter: start/stop", "x: delete", "r: refresh", "esc/q: back")
+	return lipgloss.JoinVertical(lipgloss.Left, lipgloss.JoinHorizontal(lipgloss.Top, list, detail), helpBar)
+}
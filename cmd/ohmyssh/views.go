@@ -0,0 +1,67 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// renderPanel draws a titled, bordered panel in the same style as the main
+// view panels. Lines that do not fit are cut off with a marker.
+func renderPanel(title string, color lipgloss.Color, width, height int, lines []string) string {
+	titleStyle := lipgloss.NewStyle().
+		Bold(true).
+		Foreground(color).
+		Underline(true).
+		Width(width - 2).
+		Align(lipgloss.Center)
+
+	contentHeight := height - 3 // Reserve 3 lines for title + border
+	if contentHeight < 1 {
+		contentHeight = 1
+	}
+
+	contentStyle := lipgloss.NewStyle().
+		Width(width - 1).
+		Height(contentHeight).
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(color).
+		Padding(1)
+
+	availableLines := contentHeight - 2 // 2 for borders+padding
+	if availableLines < 1 {
+		availableLines = 1
+	}
+	if len(lines) > availableLines {
+		lines = append(lines[:availableLines-1:availableLines-1], "...")
+	}
+
+	return lipgloss.JoinVertical(lipgloss.Left, titleStyle.Render(title), contentStyle.Render(strings.Join(lines, "\n")))
+}
+
+// scrollWindow returns the start index of a window of size visible that keeps
+// selected on screen.
+func scrollWindow(selected, total, visible int) int {
+	if visible <= 0 || total <= visible {
+		return 0
+	}
+	start := selected - visible/2
+	if start < 0 {
+		start = 0
+	}
+	if start > total-visible {
+		start = total - visible
+	}
+	return start
+}
+
+// renderHelpBar draws the bottom key hint line used by full-screen views.
+func (m model) renderHelpBar(items ...string) string {
+	return lipgloss.NewStyle().
+		Width(m.width).
+		Background(lipgloss.Color("240")).
+		Foreground(lipgloss.Color("255")).
+		Padding(0, 1).
+		MaxHeight(1).
+		Render(strings.Join(items, "  |  "))
+}
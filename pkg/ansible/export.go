@@ -0,0 +1,195 @@
+// Package ansible converts between ssh config hosts and Ansible inventories.
+package ansible
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pozgo/OhMySSH/pkg/parser"
+
+	"gopkg.in/yaml.v3"
+)
+
+// invalidGroupChars matches what Ansible does not accept in group names.
+var invalidGroupChars = regexp.MustCompile(`[^A-Za-z0-9_]`)
+
+// GroupName turns an ohmyssh group path such as "prod/db-eu" into a valid
+// Ansible group name, "prod_db_eu". Nested groups keep their full path so
+// that "prod/db" and "staging/db" stay apart.
+func GroupName(path string) string {
+	return invalidGroupChars.ReplaceAllString(path, "_")
+}
+
+// HostVars returns the connection variables of a host: ansible_host,
+// ansible_user, ansible_port, ansible_ssh_private_key_file and, for hosts
+// behind a jump host, ansible_ssh_common_args with the ProxyJump.
+func HostVars(host parser.InventoryHost) map[string]string {
+	vars := map[string]string{"ansible_host": host.HostName}
+	if host.User != "" {
+		vars["ansible_user"] = host.User
+	}
+	if host.Port != 0 && host.Port != 22 {
+		vars["ansible_port"] = strconv.Itoa(host.Port)
+	}
+	if identity := host.Options["identityfile"]; identity != "" {
+		vars["ansible_ssh_private_key_file"] = identity
+	}
+	if jump := host.Options["proxyjump"]; jump != "" && !strings.EqualFold(jump, "none") {
+		vars["ansible_ssh_common_args"] = "-o ProxyJump=" + jump
+	}
+	return vars
+}
+
+// group is one Ansible group with its hosts and nested groups.
+type group struct {
+	name     string
+	hosts    []string
+	children []*group
+}
+
+func (g *group) child(name string) *group {
+	for _, c := range g.children {
+		if c.name == name {
+			return c
+		}
+	}
+	c := &group{name: name}
+	g.children = append(g.children, c)
+	return c
+}
+
+// buildTree places hosts in their groups. Wildcard blocks are skipped since
+// they do not name a host, and the first pattern of a block is its name.
+func buildTree(hosts []parser.InventoryHost) (*group, map[string]map[string]string) {
+	root := &group{name: "all"}
+	vars := make(map[string]map[string]string)
+	for _, host := range hosts {
+		if host.Wildcard || len(host.Patterns) == 0 {
+			continue
+		}
+		name := host.Patterns[0]
+		if _, seen := vars[name]; seen {
+			continue
+		}
+		vars[name] = HostVars(host)
+
+		node := root
+		var path []string
+		for _, part := range strings.Split(host.Group, "/") {
+			if part = strings.TrimSpace(part); part != "" {
+				path = append(path, part)
+				node = node.child(GroupName(strings.Join(path, "/")))
+			}
+		}
+		node.hosts = append(node.hosts, name)
+	}
+	return root, vars
+}
+
+// Export renders hosts as an Ansible inventory in "ini" or "yaml" format.
+func Export(hosts []parser.InventoryHost, format string) ([]byte, error) {
+	root, vars := buildTree(hosts)
+	switch format {
+	case "ini":
+		return exportINI(root, vars), nil
+	case "yaml":
+		return exportYAML(root, vars)
+	default:
+		return nil, fmt.Errorf("unsupported inventory format %q", format)
+	}
+}
+
+func exportINI(root *group, vars map[string]map[string]string) []byte {
+	var b bytes.Buffer
+	writeHosts := func(hosts []string) {
+		for _, name := range hosts {
+			b.WriteString(name)
+			for _, key := range sortedKeys(vars[name]) {
+				fmt.Fprintf(&b, " %s=%s", key, quoteINI(vars[name][key]))
+			}
+			b.WriteString("\n")
+		}
+	}
+
+	// Hosts outside any group go first, before the first section
+	writeHosts(root.hosts)
+
+	var walk func(g *group)
+	walk = func(g *group) {
+		if len(g.hosts) > 0 {
+			fmt.Fprintf(&b, "\n[%s]\n", g.name)
+			writeHosts(g.hosts)
+		}
+		if len(g.children) > 0 {
+			fmt.Fprintf(&b, "\n[%s:children]\n", g.name)
+			for _, c := range g.children {
+				b.WriteString(c.name + "\n")
+			}
+		}
+		for _, c := range g.children {
+			walk(c)
+		}
+	}
+	for _, c := range root.children {
+		walk(c)
+	}
+	return bytes.TrimLeft(b.Bytes(), "\n")
+}
+
+// quoteINI quotes values with spaces the way Ansible's INI parser expects.
+func quoteINI(value string) string {
+	if !strings.ContainsAny(value, " \t'\"#=") {
+		return value
+	}
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
+
+// yamlGroup mirrors the YAML inventory layout; empty parts are left out.
+type yamlGroup struct {
+	Hosts    map[string]map[string]string `yaml:"hosts,omitempty"`
+	Children map[string]*yamlGroup        `yaml:"children,omitempty"`
+}
+
+func exportYAML(root *group, vars map[string]map[string]string) ([]byte, error) {
+	var convert func(g *group) *yamlGroup
+	convert = func(g *group) *yamlGroup {
+		out := &yamlGroup{}
+		for _, name := range g.hosts {
+			if out.Hosts == nil {
+				out.Hosts = make(map[string]map[string]string)
+			}
+			out.Hosts[name] = vars[name]
+		}
+		for _, c := range g.children {
+			if out.Children == nil {
+				out.Children = make(map[string]*yamlGroup)
+			}
+			out.Children[c.name] = convert(c)
+		}
+		return out
+	}
+
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(map[string]*yamlGroup{"all": convert(root)}); err != nil {
+		return nil, err
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
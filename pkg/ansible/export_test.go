@@ -0,0 +1,76 @@
+package ansible
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/pozgo/OhMySSH/pkg/parser"
+
+	"gopkg.in/yaml.v3"
+)
+
+var exportFixture = []parser.InventoryHost{
+	{Alias: "dev", Patterns: []string{"dev"}, HostName: "dev.example.com", Port: 22},
+	{
+		Alias: "db1", Patterns: []string{"db1"}, HostName: "10.0.0.5", User: "postgres", Port: 2222,
+		Group:   "prod/db-eu",
+		Options: map[string]string{"proxyjump": "ops@gw:2200", "identityfile": "~/.ssh/db key"},
+	},
+	{Alias: "web1 web", Patterns: []string{"web1", "web"}, HostName: "web1.example.com", Port: 22, Group: "prod"},
+	{Alias: "*", Patterns: []string{"*"}, Wildcard: true, User: "root"},
+}
+
+func TestGroupName(t *testing.T) {
+	if got := GroupName("prod/db-eu.1"); got != "prod_db_eu_1" {
+		t.Errorf("Expected prod_db_eu_1, got %s", got)
+	}
+}
+
+func TestExportINI(t *testing.T) {
+	data, err := Export(exportFixture, "ini")
+	if err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+
+	expected := `dev ansible_host=dev.example.com
+
+[prod]
+web1 ansible_host=web1.example.com
+
+[prod:children]
+prod_db_eu
+
+[prod_db_eu]
+db1 ansible_host=10.0.0.5 ansible_port=2222 ansible_ssh_common_args='-o ProxyJump=ops@gw:2200' ansible_ssh_private_key_file='~/.ssh/db key' ansible_user=postgres
+`
+	if string(data) != expected {
+		t.Errorf("Unexpected INI inventory:\n%s", data)
+	}
+}
+
+func TestExportYAML(t *testing.T) {
+	data, err := Export(exportFixture, "yaml")
+	if err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+
+	var inventory map[string]yamlGroup
+	if err := yaml.Unmarshal(data, &inventory); err != nil {
+		t.Fatalf("Invalid YAML: %v\n%s", err, data)
+	}
+	all := inventory["all"]
+	if !reflect.DeepEqual(all.Hosts["dev"], map[string]string{"ansible_host": "dev.example.com"}) {
+		t.Errorf("Unexpected vars for dev: %v", all.Hosts["dev"])
+	}
+	db := all.Children["prod"].Children["prod_db_eu"].Hosts["db1"]
+	if db["ansible_ssh_common_args"] != "-o ProxyJump=ops@gw:2200" || db["ansible_port"] != "2222" {
+		t.Errorf("Unexpected vars for db1: %v", db)
+	}
+	if _, ok := all.Hosts["*"]; ok {
+		t.Error("Expected wildcard blocks to be skipped")
+	}
+
+	if _, err := Export(exportFixture, "toml"); err == nil {
+		t.Error("Expected an error for an unknown format")
+	}
+}
@@ -0,0 +1,377 @@
+package ansible
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Host is a host read from an Ansible inventory with the variables that
+// apply to it, group variables included, and the groups it belongs to.
+// Groups are slash separated paths such as "prod/db".
+type Host struct {
+	Name   string
+	Vars   map[string]string
+	Groups []string
+}
+
+// inventory collects hosts in the order they first appear, with their groups.
+type inventory struct {
+	hosts      []string
+	hostVars   map[string]map[string]string
+	hostGroups map[string][]string
+	groupVars  map[string]map[string]string
+	parents    map[string]string // group -> parent group
+}
+
+func newInventory() *inventory {
+	return &inventory{
+		hostVars:   make(map[string]map[string]string),
+		hostGroups: make(map[string][]string),
+		groupVars:  make(map[string]map[string]string),
+		parents:    make(map[string]string),
+	}
+}
+
+func (inv *inventory) addHost(name, group string, vars map[string]string) {
+	if _, ok := inv.hostVars[name]; !ok {
+		inv.hosts = append(inv.hosts, name)
+		inv.hostVars[name] = make(map[string]string)
+	}
+	for key, value := range vars {
+		inv.hostVars[name][key] = value
+	}
+	if group != "" && group != "all" && group != "ungrouped" {
+		inv.hostGroups[name] = append(inv.hostGroups[name], group)
+	}
+}
+
+// ancestry returns group and its parents, outermost first. Cycles in
+// children sections are cut short.
+func (inv *inventory) ancestry(group string) []string {
+	var chain []string
+	seen := make(map[string]bool)
+	for g := group; g != "" && !seen[g]; g = inv.parents[g] {
+		seen[g] = true
+		chain = append([]string{g}, chain...)
+	}
+	return chain
+}
+
+// groupPath turns a group into a path below its top-level parent. A child
+// named after its parent, like "prod_db" below "prod", becomes "prod/db",
+// which undoes what Export does with nested groups.
+func (inv *inventory) groupPath(group string) string {
+	chain := inv.ancestry(group)
+	parts := make([]string, len(chain))
+	for i, g := range chain {
+		parts[i] = g
+		if i > 0 {
+			parts[i] = strings.TrimPrefix(g, chain[i-1]+"_")
+		}
+	}
+	return strings.Join(parts, "/")
+}
+
+// resolve applies group variables, parents first so that the more specific
+// group wins, and host variables last.
+func (inv *inventory) resolve() []Host {
+	var hosts []Host
+	for _, name := range inv.hosts {
+		host := Host{Name: name, Vars: make(map[string]string)}
+		for key, value := range inv.groupVars["all"] {
+			host.Vars[key] = value
+		}
+		for _, group := range inv.hostGroups[name] {
+			for _, g := range inv.ancestry(group) {
+				for key, value := range inv.groupVars[g] {
+					host.Vars[key] = value
+				}
+			}
+			host.Groups = append(host.Groups, inv.groupPath(group))
+		}
+		for key, value := range inv.hostVars[name] {
+			host.Vars[key] = value
+		}
+		hosts = append(hosts, host)
+	}
+	return hosts
+}
+
+// ParseInventory reads an Ansible inventory in INI or YAML format. Numeric
+// host ranges such as web[01:03] are expanded.
+func ParseInventory(data []byte) ([]Host, error) {
+	if isYAML(data) {
+		return parseYAML(data)
+	}
+	return parseINI(data)
+}
+
+// isYAML looks at the first line that is not a comment: a YAML inventory
+// opens with a document marker or a group name followed by a colon.
+func isYAML(data []byte) bool {
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+		return line == "---" || strings.HasSuffix(line, ":")
+	}
+	return false
+}
+
+func parseINI(data []byte) ([]Host, error) {
+	inv := newInventory()
+	section, kind := "ungrouped", "hosts"
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") {
+			if !strings.HasSuffix(line, "]") {
+				return nil, fmt.Errorf("line %d: invalid section %q", lineNo, line)
+			}
+			section, kind = strings.Trim(line, "[]"), "hosts"
+			if idx := strings.Index(section, ":"); idx >= 0 {
+				section, kind = section[:idx], section[idx+1:]
+			}
+			continue
+		}
+
+		fields := splitINIFields(line)
+		switch kind {
+		case "hosts":
+			vars := make(map[string]string)
+			for _, field := range fields[1:] {
+				if key, value, ok := strings.Cut(field, "="); ok {
+					vars[key] = value
+				}
+			}
+			// Like in YAML, a pattern that is no range is taken as a name
+			names, err := expandRange(fields[0])
+			if err != nil {
+				names = []string{fields[0]}
+			}
+			for _, name := range names {
+				inv.addHost(name, section, vars)
+			}
+		case "children":
+			if section != "all" {
+				inv.parents[fields[0]] = section
+			}
+		case "vars":
+			if key, value, ok := strings.Cut(line, "="); ok {
+				if inv.groupVars[section] == nil {
+					inv.groupVars[section] = make(map[string]string)
+				}
+				inv.groupVars[section][strings.TrimSpace(key)] = unquoteINI(strings.TrimSpace(value))
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return inv.resolve(), nil
+}
+
+// splitINIFields splits a host line on whitespace, keeping quoted values
+// together and removing their quotes.
+func splitINIFields(line string) []string {
+	var fields []string
+	var current strings.Builder
+	var quote byte
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case quote != 0 && c == quote:
+			quote = 0
+		case quote != 0:
+			current.WriteByte(c)
+		case c == '\'' || c == '"':
+			quote = c
+		case c == ' ' || c == '\t':
+			if current.Len() > 0 {
+				fields = append(fields, current.String())
+				current.Reset()
+			}
+		case c == '#':
+			// Comment after the host
+			i = len(line)
+		default:
+			current.WriteByte(c)
+		}
+	}
+	if current.Len() > 0 {
+		fields = append(fields, current.String())
+	}
+	return fields
+}
+
+func unquoteINI(value string) string {
+	if len(value) >= 2 && (value[0] == '\'' || value[0] == '"') && value[len(value)-1] == value[0] {
+		return value[1 : len(value)-1]
+	}
+	return value
+}
+
+// maxRangeHosts bounds how many names one host pattern may expand to.
+const maxRangeHosts = 10000
+
+// expandRange expands host ranges like db[01:10].example.com, web[a:c] or
+// db[0:20:5], as Ansible does. Several ranges expand to every combination.
+func expandRange(pattern string) ([]string, error) {
+	open := strings.Index(pattern, "[")
+	closing := strings.Index(pattern, "]")
+	if open < 0 || closing < open {
+		return []string{pattern}, nil
+	}
+	values, err := rangeValues(pattern[open+1 : closing])
+	if err != nil {
+		return nil, fmt.Errorf("unsupported host range %q: %v", pattern, err)
+	}
+	rest, err := expandRange(pattern[closing+1:])
+	if err != nil {
+		return nil, err
+	}
+	if len(values)*len(rest) > maxRangeHosts {
+		return nil, fmt.Errorf("host range %q expands to more than %d hosts", pattern, maxRangeHosts)
+	}
+
+	names := make([]string, 0, len(values)*len(rest))
+	for _, value := range values {
+		for _, suffix := range rest {
+			names = append(names, pattern[:open]+value+suffix)
+		}
+	}
+	return names, nil
+}
+
+// rangeValues lists the values of one range: numbers keeping the width of
+// the start, such as 01:10, or letters, such as a:f, with an optional
+// stride, such as 0:20:5.
+func rangeValues(spec string) ([]string, error) {
+	parts := strings.Split(spec, ":")
+	if len(parts) != 2 && len(parts) != 3 {
+		return nil, fmt.Errorf("expected start:end or start:end:stride")
+	}
+	stride := 1
+	if len(parts) == 3 {
+		n, err := strconv.Atoi(parts[2])
+		if err != nil || n < 1 {
+			return nil, fmt.Errorf("invalid stride %q", parts[2])
+		}
+		stride = n
+	}
+
+	from, to := parts[0], parts[1]
+	var values []string
+	if isLetterRange(from, to) {
+		for c := int(from[0]); c <= int(to[0]); c += stride {
+			values = append(values, string(rune(c)))
+		}
+		return values, nil
+	}
+	start, err1 := strconv.Atoi(from)
+	end, err2 := strconv.Atoi(to)
+	if err1 != nil || err2 != nil || end < start {
+		return nil, fmt.Errorf("expected numbers or letters in ascending order")
+	}
+	if (end-start)/stride > 1000 {
+		return nil, fmt.Errorf("more than 1000 values")
+	}
+	for i := start; i <= end; i += stride {
+		values = append(values, fmt.Sprintf("%0*d", len(from), i))
+	}
+	return values, nil
+}
+
+// isLetterRange reports whether from and to are single letters of the same
+// case in ascending order.
+func isLetterRange(from, to string) bool {
+	if len(from) != 1 || len(to) != 1 || from > to {
+		return false
+	}
+	lower := func(c byte) bool { return c >= 'a' && c <= 'z' }
+	upper := func(c byte) bool { return c >= 'A' && c <= 'Z' }
+	return (lower(from[0]) && lower(to[0])) || (upper(from[0]) && upper(to[0]))
+}
+
+// yamlGroupIn is the YAML inventory layout with values of any scalar type.
+type yamlGroupIn struct {
+	Hosts    map[string]map[string]interface{} `yaml:"hosts"`
+	Vars     map[string]interface{}            `yaml:"vars"`
+	Children map[string]*yamlGroupIn           `yaml:"children"`
+}
+
+func parseYAML(data []byte) ([]Host, error) {
+	var root map[string]*yamlGroupIn
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return nil, fmt.Errorf("failed to parse inventory: %v", err)
+	}
+
+	inv := newInventory()
+	var walk func(name string, g *yamlGroupIn)
+	walk = func(name string, g *yamlGroupIn) {
+		if g == nil {
+			return
+		}
+		if len(g.Vars) > 0 {
+			inv.groupVars[name] = stringify(g.Vars)
+		}
+		for _, hostName := range sortedHostNames(g.Hosts) {
+			names, err := expandRange(hostName)
+			if err != nil {
+				names = []string{hostName}
+			}
+			for _, n := range names {
+				inv.addHost(n, name, stringify(g.Hosts[hostName]))
+			}
+		}
+		for _, childName := range sortedGroupNames(g.Children) {
+			if name != "all" {
+				inv.parents[childName] = name
+			}
+			walk(childName, g.Children[childName])
+		}
+	}
+	for _, name := range sortedGroupNames(root) {
+		walk(name, root[name])
+	}
+	return inv.resolve(), nil
+}
+
+func stringify(values map[string]interface{}) map[string]string {
+	out := make(map[string]string, len(values))
+	for key, value := range values {
+		out[key] = fmt.Sprint(value)
+	}
+	return out
+}
+
+func sortedHostNames(m map[string]map[string]interface{}) []string {
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func sortedGroupNames(m map[string]*yamlGroupIn) []string {
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
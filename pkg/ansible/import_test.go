@@ -0,0 +1,138 @@
+package ansible
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseInventoryINI(t *testing.T) {
+	data := []byte(`# inventory
+bastion ansible_host=203.0.113.10
+
+[prod:vars]
+ansible_user=deploy
+
+[prod]
+web[1:2] ansible_port=2222
+
+[prod:children]
+prod_db
+
+[prod_db]
+db1 ansible_host=10.0.0.5 ansible_ssh_common_args='-o ProxyJump=bastion'
+
+[monitored]
+db1
+`)
+	hosts, err := ParseInventory(data)
+	if err != nil {
+		t.Fatalf("ParseInventory failed: %v", err)
+	}
+
+	var names []string
+	byName := make(map[string]Host)
+	for _, host := range hosts {
+		names = append(names, host.Name)
+		byName[host.Name] = host
+	}
+	if !reflect.DeepEqual(names, []string{"bastion", "web1", "web2", "db1"}) {
+		t.Fatalf("Unexpected hosts: %v", names)
+	}
+
+	if web := byName["web2"]; web.Vars["ansible_user"] != "deploy" || web.Vars["ansible_port"] != "2222" {
+		t.Errorf("Expected group and host vars on web2, got %v", web.Vars)
+	}
+	db := byName["db1"]
+	if !reflect.DeepEqual(db.Groups, []string{"prod/db", "monitored"}) {
+		t.Errorf("Expected db1 in prod/db and monitored, got %v", db.Groups)
+	}
+	if db.Vars["ansible_user"] != "deploy" || db.Vars["ansible_ssh_common_args"] != "-o ProxyJump=bastion" {
+		t.Errorf("Unexpected vars for db1: %v", db.Vars)
+	}
+	if len(byName["bastion"].Groups) != 0 {
+		t.Errorf("Expected bastion to be ungrouped, got %v", byName["bastion"].Groups)
+	}
+}
+
+func TestParseInventoryYAML(t *testing.T) {
+	data := []byte(`all:
+  hosts:
+    dev:
+      ansible_host: dev.example.com
+  children:
+    prod:
+      vars:
+        ansible_user: deploy
+      hosts:
+        web1:
+          ansible_port: 2222
+`)
+	hosts, err := ParseInventory(data)
+	if err != nil {
+		t.Fatalf("ParseInventory failed: %v", err)
+	}
+	if len(hosts) != 2 {
+		t.Fatalf("Expected 2 hosts, got %d", len(hosts))
+	}
+	web := hosts[1]
+	if web.Name != "web1" || web.Vars["ansible_port"] != "2222" || web.Vars["ansible_user"] != "deploy" {
+		t.Errorf("Unexpected host: %+v", web)
+	}
+	if !reflect.DeepEqual(web.Groups, []string{"prod"}) {
+		t.Errorf("Expected web1 in prod, got %v", web.Groups)
+	}
+}
+
+func TestParseInventoryRoundTrip(t *testing.T) {
+	data, err := Export(exportFixture, "ini")
+	if err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+	hosts, err := ParseInventory(data)
+	if err != nil {
+		t.Fatalf("ParseInventory failed: %v", err)
+	}
+	for _, host := range hosts {
+		if host.Name == "db1" && !reflect.DeepEqual(host.Groups, []string{"prod/db_eu"}) {
+			t.Errorf("Expected db1 back in prod/db_eu, got %v", host.Groups)
+		}
+	}
+}
+
+func TestExpandRange(t *testing.T) {
+	tests := map[string][]string{
+		"db1":                   {"db1"},
+		"db[01:03].example.com": {"db01.example.com", "db02.example.com", "db03.example.com"},
+		"web[a:c]":              {"weba", "webb", "webc"},
+		"rack[A:B]-[1:2]":       {"rackA-1", "rackA-2", "rackB-1", "rackB-2"},
+		"db[0:20:10]":           {"db0", "db10", "db20"},
+		"node[a:e:2]":           {"nodea", "nodec", "nodee"},
+	}
+	for pattern, want := range tests {
+		if got, err := expandRange(pattern); err != nil || !reflect.DeepEqual(got, want) {
+			t.Errorf("expandRange(%s) = %v, %v, want %v", pattern, got, err, want)
+		}
+	}
+	for _, pattern := range []string{"db[3:1]", "db[a:3]", "db[a:B]", "db[1:2:0]", "db[x]", "db[0:5000]"} {
+		if _, err := expandRange(pattern); err == nil {
+			t.Errorf("Expected an error for %s", pattern)
+		}
+	}
+	if _, err := expandRange("h[0:999]-[0:999]"); err == nil {
+		t.Error("Expected an error for a range with a million hosts")
+	}
+}
+
+func TestParseInventoryINIRangeFallback(t *testing.T) {
+	hosts, err := ParseInventory([]byte("[web]\nweb[a:b] ansible_port=2222\nodd[x] ansible_host=10.0.0.9\n"))
+	if err != nil {
+		t.Fatalf("ParseInventory failed: %v", err)
+	}
+	var names []string
+	for _, host := range hosts {
+		names = append(names, host.Name)
+	}
+	if !reflect.DeepEqual(names, []string{"weba", "webb", "odd[x]"}) {
+		t.Errorf("Unexpected hosts: %v", names)
+	}
+}
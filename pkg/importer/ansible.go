@@ -0,0 +1,70 @@
+package importer
+
+import (
+	"strings"
+
+	"github.com/pozgo/OhMySSH/pkg/ansible"
+)
+
+// firstVar returns the first of the given variables that is set; Ansible
+// still accepts the older ansible_ssh_* spellings.
+func firstVar(vars map[string]string, names ...string) string {
+	for _, name := range names {
+		if value := vars[name]; value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// jumpFromArgs finds a ProxyJump in extra ssh arguments, given either as
+// "-o ProxyJump=host", "-o ProxyJump host" or "-J host".
+func jumpFromArgs(args string) string {
+	fields := strings.Fields(args)
+	for i, field := range fields {
+		switch {
+		case field == "-J" && i+1 < len(fields):
+			return fields[i+1]
+		case strings.HasPrefix(field, "-J") && len(field) > 2:
+			return field[2:]
+		case field == "-o" && i+1 < len(fields):
+			option := fields[i+1]
+			key, value, ok := strings.Cut(option, "=")
+			if !ok && i+2 < len(fields) {
+				key, value = option, fields[i+2]
+			}
+			if strings.EqualFold(key, "ProxyJump") {
+				return value
+			}
+		}
+	}
+	return ""
+}
+
+// parseAnsible maps connection variables back to directives. The first group
+// of a host becomes its group and any others become tags.
+func parseAnsible(data []byte) ([]Entry, error) {
+	hosts, err := ansible.ParseInventory(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []Entry
+	for _, host := range hosts {
+		vars := host.Vars
+		options, err := connection(
+			firstVar(vars, "ansible_host", "ansible_ssh_host"),
+			firstVar(vars, "ansible_user", "ansible_ssh_user"),
+			firstVar(vars, "ansible_port", "ansible_ssh_port"),
+			firstVar(vars, "ansible_ssh_private_key_file", "ansible_private_key_file"),
+			jumpFromArgs(firstVar(vars, "ansible_ssh_common_args")+" "+firstVar(vars, "ansible_ssh_extra_args")),
+		)
+		entry := Entry{Alias: host.Name, Options: options, Source: host.Name, Problem: problem(err)}
+		if len(host.Groups) > 0 {
+			entry.Meta.Group = host.Groups[0]
+			entry.Meta.Tags = host.Groups[1:]
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
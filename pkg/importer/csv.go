@@ -0,0 +1,116 @@
+package importer
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// csvColumns maps normalized header names to entry fields. Termius exports
+// "Label", "Hostname/IP", "Port", "Username", "Groups" and "Tags"; the other
+// names cover hand made spreadsheets. Password columns are never read.
+var csvColumns = map[string]string{
+	"label":        "alias",
+	"alias":        "alias",
+	"name":         "alias",
+	"title":        "alias",
+	"session":      "alias",
+	"hostname/ip":  "hostname",
+	"hostname":     "hostname",
+	"host":         "hostname",
+	"address":      "hostname",
+	"ip":           "hostname",
+	"server":       "hostname",
+	"port":         "port",
+	"username":     "user",
+	"user":         "user",
+	"login":        "user",
+	"group":        "group",
+	"groups":       "group",
+	"folder":       "group",
+	"tags":         "tags",
+	"tag":          "tags",
+	"identityfile": "identity",
+	"identity":     "identity",
+	"key":          "identity",
+	"sshkey":       "identity",
+	"privatekey":   "identity",
+	"keyfile":      "identity",
+	"proxyjump":    "jump",
+	"jumphost":     "jump",
+	"notes":        "notes",
+	"note":         "notes",
+	"description":  "notes",
+	"protocol":     "protocol",
+	"owner":        "owner",
+}
+
+func normalizeHeader(name string) string {
+	name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+	return strings.NewReplacer(" ", "", "_", "", "-", "").Replace(name)
+}
+
+// parseCSV reads a CSV file with a header row. Rows without a hostname and
+// rows for other protocols than ssh are skipped; a row without a label uses
+// its hostname as the alias.
+func parseCSV(data []byte) ([]Entry, error) {
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse CSV: %v", err)
+	}
+	if len(records) == 0 {
+		return nil, nil
+	}
+
+	columns := make(map[string]int)
+	for i, name := range records[0] {
+		if field, ok := csvColumns[normalizeHeader(name)]; ok {
+			if _, seen := columns[field]; !seen {
+				columns[field] = i
+			}
+		}
+	}
+	if _, ok := columns["hostname"]; !ok {
+		return nil, errors.New("CSV header has no hostname column")
+	}
+
+	var entries []Entry
+	for n, record := range records[1:] {
+		get := func(field string) string {
+			if i, ok := columns[field]; ok && i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+		hostname := get("hostname")
+		if hostname == "" {
+			continue
+		}
+		if protocol := strings.ToLower(get("protocol")); protocol != "" && protocol != "ssh" {
+			continue
+		}
+
+		alias := SanitizeAlias(get("alias"))
+		if alias == "" {
+			alias = hostname
+		}
+		options, err := connection(hostname, get("user"), get("port"), get("identity"), get("jump"))
+		entry := Entry{
+			Alias:   alias,
+			Options: options,
+			Source:  fmt.Sprintf("row %d", n+2),
+			Problem: problem(err),
+		}
+		entry.Meta.Group = strings.Trim(get("group"), "/")
+		entry.Meta.Tags = splitList(get("tags"))
+		entry.Meta.Owner = get("owner")
+		entry.Meta.Notes = get("notes")
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
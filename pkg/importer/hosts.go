@@ -0,0 +1,71 @@
+package importer
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"net"
+	"strings"
+)
+
+// systemNames are entries every hosts file carries that are not servers.
+var systemNames = map[string]bool{
+	"localhost":     true,
+	"broadcasthost": true,
+}
+
+// pickAlias prefers the first short name of a hosts line, so that
+// "10.0.0.5 db1.example.com db1" becomes "db1".
+func pickAlias(names []string) string {
+	for _, name := range names {
+		if !strings.Contains(name, ".") {
+			return name
+		}
+	}
+	return names[0]
+}
+
+// parseHosts reads an /etc/hosts style file: an address followed by its
+// names. Loopback, link-local, multicast and unspecified addresses are
+// skipped, as are localhost and the ip6- names. A trailing comment becomes
+// the note of the host.
+func parseHosts(data []byte) ([]Entry, error) {
+	var entries []Entry
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line, comment, _ := strings.Cut(scanner.Text(), "#")
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		ip := net.ParseIP(fields[0])
+		if ip == nil || ip.IsLoopback() || ip.IsUnspecified() || ip.IsMulticast() ||
+			ip.IsLinkLocalUnicast() || ip.Equal(net.IPv4bcast) {
+			continue
+		}
+
+		var names []string
+		for _, name := range fields[1:] {
+			if !systemNames[strings.ToLower(name)] && !strings.HasPrefix(name, "ip6-") {
+				names = append(names, name)
+			}
+		}
+		if len(names) == 0 {
+			continue
+		}
+		options, _ := connection(fields[0], "", "", "", "") // no port to get wrong
+		entry := Entry{
+			Alias:   pickAlias(names),
+			Options: options,
+			Source:  fmt.Sprintf("line %d", lineNo),
+		}
+		entry.Meta.Notes = strings.TrimSpace(comment)
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
@@ -0,0 +1,214 @@
+// Package importer turns hosts exported from other SSH clients and tools into
+// new ssh config Host blocks.
+package importer
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/pozgo/OhMySSH/pkg/parser"
+)
+
+// Supported import formats.
+const (
+	FormatAnsible = "ansible"
+	FormatCSV     = "csv"
+	FormatPuTTY   = "putty"
+	FormatHosts   = "hosts"
+)
+
+// Formats lists the import formats in the order they are documented.
+var Formats = []string{FormatAnsible, FormatCSV, FormatPuTTY, FormatHosts}
+
+// Entry is one host to be added: its alias, the directives of the new Host
+// block and the annotations written above it. Source tells where the entry
+// came from in the imported file, for messages. Problem, when set, is why
+// the entry cannot be added as it is.
+type Entry struct {
+	Alias   string
+	Options []parser.Directive
+	Meta    parser.Meta
+	Source  string
+	Problem string
+}
+
+// ValidFormat reports whether Parse understands format.
+func ValidFormat(format string) bool {
+	switch strings.ToLower(format) {
+	case FormatAnsible, FormatCSV, "termius", FormatPuTTY, FormatHosts:
+		return true
+	}
+	return false
+}
+
+// Parse reads data in the given format. "termius" is accepted as another
+// name for "csv", since that is what Termius exports.
+func Parse(format string, data []byte) ([]Entry, error) {
+	switch strings.ToLower(format) {
+	case FormatAnsible:
+		return parseAnsible(data)
+	case FormatCSV, "termius":
+		return parseCSV(data)
+	case FormatPuTTY:
+		return parsePuTTY(data)
+	case FormatHosts:
+		return parseHosts(data)
+	default:
+		return nil, fmt.Errorf("unsupported import format %q (use %s)", format, strings.Join(Formats, ", "))
+	}
+}
+
+// SanitizeAlias turns a session name into a usable Host alias by replacing
+// whitespace, '#' and the pattern characters '*', '?', '!' and ',' with
+// dashes. Leading dashes are dropped so that ssh never takes the alias for an
+// option. The result is empty if nothing usable is left.
+func SanitizeAlias(name string) string {
+	fields := strings.FieldsFunc(strings.TrimSpace(name), func(r rune) bool {
+		return strings.ContainsRune(" \t\r\n#*?!,", r)
+	})
+	return strings.TrimLeft(strings.Join(fields, "-"), "-")
+}
+
+// connection builds the directives of a Host block, leaving out empty values
+// and the default port. The directives are returned along with an error for
+// a port ssh would not accept.
+func connection(hostname, user, port, identity, jump string) ([]parser.Directive, error) {
+	var options []parser.Directive
+	add := func(key, value string) {
+		if value = strings.TrimSpace(value); value != "" {
+			options = append(options, parser.Directive{Key: key, Value: value})
+		}
+	}
+	add("HostName", hostname)
+	add("User", user)
+	if port != "22" {
+		add("Port", port)
+	}
+	add("IdentityFile", identity)
+	add("ProxyJump", jump)
+	if port = strings.TrimSpace(port); port != "" {
+		if n, err := strconv.Atoi(port); err != nil || n < 1 || n > 65535 {
+			return options, fmt.Errorf("invalid port %q", port)
+		}
+	}
+	return options, nil
+}
+
+// problem returns err as the Problem of an entry.
+func problem(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
+
+// validate returns why entry cannot be written to the config, if it cannot.
+func (e Entry) validate() error {
+	if e.Problem != "" {
+		return errors.New(e.Problem)
+	}
+	if err := parser.ValidateAlias(e.Alias); err != nil {
+		return err
+	}
+	for _, option := range e.Options {
+		if err := parser.ValidateDirective(option.Key, option.Value); err != nil {
+			return err
+		}
+	}
+	return parser.ValidateMeta(e.Meta)
+}
+
+// Conflict is an entry that will not be added, and why.
+type Conflict struct {
+	Entry  Entry
+	Reason string
+}
+
+// Plan splits imported entries into the ones to add and the conflicting
+// ones: aliases already used by a Host block, aliases that appear more than
+// once in the import, and aliases or values ssh would not accept.
+type Plan struct {
+	Add       []Entry
+	Conflicts []Conflict
+}
+
+// NewPlan checks entries against the hosts of the current config. The first
+// of several entries with the same alias is kept.
+func NewPlan(entries []Entry, existing []parser.Host) Plan {
+	defined := make(map[string]parser.Host)
+	for _, host := range existing {
+		for _, pattern := range host.Patterns() {
+			if _, ok := defined[strings.ToLower(pattern)]; !ok {
+				defined[strings.ToLower(pattern)] = host
+			}
+		}
+	}
+
+	var plan Plan
+	seen := make(map[string]bool)
+	for _, entry := range entries {
+		key := strings.ToLower(entry.Alias)
+		if err := entry.validate(); err != nil {
+			plan.Conflicts = append(plan.Conflicts, Conflict{Entry: entry, Reason: err.Error()})
+		} else if host, ok := defined[key]; ok {
+			reason := "already defined"
+			if host.Source != "" {
+				reason = fmt.Sprintf("already defined in %s:%d", host.Source, host.Line)
+			}
+			plan.Conflicts = append(plan.Conflicts, Conflict{Entry: entry, Reason: reason})
+		} else if seen[key] {
+			plan.Conflicts = append(plan.Conflicts, Conflict{Entry: entry, Reason: "duplicate alias in import"})
+		} else {
+			seen[key] = true
+			plan.Add = append(plan.Add, entry)
+		}
+	}
+	return plan
+}
+
+// SetGroup places every entry below group: entries that already have a group
+// keep it as a subgroup.
+func SetGroup(entries []Entry, group string) {
+	group = strings.Trim(group, "/ ")
+	if group == "" {
+		return
+	}
+	for i := range entries {
+		if entries[i].Meta.Group == "" {
+			entries[i].Meta.Group = group
+		} else {
+			entries[i].Meta.Group = group + "/" + entries[i].Meta.Group
+		}
+	}
+}
+
+// Apply adds the planned Host blocks to content.
+func (p Plan) Apply(content string) (string, error) {
+	for _, entry := range p.Add {
+		var err error
+		content, err = parser.AddHost(content, entry.Alias, entry.Options)
+		if err != nil {
+			return "", err
+		}
+		if !entry.Meta.IsZero() {
+			if content, err = parser.SetHostMeta(content, entry.Alias, entry.Meta); err != nil {
+				return "", err
+			}
+		}
+	}
+	return content, nil
+}
+
+// Preview renders the Host blocks that Apply would add.
+func (p Plan) Preview() (string, error) {
+	return p.Apply("")
+}
+
+// splitList splits tag lists separated by commas, semicolons or whitespace.
+func splitList(value string) []string {
+	return strings.FieldsFunc(value, func(r rune) bool {
+		return r == ',' || r == ';' || r == ' ' || r == '\t'
+	})
+}
@@ -0,0 +1,344 @@
+package importer
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"unicode/utf16"
+
+	"github.com/pozgo/OhMySSH/pkg/parser"
+)
+
+func options(entry Entry) map[string]string {
+	values := make(map[string]string)
+	for _, option := range entry.Options {
+		values[option.Key] = option.Value
+	}
+	return values
+}
+
+func TestParseAnsible(t *testing.T) {
+	data := []byte(`[prod]
+db1 ansible_host=10.0.0.5 ansible_user=postgres ansible_port=2222 ansible_ssh_common_args='-J ops@gw'
+web1 ansible_host=web1.example.com ansible_port=22
+
+[monitored]
+db1
+`)
+	entries, err := Parse("ansible", data)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("Expected 2 entries, got %d", len(entries))
+	}
+
+	db := entries[0]
+	expected := map[string]string{"HostName": "10.0.0.5", "User": "postgres", "Port": "2222", "ProxyJump": "ops@gw"}
+	if db.Alias != "db1" || !reflect.DeepEqual(options(db), expected) {
+		t.Errorf("Unexpected entry: %s %v", db.Alias, options(db))
+	}
+	if db.Meta.Group != "prod" || !reflect.DeepEqual(db.Meta.Tags, []string{"monitored"}) {
+		t.Errorf("Unexpected meta: %+v", db.Meta)
+	}
+	if _, ok := options(entries[1])["Port"]; ok {
+		t.Error("Expected the default port to be left out")
+	}
+}
+
+func TestJumpFromArgs(t *testing.T) {
+	tests := map[string]string{
+		"-o ProxyJump=gw":                  "gw",
+		"-o StrictHostKeyChecking=no -J a": "a",
+		"-Jbastion":                        "bastion",
+		"-o proxyjump gw:2200":             "gw:2200",
+		"-o ServerAliveInterval=30":        "",
+	}
+	for args, want := range tests {
+		if got := jumpFromArgs(args); got != want {
+			t.Errorf("jumpFromArgs(%q) = %q, want %q", args, got, want)
+		}
+	}
+}
+
+func TestParseCSV(t *testing.T) {
+	data := []byte("\ufeffGroups,Label,Tags,Hostname/IP,Protocol,Port,Username,Password\n" +
+		"Prod/DB,Primary DB,\"db,critical\",10.0.0.5,ssh,2222,postgres,secret\n" +
+		",,,router.lan,telnet,23,admin,\n" +
+		",,,build.example.com,,,,\n")
+	entries, err := Parse("termius", data)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("Expected the telnet row to be skipped, got %d entries", len(entries))
+	}
+
+	db := entries[0]
+	expected := map[string]string{"HostName": "10.0.0.5", "User": "postgres", "Port": "2222"}
+	if db.Alias != "Primary-DB" || !reflect.DeepEqual(options(db), expected) {
+		t.Errorf("Unexpected entry: %s %v", db.Alias, options(db))
+	}
+	if db.Meta.Group != "Prod/DB" || !reflect.DeepEqual(db.Meta.Tags, []string{"db", "critical"}) {
+		t.Errorf("Unexpected meta: %+v", db.Meta)
+	}
+	if entries[1].Alias != "build.example.com" {
+		t.Errorf("Expected the hostname as alias, got %s", entries[1].Alias)
+	}
+
+	if _, err := Parse("csv", []byte("Label,Port\nweb,22\n")); err == nil {
+		t.Error("Expected an error without a hostname column")
+	}
+}
+
+func TestParsePuTTY(t *testing.T) {
+	reg := `Windows Registry Editor Version 5.00
+
+[HKEY_CURRENT_USER\Software\SimonTatham\PuTTY\Sessions]
+
+[HKEY_CURRENT_USER\Software\SimonTatham\PuTTY\Sessions\Default%20Settings]
+"HostName"=""
+
+[HKEY_CURRENT_USER\Software\SimonTatham\PuTTY\Sessions\web%20server]
+"HostName"="deploy@web.example.com"
+"PortNumber"=dword:00000016
+"Protocol"="ssh"
+"PublicKeyFile"="C:\\Users\\me\\web.ppk"
+
+[HKEY_CURRENT_USER\Software\SimonTatham\PuTTY\Sessions\switch]
+"HostName"="10.0.0.2"
+"PortNumber"=dword:00000017
+"Protocol"="telnet"
+
+[HKEY_CURRENT_USER\Software\SimonTatham\PuTTY\Sessions\db]
+"HostName"="10.0.0.5"
+"UserName"="postgres"
+"PortNumber"=dword:000008ae
+`
+	// regedit writes UTF-16 with a byte order mark
+	units := utf16.Encode([]rune(strings.ReplaceAll(reg, "\n", "\r\n")))
+	data := []byte{0xff, 0xfe}
+	for _, unit := range units {
+		data = append(data, byte(unit), byte(unit>>8))
+	}
+
+	entries, err := Parse("putty", data)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("Expected 2 entries, got %d: %+v", len(entries), entries)
+	}
+
+	web := entries[0]
+	if web.Alias != "web-server" || !reflect.DeepEqual(options(web), map[string]string{"HostName": "web.example.com", "User": "deploy"}) {
+		t.Errorf("Unexpected entry: %s %v", web.Alias, options(web))
+	}
+	if !strings.Contains(web.Meta.Notes, `C:\Users\me\web.ppk`) {
+		t.Errorf("Expected the key path in the notes, got %q", web.Meta.Notes)
+	}
+	if db := entries[1]; options(db)["Port"] != "2222" || options(db)["User"] != "postgres" {
+		t.Errorf("Unexpected entry: %s %v", db.Alias, options(db))
+	}
+}
+
+func TestParseHosts(t *testing.T) {
+	data := []byte(`127.0.0.1 localhost
+::1 localhost ip6-localhost ip6-loopback
+ff02::1 ip6-allnodes
+10.0.0.5 db1.example.com db1 # primary database
+192.168.1.20 nas.lan
+`)
+	entries, err := Parse("hosts", data)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("Expected 2 entries, got %d", len(entries))
+	}
+	if entries[0].Alias != "db1" || options(entries[0])["HostName"] != "10.0.0.5" || entries[0].Meta.Notes != "primary database" {
+		t.Errorf("Unexpected entry: %+v", entries[0])
+	}
+	if entries[1].Alias != "nas.lan" {
+		t.Errorf("Expected nas.lan, got %s", entries[1].Alias)
+	}
+
+	if _, err := Parse("rdp", data); err == nil {
+		t.Error("Expected an error for an unknown format")
+	}
+}
+
+func TestNewPlan(t *testing.T) {
+	existing := []parser.Host{{Name: "db1 db", Source: "/home/me/.ssh/config", Line: 4}}
+	entries := []Entry{
+		{Alias: "DB"},
+		{Alias: "web1"},
+		{Alias: "web1"},
+		{Alias: "bad alias"},
+		{Alias: "web2"},
+	}
+
+	plan := NewPlan(entries, existing)
+	var added []string
+	for _, entry := range plan.Add {
+		added = append(added, entry.Alias)
+	}
+	if !reflect.DeepEqual(added, []string{"web1", "web2"}) {
+		t.Errorf("Unexpected hosts to add: %v", added)
+	}
+
+	reasons := make(map[string]string)
+	for _, conflict := range plan.Conflicts {
+		reasons[conflict.Entry.Alias] = conflict.Reason
+	}
+	if reasons["DB"] != "already defined in /home/me/.ssh/config:4" {
+		t.Errorf("Unexpected reason for DB: %q", reasons["DB"])
+	}
+	if reasons["web1"] != "duplicate alias in import" {
+		t.Errorf("Unexpected reason for web1: %q", reasons["web1"])
+	}
+	if reasons["bad alias"] == "" {
+		t.Error("Expected the invalid alias to conflict")
+	}
+}
+
+func TestPlanApply(t *testing.T) {
+	entries := []Entry{
+		{Alias: "db1", Options: []parser.Directive{{Key: "HostName", Value: "10.0.0.5"}, {Key: "User", Value: "postgres"}}, Meta: parser.Meta{Group: "db"}},
+		{Alias: "web1", Options: []parser.Directive{{Key: "HostName", Value: "web1.example.com"}, {Key: "Port", Value: "2222"}}},
+	}
+	SetGroup(entries, "imported")
+	plan := NewPlan(entries, nil)
+
+	content := "Host old\n    HostName old.example.com\n\nHost *\n    User root\n"
+	updated, err := plan.Apply(content)
+	if err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	expected := `Host old
+    HostName old.example.com
+
+# ohmyssh: group=imported/db
+Host db1
+    HostName 10.0.0.5
+    User postgres
+
+# ohmyssh: group=imported
+Host web1
+    HostName web1.example.com
+    Port 2222
+
+Host *
+    User root
+`
+	if updated != expected {
+		t.Errorf("Unexpected config:\n%s", updated)
+	}
+
+	preview, err := plan.Preview()
+	if err != nil {
+		t.Fatalf("Preview failed: %v", err)
+	}
+	if !strings.HasPrefix(preview, "# ohmyssh: group=imported/db\nHost db1\n") {
+		t.Errorf("Unexpected preview:\n%s", preview)
+	}
+}
+
+// planFor parses data and plans it against an empty config.
+func planFor(t *testing.T, format, data string) Plan {
+	t.Helper()
+	entries, err := Parse(format, []byte(data))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	return NewPlan(entries, nil)
+}
+
+func TestImportRejectsInjectedDirectives(t *testing.T) {
+	csv := "Label,Hostname,Port,Notes\n" +
+		"web,\"web.example.com\nProxyCommand sh -c 'touch /tmp/pwned'\",22,\n" +
+		"db,db.example.com,22,\"ok\rProxyCommand evil\"\n" +
+		"good,good.example.com,22,\n"
+	yaml := `all:
+  hosts:
+    web:
+      ansible_host: "web.example.com\nProxyCommand sh -c 'touch /tmp/pwned'"
+    good:
+      ansible_host: good.example.com
+`
+	for format, data := range map[string]string{"csv": csv, "ansible": yaml} {
+		plan := planFor(t, format, data)
+		if len(plan.Add) != 1 || plan.Add[0].Alias != "good" {
+			t.Errorf("%s: expected only the good host to be added, got %+v", format, plan.Add)
+		}
+		for _, conflict := range plan.Conflicts {
+			if !strings.Contains(conflict.Reason, "control characters") {
+				t.Errorf("%s: unexpected reason for %s: %s", format, conflict.Entry.Alias, conflict.Reason)
+			}
+		}
+		content, err := plan.Apply("")
+		if err != nil || strings.Contains(content, "ProxyCommand") {
+			t.Errorf("%s: expected no ProxyCommand to be written, got %v:\n%s", format, err, content)
+		}
+	}
+
+	if _, err := parser.AddHost("", "web", []parser.Directive{{Key: "HostName", Value: "web\nProxyCommand evil"}}); err == nil {
+		t.Error("Expected AddHost to refuse a value with a newline")
+	}
+}
+
+func TestImportRejectsInvalidPorts(t *testing.T) {
+	plan := planFor(t, "csv", "Label,Hostname,Port\nweb,web.example.com,abc\ndb,db.example.com,70000\ngood,good.example.com,2222\n")
+	if len(plan.Add) != 1 || len(plan.Conflicts) != 2 {
+		t.Fatalf("Expected 1 entry to add and 2 conflicts, got %+v", plan)
+	}
+	if conflict := plan.Conflicts[0]; conflict.Entry.Source != "row 2" || conflict.Reason != `invalid port "abc"` {
+		t.Errorf("Unexpected conflict %s: %s", conflict.Entry.Source, conflict.Reason)
+	}
+
+	plan = planFor(t, "putty", `[HKEY_CURRENT_USER\Software\SimonTatham\PuTTY\Sessions\web]
+"HostName"="web.example.com"
+"PortNumber"=dword:00000000
+`)
+	if len(plan.Add) != 0 || len(plan.Conflicts) != 1 || plan.Conflicts[0].Reason != `invalid port "0"` {
+		t.Errorf("Expected port 0 to conflict, got %+v", plan)
+	}
+}
+
+func TestImportSanitizesPatternAliases(t *testing.T) {
+	csv := "Label,Hostname\n" +
+		"*,star.example.com\n" +
+		"web?,web.example.com\n" +
+		"!x,x.example.com\n" +
+		"-oProxyCommand=evil,evil.example.com\n" +
+		"\"a,b\",ab.example.com\n"
+	plan := planFor(t, "csv", csv)
+	var aliases []string
+	for _, entry := range plan.Add {
+		aliases = append(aliases, entry.Alias)
+	}
+	expected := []string{"star.example.com", "web", "x", "oProxyCommand=evil", "a-b"}
+	if !reflect.DeepEqual(aliases, expected) || len(plan.Conflicts) != 0 {
+		t.Fatalf("Expected aliases %v, got %v and conflicts %+v", expected, aliases, plan.Conflicts)
+	}
+	content, err := plan.Apply("")
+	if err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	if strings.Contains(content, "Host *") || strings.Contains(content, "Host -") {
+		t.Errorf("Expected no pattern or option aliases to be written:\n%s", content)
+	}
+
+	// A hostname used as the alias is still checked
+	plan = planFor(t, "csv", "Label,Hostname\n,-oProxyCommand=evil\n")
+	if len(plan.Add) != 0 || len(plan.Conflicts) != 1 {
+		t.Errorf("Expected the entry to conflict, got %+v", plan)
+	}
+
+	plan = planFor(t, "putty", `[HKEY_CURRENT_USER\Software\SimonTatham\PuTTY\Sessions\*]
+"HostName"="star.example.com"
+`)
+	if len(plan.Add) != 1 || plan.Add[0].Alias != "star.example.com" {
+		t.Errorf("Expected the session to fall back to its hostname, got %+v", plan)
+	}
+}
@@ -0,0 +1,119 @@
+package importer
+
+import (
+	"bufio"
+	"bytes"
+	"net/url"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+// puttySessionsKey is the registry key PuTTY keeps its saved sessions under.
+const puttySessionsKey = `\Software\SimonTatham\PuTTY\Sessions\`
+
+// decodeRegistry returns the text of a .reg file. regedit writes them as
+// UTF-16 with a byte order mark, older tools as plain text.
+func decodeRegistry(data []byte) string {
+	if len(data) >= 2 && data[0] == 0xff && data[1] == 0xfe {
+		data = data[2:]
+		units := make([]uint16, len(data)/2)
+		for i := range units {
+			units[i] = uint16(data[2*i]) | uint16(data[2*i+1])<<8
+		}
+		return string(utf16.Decode(units))
+	}
+	return strings.TrimPrefix(string(data), "\ufeff")
+}
+
+// parseRegValue decodes a registry value: a quoted string with backslash
+// escapes or a dword in hex.
+func parseRegValue(value string) string {
+	if strings.HasPrefix(value, "dword:") {
+		n, err := strconv.ParseUint(strings.TrimPrefix(value, "dword:"), 16, 32)
+		if err != nil {
+			return ""
+		}
+		return strconv.FormatUint(n, 10)
+	}
+	if len(value) >= 2 && value[0] == '"' && value[len(value)-1] == '"' {
+		value = value[1 : len(value)-1]
+		return strings.NewReplacer(`\\`, `\`, `\"`, `"`).Replace(value)
+	}
+	return value
+}
+
+// parsePuTTY reads the saved sessions from a registry export of PuTTY's
+// settings. Only ssh sessions are imported and "Default Settings" is skipped.
+// PuTTY keys (.ppk) are not usable by OpenSSH, so the key path is kept as a
+// note to convert it with puttygen.
+func parsePuTTY(data []byte) ([]Entry, error) {
+	var entries []Entry
+	var session string
+	var values map[string]string
+
+	flush := func() {
+		if session == "" || session == "Default Settings" {
+			return
+		}
+		if protocol := values["Protocol"]; protocol != "" && protocol != "ssh" {
+			return
+		}
+		hostname, user := values["HostName"], values["UserName"]
+		if at := strings.LastIndex(hostname, "@"); at >= 0 {
+			if user == "" {
+				user = hostname[:at]
+			}
+			hostname = hostname[at+1:]
+		}
+		if hostname == "" {
+			return
+		}
+		alias := SanitizeAlias(session)
+		if alias == "" {
+			alias = hostname
+		}
+		options, err := connection(hostname, user, values["PortNumber"], "", "")
+		entry := Entry{
+			Alias:   alias,
+			Options: options,
+			Source:  session,
+			Problem: problem(err),
+		}
+		if key := values["PublicKeyFile"]; key != "" {
+			entry.Meta.Notes = "PuTTY key: " + key + " (convert with puttygen -O private-openssh)"
+		}
+		entries = append(entries, entry)
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader([]byte(decodeRegistry(data))))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			flush()
+			session, values = "", make(map[string]string)
+			key := strings.Trim(line, "[]")
+			if idx := strings.Index(key, puttySessionsKey); idx >= 0 {
+				name := key[idx+len(puttySessionsKey):]
+				if decoded, err := url.PathUnescape(name); err == nil {
+					name = decoded
+				}
+				if !strings.Contains(name, `\`) {
+					session = name
+				}
+			}
+			continue
+		}
+		if session == "" || !strings.HasPrefix(line, `"`) {
+			continue
+		}
+		if name, value, ok := strings.Cut(line, "="); ok {
+			values[strings.Trim(name, `"`)] = parseRegValue(value)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	flush()
+	return entries, nil
+}
@@ -0,0 +1,97 @@
+package knownhosts
+
+import "strings"
+
+// Candidate is a host found in known_hosts that could become a Host entry.
+// Names holds the other names stored on the same lines, usually its address.
+type Candidate struct {
+	Host     string
+	Port     string
+	Names    []string
+	KeyTypes []string
+	Line     int
+}
+
+// Discovery is the result of Discover. Hashed counts the lines whose host
+// names are hashed and so cannot be listed; Markers counts @cert-authority
+// and @revoked lines, which describe keys rather than hosts.
+type Discovery struct {
+	Candidates []Candidate
+	Hashed     int
+	Markers    int
+}
+
+// Discover lists the hosts of entries that covered does not report as
+// already known, one candidate per host and port. Patterns with wildcards
+// or negations are skipped since they do not name a single host.
+func Discover(entries []Entry, covered func(host string) bool) Discovery {
+	var result Discovery
+	index := make(map[string]int)
+	seen := make(map[string]bool)
+	for _, entry := range entries {
+		switch {
+		case entry.Marker != "":
+			result.Markers++
+			continue
+		case entry.IsHashed():
+			result.Hashed++
+			continue
+		}
+
+		var names []string
+		for _, pattern := range entry.Hosts {
+			if !strings.ContainsAny(pattern, "*?!") {
+				names = append(names, pattern)
+			}
+		}
+		if len(names) == 0 {
+			continue
+		}
+		host, port := SplitHostPort(names[0])
+		key := strings.ToLower(names[0])
+
+		if i, ok := index[key]; ok {
+			if i >= 0 {
+				result.Candidates[i].KeyTypes = appendUnique(result.Candidates[i].KeyTypes, entry.KeyType)
+			}
+			continue
+		}
+		// An address stored next to a name already listed is not a host of
+		// its own
+		if seen[key] {
+			continue
+		}
+
+		isCovered := false
+		for _, name := range names {
+			seen[strings.ToLower(name)] = true
+			if h, _ := SplitHostPort(name); covered(h) {
+				isCovered = true
+			}
+		}
+		if isCovered {
+			// Later lines for the same host are covered too
+			index[key] = -1
+			continue
+		}
+
+		index[key] = len(result.Candidates)
+		result.Candidates = append(result.Candidates, Candidate{
+			Host:     host,
+			Port:     port,
+			Names:    names[1:],
+			KeyTypes: []string{entry.KeyType},
+			Line:     entry.Line,
+		})
+	}
+	return result
+}
+
+func appendUnique(values []string, value string) []string {
+	for _, v := range values {
+		if v == value {
+			return values
+		}
+	}
+	return append(values, value)
+}
@@ -0,0 +1,44 @@
+package knownhosts
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDiscover(t *testing.T) {
+	entries := Parse([]byte(knownHostsFixture + `140.82.121.3 ssh-rsa AAAAB3NzaC1yc2E=
+db.internal ssh-ed25519 ` + ed25519Key + `
+`))
+	covered := func(host string) bool { return host == "db.internal" }
+
+	result := Discover(entries, covered)
+	if result.Hashed != 1 || result.Markers != 2 {
+		t.Errorf("Expected 1 hashed and 2 marker lines, got %d and %d", result.Hashed, result.Markers)
+	}
+	if len(result.Candidates) != 2 {
+		t.Fatalf("Expected 2 candidates, got %+v", result.Candidates)
+	}
+
+	github := result.Candidates[0]
+	if github.Host != "github.com" || github.Port != "22" || github.Line != 2 {
+		t.Errorf("Unexpected candidate: %+v", github)
+	}
+	if !reflect.DeepEqual(github.Names, []string{"140.82.121.3"}) {
+		t.Errorf("Expected the address as another name, got %v", github.Names)
+	}
+	if !reflect.DeepEqual(github.KeyTypes, []string{"ssh-ed25519", "ecdsa-sha2-nistp256"}) {
+		t.Errorf("Expected both key types, got %v", github.KeyTypes)
+	}
+
+	if git := result.Candidates[1]; git.Host != "git.example.com" || git.Port != "2222" {
+		t.Errorf("Unexpected candidate: %+v", git)
+	}
+}
+
+func TestDiscoverCoveredByAnyName(t *testing.T) {
+	entries := Parse([]byte("web1,10.0.0.1 ssh-ed25519 " + ed25519Key + "\n"))
+	result := Discover(entries, func(host string) bool { return host == "10.0.0.1" })
+	if len(result.Candidates) != 0 {
+		t.Errorf("Expected no candidates, got %+v", result.Candidates)
+	}
+}
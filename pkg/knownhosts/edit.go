@@ -0,0 +1,182 @@
+package knownhosts
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ErrChanged means the known_hosts file no longer holds an entry where it
+// was read, because ssh or another program wrote to it in the meantime.
+var ErrChanged = errors.New("known_hosts changed since it was read, reload and try again")
+
+// Lookup returns the entries ssh would consider for host on port, markers
+// included.
+func Lookup(entries []Entry, host, port string) []Entry {
+	var matches []Entry
+	for _, entry := range entries {
+		if entry.Matches(host, port) {
+			matches = append(matches, entry)
+		}
+	}
+	return matches
+}
+
+// RemoveLines returns data without the given 1-based line numbers.
+func RemoveLines(data []byte, lines []int) []byte {
+	remove := make(map[int]bool, len(lines))
+	for _, line := range lines {
+		remove[line] = true
+	}
+	var out bytes.Buffer
+	for i, line := range bytes.SplitAfter(data, []byte("\n")) {
+		if !remove[i+1] {
+			out.Write(line)
+		}
+	}
+	return out.Bytes()
+}
+
+// sameEntry reports whether a and b are the same key line.
+func sameEntry(a, b Entry) bool {
+	return a.Line == b.Line && a.Marker == b.Marker && a.KeyType == b.KeyType && a.Key == b.Key &&
+		strings.Join(a.Hosts, ",") == strings.Join(b.Hosts, ",")
+}
+
+// Remove deletes entries, as read earlier, from the known_hosts file at
+// path. Every entry must still be on its line, or nothing is removed and
+// ErrChanged is returned. Like ssh-keygen -R, the previous content is kept
+// in path.old. The new content is written to a temporary file and renamed
+// over the old one, so ssh never reads a partly written file.
+func Remove(path string, entries []Entry) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read known_hosts: %v", err)
+	}
+	current := make(map[int]Entry)
+	for _, entry := range Parse(data) {
+		current[entry.Line] = entry
+	}
+	lines := make([]int, 0, len(entries))
+	for _, entry := range entries {
+		if found, ok := current[entry.Line]; !ok || !sameEntry(found, entry) {
+			return fmt.Errorf("%w: line %d", ErrChanged, entry.Line)
+		}
+		lines = append(lines, entry.Line)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("failed to read known_hosts: %v", err)
+	}
+	if err := ioutil.WriteFile(path+".old", data, info.Mode().Perm()); err != nil {
+		return fmt.Errorf("failed to create backup: %v", err)
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(path), ".known_hosts-*")
+	if err != nil {
+		return fmt.Errorf("failed to save known_hosts: %v", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(RemoveLines(data, lines)); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to save known_hosts: %v", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to save known_hosts: %v", err)
+	}
+	if err := os.Chmod(tmp.Name(), info.Mode().Perm()); err != nil {
+		return fmt.Errorf("failed to save known_hosts: %v", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to save known_hosts: %v", err)
+	}
+	return nil
+}
+
+// KeyStatus is the outcome of comparing a stored key with the one a server
+// offers.
+type KeyStatus int
+
+const (
+	KeyMatch      KeyStatus = iota // stored and offered keys are the same
+	KeyChanged                     // the server offers a different key
+	KeyRevoked                     // the offered key is marked @revoked
+	KeyNew                         // offered but not stored
+	KeyNotOffered                  // stored but not offered
+)
+
+func (s KeyStatus) String() string {
+	switch s {
+	case KeyMatch:
+		return "match"
+	case KeyChanged:
+		return "CHANGED"
+	case KeyRevoked:
+		return "REVOKED"
+	case KeyNew:
+		return "not stored"
+	default:
+		return "not offered"
+	}
+}
+
+// KeyCheck compares one key type. Stored and Offered are fingerprints and
+// empty when there is no such key.
+type KeyCheck struct {
+	KeyType string
+	Stored  string
+	Offered string
+	Status  KeyStatus
+}
+
+// Compare matches the keys a server offers against the stored entries of
+// that host, by key type. Certificate authority lines are not host keys and
+// are left out; revoked keys only matter if the server offers one of them.
+func Compare(stored, offered []Entry) []KeyCheck {
+	revoked := make(map[string]bool)
+	storedByType := make(map[string][]string)
+	var order []string
+	for _, entry := range stored {
+		switch entry.Marker {
+		case MarkerRevoked:
+			revoked[entry.Key] = true
+		case "":
+			if _, ok := storedByType[entry.KeyType]; !ok {
+				order = append(order, entry.KeyType)
+			}
+			storedByType[entry.KeyType] = append(storedByType[entry.KeyType], entry.Key)
+		}
+	}
+
+	var checks []KeyCheck
+	offeredTypes := make(map[string]bool)
+	for _, entry := range offered {
+		offeredTypes[entry.KeyType] = true
+		check := KeyCheck{KeyType: entry.KeyType, Offered: entry.Fingerprint(), Status: KeyNew}
+		keys := storedByType[entry.KeyType]
+		if len(keys) > 0 {
+			check.Stored = Fingerprint(keys[0])
+			check.Status = KeyChanged
+		}
+		for _, key := range keys {
+			if key == entry.Key {
+				check.Stored = check.Offered
+				check.Status = KeyMatch
+			}
+		}
+		if revoked[entry.Key] {
+			check.Status = KeyRevoked
+		}
+		checks = append(checks, check)
+	}
+	for _, keyType := range order {
+		if !offeredTypes[keyType] {
+			checks = append(checks, KeyCheck{KeyType: keyType, Stored: Fingerprint(storedByType[keyType][0]), Status: KeyNotOffered})
+		}
+	}
+	return checks
+}
@@ -0,0 +1,121 @@
+package knownhosts
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const otherKey = "AAAAC3NzaC1lZDI1NTE5AAAAIBSx8Jrfdiwv5YmUZG7jiXC4nyKU8oBvXtnTkMbPLmC0"
+
+func TestLookup(t *testing.T) {
+	entries := Parse([]byte(knownHostsFixture))
+	matches := Lookup(entries, "git.example.com", "2222")
+	if len(matches) != 1 || matches[0].Line != 4 {
+		t.Errorf("Unexpected matches: %+v", matches)
+	}
+
+	// Patterns are matched against "[host]:port", so the CA only applies to
+	// port 22 here
+	matches = Lookup(entries, "git.example.com", "22")
+	if len(matches) != 1 || matches[0].Marker != MarkerCertAuthority {
+		t.Errorf("Unexpected matches: %+v", matches)
+	}
+}
+
+func TestRemove(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "known_hosts")
+	original := "a ssh-ed25519 AAAA\nb ssh-ed25519 BBBB\nc ssh-ed25519 CCCC"
+	if err := ioutil.WriteFile(path, []byte(original), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, _ := ReadFile(path)
+	if err := Remove(path, Lookup(entries, "b", "22")); err != nil {
+		t.Fatalf("Remove failed: %v", err)
+	}
+	if err := Remove(path, Lookup(entries, "c", "22")); err == nil {
+		t.Fatal("Expected an error for an entry that moved")
+	}
+	entries, _ = ReadFile(path)
+	if err := Remove(path, Lookup(entries, "c", "22")); err != nil {
+		t.Fatalf("Remove failed: %v", err)
+	}
+	data, _ := ioutil.ReadFile(path)
+	if string(data) != "a ssh-ed25519 AAAA\n" {
+		t.Errorf("Unexpected content: %q", data)
+	}
+	backup, _ := ioutil.ReadFile(path + ".old")
+	if string(backup) != "a ssh-ed25519 AAAA\nc ssh-ed25519 CCCC" {
+		t.Errorf("Expected the backup to hold the old content, got %q", backup)
+	}
+	if info, _ := os.Stat(path); info.Mode().Perm() != 0600 {
+		t.Errorf("Expected permissions to be kept, got %v", info.Mode())
+	}
+}
+
+func TestRemoveAfterChange(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "known_hosts")
+	if err := ioutil.WriteFile(path, []byte("a ssh-ed25519 AAAA\nb ssh-ed25519 BBBB\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	entries, _ := ReadFile(path)
+	stale := Lookup(entries, "b", "22")
+
+	// ssh replaces the key of a on line 1 and another session rewrites the
+	// file, so line 2 now holds someone else's key
+	changed := "a ssh-ed25519 AAAB\nother ssh-ed25519 OOOO\nb ssh-ed25519 BBBB\n"
+	if err := ioutil.WriteFile(path, []byte(changed), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := Remove(path, stale); !errors.Is(err, ErrChanged) {
+		t.Errorf("Expected ErrChanged, got %v", err)
+	}
+	if data, _ := ioutil.ReadFile(path); string(data) != changed {
+		t.Errorf("Expected the file to be left alone, got %q", data)
+	}
+}
+
+func TestCompare(t *testing.T) {
+	stored := []Entry{
+		{KeyType: "ssh-ed25519", Key: ed25519Key},
+		{KeyType: "ssh-rsa", Key: "AAAAB3NzaC1yc2E="},
+		{KeyType: "ecdsa-sha2-nistp256", Key: "AAAAE2VjZHNhLXNoYTItbmlzdHAyNTY="},
+		{Marker: MarkerRevoked, KeyType: "ssh-ed25519", Key: otherKey},
+		{Marker: MarkerCertAuthority, KeyType: "ssh-ed25519", Key: otherKey},
+	}
+	offered := []Entry{
+		{KeyType: "ssh-ed25519", Key: ed25519Key},
+		{KeyType: "ssh-rsa", Key: "AAAAB3NzaC1yc2EAAAA="},
+		{KeyType: "ssh-dss", Key: "AAAAB3NzaC1kc3M="},
+	}
+
+	checks := Compare(stored, offered)
+	expected := []struct {
+		keyType string
+		status  KeyStatus
+	}{
+		{"ssh-ed25519", KeyMatch},
+		{"ssh-rsa", KeyChanged},
+		{"ssh-dss", KeyNew},
+		{"ecdsa-sha2-nistp256", KeyNotOffered},
+	}
+	if len(checks) != len(expected) {
+		t.Fatalf("Expected %d checks, got %+v", len(expected), checks)
+	}
+	for i, e := range expected {
+		if checks[i].KeyType != e.keyType || checks[i].Status != e.status {
+			t.Errorf("check %d: expected %s %v, got %s %v", i, e.keyType, e.status, checks[i].KeyType, checks[i].Status)
+		}
+	}
+	if checks[1].Stored == checks[1].Offered || checks[1].Stored == "" {
+		t.Errorf("Expected both fingerprints for a changed key, got %+v", checks[1])
+	}
+
+	revoked := Compare(stored, []Entry{{KeyType: "ssh-ed25519", Key: otherKey}})
+	if revoked[0].Status != KeyRevoked {
+		t.Errorf("Expected a revoked key, got %v", revoked[0].Status)
+	}
+}
@@ -0,0 +1,198 @@
+// Package knownhosts reads OpenSSH known_hosts files.
+package knownhosts
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Markers that may precede the host patterns of a line.
+const (
+	MarkerCertAuthority = "@cert-authority"
+	MarkerRevoked       = "@revoked"
+)
+
+// hashPrefix starts hashed host names written with HashKnownHosts.
+const hashPrefix = "|1|"
+
+// Entry is one key line of a known_hosts file.
+type Entry struct {
+	Line    int
+	Marker  string   // MarkerCertAuthority, MarkerRevoked or empty
+	Hosts   []string // patterns as written, e.g. "[git.example.com]:2222"
+	KeyType string
+	Key     string // base64 encoded public key
+	Comment string
+}
+
+// DefaultPath returns ~/.ssh/known_hosts.
+func DefaultPath() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return filepath.Join(".ssh", "known_hosts")
+	}
+	return filepath.Join(home, ".ssh", "known_hosts")
+}
+
+// Parse reads every key line of a known_hosts file. Blank lines, comments
+// and lines ssh could not use either are skipped.
+func Parse(data []byte) []Entry {
+	var entries []Entry
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		entry := Entry{Line: lineNo}
+		if strings.HasPrefix(fields[0], "@") {
+			entry.Marker = fields[0]
+			fields = fields[1:]
+		}
+		if len(fields) < 3 {
+			continue
+		}
+		entry.Hosts = strings.Split(fields[0], ",")
+		entry.KeyType = fields[1]
+		entry.Key = fields[2]
+		entry.Comment = strings.Join(fields[3:], " ")
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// ReadFile parses the known_hosts file at path. A missing file has no
+// entries.
+func ReadFile(path string) ([]Entry, error) {
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return Parse(data), nil
+}
+
+// IsHashed reports whether the host names of the entry are hashed, which
+// hides them: they can only be matched against a name, not listed.
+func (e Entry) IsHashed() bool {
+	return len(e.Hosts) == 1 && strings.HasPrefix(e.Hosts[0], hashPrefix)
+}
+
+// Fingerprint returns the SHA256 fingerprint of the key in the format ssh
+// prints it, e.g. "SHA256:uNiVztksCsDhcc0u9e8BujQXVUpKZIDTMczCvj3tD2s".
+func (e Entry) Fingerprint() string {
+	return Fingerprint(e.Key)
+}
+
+// Fingerprint returns the SHA256 fingerprint of a base64 encoded public key,
+// or an empty string if the key cannot be decoded.
+func Fingerprint(key string) string {
+	blob, err := base64.StdEncoding.DecodeString(key)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(blob)
+	return "SHA256:" + base64.RawStdEncoding.EncodeToString(sum[:])
+}
+
+// SplitHostPort splits a known_hosts name such as "[host]:2222" into host
+// and port. Names without a port use port 22.
+func SplitHostPort(name string) (host, port string) {
+	if strings.HasPrefix(name, "[") {
+		if end := strings.Index(name, "]:"); end > 0 {
+			return name[1:end], name[end+2:]
+		}
+	}
+	return name, "22"
+}
+
+// JoinHostPort is the reverse of SplitHostPort: ssh only writes the port when
+// it is not 22.
+func JoinHostPort(host, port string) string {
+	if port == "" || port == "22" {
+		return host
+	}
+	return "[" + host + "]:" + port
+}
+
+// Matches reports whether the entry applies to host on port, the way ssh
+// looks it up: hashed names are compared by hash, others as patterns where
+// a negated match excludes the line.
+func (e Entry) Matches(host, port string) bool {
+	name := JoinHostPort(host, port)
+	if e.IsHashed() {
+		return matchHashed(e.Hosts[0], name)
+	}
+	matched := false
+	for _, pattern := range e.Hosts {
+		negated := strings.HasPrefix(pattern, "!")
+		if matchPattern(strings.TrimPrefix(pattern, "!"), name) {
+			if negated {
+				return false
+			}
+			matched = true
+		}
+	}
+	return matched
+}
+
+// matchHashed checks a "|1|salt|hash" name, where hash is the HMAC-SHA1 of
+// the name keyed with salt.
+func matchHashed(hashed, name string) bool {
+	parts := strings.Split(strings.TrimPrefix(hashed, hashPrefix), "|")
+	if len(parts) != 2 {
+		return false
+	}
+	salt, err := base64.StdEncoding.DecodeString(parts[0])
+	if err != nil {
+		return false
+	}
+	want, err := base64.StdEncoding.DecodeString(parts[1])
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(sha1.New, salt)
+	mac.Write([]byte(name))
+	return hmac.Equal(mac.Sum(nil), want)
+}
+
+// matchPattern matches known_hosts patterns, where * matches any run of
+// characters and ? exactly one.
+func matchPattern(pattern, name string) bool {
+	for len(pattern) > 0 {
+		switch pattern[0] {
+		case '*':
+			for i := len(name); i >= 0; i-- {
+				if matchPattern(pattern[1:], name[i:]) {
+					return true
+				}
+			}
+			return false
+		case '?':
+			if name == "" {
+				return false
+			}
+			name = name[1:]
+		default:
+			if name == "" || !strings.EqualFold(pattern[:1], name[:1]) {
+				return false
+			}
+			name = name[1:]
+		}
+		pattern = pattern[1:]
+	}
+	return name == ""
+}
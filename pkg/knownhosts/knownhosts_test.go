@@ -0,0 +1,90 @@
+package knownhosts
+
+import (
+	"reflect"
+	"testing"
+)
+
+const ed25519Key = "AAAAC3NzaC1lZDI1NTE5AAAAIOMqqnkVzrm0SdG6UOoqKLsabgH5C9okWi0dh2l9GKJl"
+
+const knownHostsFixture = `# managed by hand
+github.com,140.82.121.3 ssh-ed25519 ` + ed25519Key + `
+github.com ecdsa-sha2-nistp256 AAAAE2VjZHNhLXNoYTItbmlzdHAyNTY=
+[git.example.com]:2222 ssh-ed25519 ` + ed25519Key + ` deploy key
+
+|1|MDEyMzQ1Njc4OWFiY2RlZmdoaWo=|p0qzQgminFDNpO0/gAB8yw9rqLs= ssh-ed25519 ` + ed25519Key + `
+@cert-authority *.example.com ssh-ed25519 ` + ed25519Key + `
+@revoked old.example.com ssh-rsa AAAAB3NzaC1yc2E=
+*.lan,!router.lan ssh-rsa AAAAB3NzaC1yc2E=
+broken-line
+`
+
+func TestParse(t *testing.T) {
+	entries := Parse([]byte(knownHostsFixture))
+	if len(entries) != 7 {
+		t.Fatalf("Expected 7 entries, got %d", len(entries))
+	}
+
+	first := entries[0]
+	if first.Line != 2 || !reflect.DeepEqual(first.Hosts, []string{"github.com", "140.82.121.3"}) || first.KeyType != "ssh-ed25519" {
+		t.Errorf("Unexpected first entry: %+v", first)
+	}
+	if entries[2].Comment != "deploy key" {
+		t.Errorf("Expected comment, got %q", entries[2].Comment)
+	}
+	if !entries[3].IsHashed() || entries[0].IsHashed() {
+		t.Error("Unexpected IsHashed result")
+	}
+	if entries[4].Marker != MarkerCertAuthority || entries[5].Marker != MarkerRevoked {
+		t.Errorf("Unexpected markers: %q, %q", entries[4].Marker, entries[5].Marker)
+	}
+}
+
+func TestFingerprint(t *testing.T) {
+	entry := Entry{Key: ed25519Key}
+	if got := entry.Fingerprint(); got != "SHA256:+DiY3wvvV6TuJJhbpZisF/zLDA0zPMSvHdkr4UvCOqU" {
+		t.Errorf("Unexpected fingerprint %s", got)
+	}
+	if got := Fingerprint("not base64!"); got != "" {
+		t.Errorf("Expected no fingerprint for an invalid key, got %s", got)
+	}
+}
+
+func TestSplitHostPort(t *testing.T) {
+	if host, port := SplitHostPort("[git.example.com]:2222"); host != "git.example.com" || port != "2222" {
+		t.Errorf("Unexpected split: %s %s", host, port)
+	}
+	if host, port := SplitHostPort("github.com"); host != "github.com" || port != "22" {
+		t.Errorf("Unexpected split: %s %s", host, port)
+	}
+	if got := JoinHostPort("git.example.com", "2222"); got != "[git.example.com]:2222" {
+		t.Errorf("Unexpected join: %s", got)
+	}
+	if got := JoinHostPort("github.com", "22"); got != "github.com" {
+		t.Errorf("Unexpected join: %s", got)
+	}
+}
+
+func TestMatches(t *testing.T) {
+	entries := Parse([]byte(knownHostsFixture))
+	cases := []struct {
+		entry      int
+		host, port string
+		match      bool
+	}{
+		{0, "140.82.121.3", "22", true},
+		{0, "GitHub.com", "22", true},
+		{0, "github.com", "2222", false},
+		{2, "git.example.com", "2222", true},
+		{2, "git.example.com", "22", false},
+		{3, "hidden.example.com", "22", true},
+		{3, "other.example.com", "22", false},
+		{6, "nas.lan", "22", true},
+		{6, "router.lan", "22", false},
+	}
+	for _, c := range cases {
+		if got := entries[c.entry].Matches(c.host, c.port); got != c.match {
+			t.Errorf("entry %d Matches(%s, %s) = %v, expected %v", c.entry, c.host, c.port, got, c.match)
+		}
+	}
+}
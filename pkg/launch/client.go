@@ -0,0 +1,103 @@
+package launch
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// The built-in clients. Any other client is a template from the settings.
+const (
+	SSH     = "ssh"
+	Mosh    = "mosh"
+	AutoSSH = "autossh"
+)
+
+// Endpoint is a host as its effective settings in the SSH config describe
+// it, for clients that cannot read the SSH config themselves.
+type Endpoint struct {
+	Alias    string
+	HostName string // the alias when not set
+	User     string // the local user when not set
+	Port     string // 22 when not set
+}
+
+// ClientCommand returns the argv that connects to e with client, applying
+// o. An empty client is ssh. custom maps the names of other clients to
+// command templates, where {alias}, {hostname}, {user} and {port} stand for
+// the settings of e, and {args} for the ssh arguments of o as separate
+// words. A template without any of them gets the ssh arguments and the
+// alias appended, like a drop-in replacement for ssh. Values are passed as
+// single arguments, never through a shell.
+func ClientCommand(client string, custom map[string]string, e Endpoint, o Options) ([]string, error) {
+	args := o.Args()
+	switch client {
+	case "", SSH:
+		return append(append([]string{"ssh"}, args...), "--", e.Alias), nil
+	case Mosh:
+		// mosh starts ssh itself and takes its options as one command line
+		argv := []string{"mosh"}
+		if len(args) > 0 {
+			argv = append(argv, "--ssh="+CommandLine(append([]string{"ssh"}, args...)))
+		}
+		return append(argv, "--", e.Alias), nil
+	case AutoSSH:
+		// Without a monitor port autossh relies on ssh noticing a dead
+		// connection, which these make it do within a minute and a half.
+		argv := append([]string{"autossh", "-M", "0"}, args...)
+		argv = append(argv, "-o", "ServerAliveInterval=30", "-o", "ServerAliveCountMax=3")
+		return append(argv, "--", e.Alias), nil
+	}
+
+	template, ok := custom[client]
+	if !ok {
+		return nil, fmt.Errorf("unknown client %q, expected %s", client, strings.Join(Clients(custom), ", "))
+	}
+	words, err := splitWords(template)
+	if err != nil {
+		return nil, fmt.Errorf("invalid command of client %s: %v", client, err)
+	}
+	if len(words) == 0 {
+		return nil, fmt.Errorf("no command is configured for client %s", client)
+	}
+
+	if o.User != "" {
+		e.User = o.User
+	}
+	if o.Port != "" {
+		e.Port = o.Port
+	}
+	replacer := strings.NewReplacer("{alias}", e.Alias, "{hostname}", e.HostName, "{user}", e.User, "{port}", e.Port)
+	var argv []string
+	used := false
+	for _, word := range words {
+		if word == "{args}" {
+			argv = append(argv, args...)
+			used = true
+			continue
+		}
+		expanded := replacer.Replace(word)
+		if expanded != word {
+			used = true
+		}
+		argv = append(argv, expanded)
+	}
+	if !used {
+		argv = append(append(argv, args...), e.Alias)
+	}
+	return argv, nil
+}
+
+// Clients returns the names of the built-in clients followed by those of
+// custom, sorted.
+func Clients(custom map[string]string) []string {
+	names := []string{SSH, Mosh, AutoSSH}
+	var extra []string
+	for name := range custom {
+		if name != SSH && name != Mosh && name != AutoSSH {
+			extra = append(extra, name)
+		}
+	}
+	sort.Strings(extra)
+	return append(names, extra...)
+}
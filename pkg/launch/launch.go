@@ -0,0 +1,272 @@
+// Package launch opens ssh sessions: with one-off options, through ssh,
+// mosh, autossh or a custom client, and outside of the terminal ohmyssh
+// runs in, in a new tmux window or pane, a new screen window, or a new
+// terminal window started from a command template.
+package launch
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// startupGrace is how long a terminal is watched for failing to start.
+var startupGrace = time.Second
+
+// Target is where a session opens.
+type Target string
+
+const (
+	Current    Target = "current"
+	TmuxWindow Target = "tmux-window"
+	TmuxPane   Target = "tmux-pane"
+	Screen     Target = "screen"
+	Terminal   Target = "terminal"
+)
+
+// Targets lists all targets in the order they are offered.
+var Targets = []Target{Current, TmuxWindow, TmuxPane, Screen, Terminal}
+
+// ParseTarget reads a target name; "" is the current terminal.
+func ParseTarget(s string) (Target, error) {
+	if s == "" {
+		return Current, nil
+	}
+	for _, t := range Targets {
+		if string(t) == s {
+			return t, nil
+		}
+	}
+	return Current, fmt.Errorf("unknown launch target %q, use one of %s", s, strings.Join(targetNames(), ", "))
+}
+
+func targetNames() []string {
+	names := make([]string, len(Targets))
+	for i, t := range Targets {
+		names[i] = string(t)
+	}
+	return names
+}
+
+// Describe names the target for menus and messages.
+func (t Target) Describe() string {
+	switch t {
+	case TmuxWindow:
+		return "new tmux window"
+	case TmuxPane:
+		return "new tmux pane"
+	case Screen:
+		return "new screen window"
+	case Terminal:
+		return "new terminal window"
+	default:
+		return "this terminal"
+	}
+}
+
+// Launcher opens sessions in the targets the environment offers.
+type Launcher struct {
+	Terminal string              // command template of the terminal target
+	Getenv   func(string) string // os.Getenv if nil
+}
+
+// NewLauncher returns a launcher that opens terminals with template.
+func NewLauncher(template string) *Launcher {
+	return &Launcher{Terminal: template}
+}
+
+func (l *Launcher) getenv(key string) string {
+	if l.Getenv != nil {
+		return l.Getenv(key)
+	}
+	return os.Getenv(key)
+}
+
+// Available returns nil if sessions can open in t from here, or else why
+// not.
+func (l *Launcher) Available(t Target) error {
+	switch t {
+	case TmuxWindow, TmuxPane:
+		if l.getenv("TMUX") == "" {
+			return fmt.Errorf("not running inside tmux")
+		}
+	case Screen:
+		if l.getenv("STY") == "" {
+			return fmt.Errorf("not running inside screen")
+		}
+	case Terminal:
+		if strings.TrimSpace(l.Terminal) == "" {
+			return fmt.Errorf("no terminal command is configured")
+		}
+	}
+	return nil
+}
+
+// Command returns the command that opens session, the arguments of the
+// program to run, in t under title. The current terminal has no command.
+func (l *Launcher) Command(t Target, title string, session []string) ([]string, error) {
+	if err := l.Available(t); err != nil {
+		return nil, err
+	}
+	switch t {
+	case TmuxWindow:
+		return append([]string{"tmux", "new-window", "-n", title}, session...), nil
+	case TmuxPane:
+		return append([]string{"tmux", "split-window", "-h"}, session...), nil
+	case Screen:
+		return append([]string{"screen", "-X", "screen", "-t", title}, session...), nil
+	case Terminal:
+		return expandTemplate(l.Terminal, title, session)
+	}
+	return nil, fmt.Errorf("sessions in %s are not launched", t.Describe())
+}
+
+// Start opens session in t and returns once it is open. tmux and screen
+// return right away; a terminal keeps running, so it is detached and only
+// watched briefly for failing to start.
+func (l *Launcher) Start(t Target, title string, session []string) error {
+	args, err := l.Command(t, title, session)
+	if err != nil {
+		return err
+	}
+	// Output goes to a file rather than a pipe, so that the terminal can
+	// still write to it once ohmyssh is gone
+	output, err := os.CreateTemp("", "ohmyssh-launch-*")
+	if err != nil {
+		return fmt.Errorf("failed to launch %s: %v", args[0], err)
+	}
+	defer os.Remove(output.Name())
+	defer output.Close()
+
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Stdout = output
+	cmd.Stderr = output
+	if t == Terminal {
+		detach(cmd)
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to launch %s: %v", args[0], err)
+	}
+	exited := make(chan error, 1)
+	go func() { exited <- cmd.Wait() }()
+
+	wait := startupGrace
+	if t != Terminal {
+		wait = 10 * time.Second
+	}
+	select {
+	case err := <-exited:
+		if err == nil {
+			return nil
+		}
+		if reason := lastLine(output); reason != "" {
+			return fmt.Errorf("%s failed: %s", args[0], reason)
+		}
+		return fmt.Errorf("%s failed: %v", args[0], err)
+	case <-time.After(wait):
+		if t != Terminal {
+			return fmt.Errorf("%s did not respond", args[0])
+		}
+		return nil
+	}
+}
+
+// lastLine returns the last line written to f.
+func lastLine(f *os.File) string {
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return ""
+	}
+	data, _ := io.ReadAll(io.LimitReader(f, 64*1024))
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	return strings.TrimSpace(lines[len(lines)-1])
+}
+
+// expandTemplate builds the terminal command from template. {cmd} as a word
+// of its own stands for the arguments of session, within a word for the
+// session as a shell command line; {title} is the title. Without {cmd} the
+// session is appended.
+func expandTemplate(template, title string, session []string) ([]string, error) {
+	words, err := splitWords(template)
+	if err != nil {
+		return nil, fmt.Errorf("invalid terminal command: %v", err)
+	}
+	if len(words) == 0 {
+		return nil, fmt.Errorf("no terminal command is configured")
+	}
+	commandLine := CommandLine(session)
+
+	var args []string
+	used := false
+	for _, word := range words {
+		if word == "{cmd}" {
+			args = append(args, session...)
+			used = true
+			continue
+		}
+		if strings.Contains(word, "{cmd}") {
+			word = strings.ReplaceAll(word, "{cmd}", commandLine)
+			used = true
+		}
+		args = append(args, strings.ReplaceAll(word, "{title}", title))
+	}
+	if !used {
+		args = append(args, session...)
+	}
+	return args, nil
+}
+
+// splitWords splits s at spaces like a shell would, honouring single and
+// double quotes and backslash escapes, but nothing else.
+func splitWords(s string) ([]string, error) {
+	var words []string
+	var word strings.Builder
+	inWord := false
+	var quote rune
+	escaped := false
+	for _, r := range s {
+		switch {
+		case escaped:
+			word.WriteRune(r)
+			escaped = false
+		case r == '\\' && quote != '\'':
+			escaped = true
+			inWord = true
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				word.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote = r
+			inWord = true
+		case r == ' ' || r == '\t' || r == '\n':
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+	}
+	if quote != 0 || escaped {
+		return nil, fmt.Errorf("unterminated quote in %q", s)
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	return words, nil
+}
+
+// shellQuote quotes s for a POSIX shell unless it is plain.
+func shellQuote(s string) string {
+	if s != "" && strings.Trim(s, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-_./=:@,+%") == "" {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
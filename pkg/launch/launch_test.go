@@ -0,0 +1,172 @@
+package launch
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func env(vars map[string]string) func(string) string {
+	return func(key string) string { return vars[key] }
+}
+
+func TestParseTarget(t *testing.T) {
+	for _, name := range []string{"", "current", "tmux-window", "tmux-pane", "screen", "terminal"} {
+		if _, err := ParseTarget(name); err != nil {
+			t.Errorf("ParseTarget(%q): %v", name, err)
+		}
+	}
+	if _, err := ParseTarget("tmux"); err == nil || !strings.Contains(err.Error(), "tmux-window") {
+		t.Errorf("Expected an error listing the targets, got %v", err)
+	}
+}
+
+func TestAvailable(t *testing.T) {
+	outside := &Launcher{Getenv: env(nil)}
+	for _, target := range []Target{TmuxWindow, TmuxPane, Screen, Terminal} {
+		if outside.Available(target) == nil {
+			t.Errorf("Expected %s to be unavailable", target)
+		}
+	}
+	if outside.Available(Current) != nil {
+		t.Error("Expected the current terminal to be available")
+	}
+
+	inside := &Launcher{Terminal: "xterm -e {cmd}", Getenv: env(map[string]string{"TMUX": "/tmp/tmux-1000/default,1,0", "STY": "1.pts-0"})}
+	for _, target := range Targets {
+		if err := inside.Available(target); err != nil {
+			t.Errorf("Expected %s to be available, got %v", target, err)
+		}
+	}
+}
+
+func TestCommand(t *testing.T) {
+	l := &Launcher{Terminal: "xterm -T {title} -e {cmd}", Getenv: env(map[string]string{"TMUX": "x", "STY": "x"})}
+	session := []string{"/usr/bin/ohmyssh", "connect", "db1"}
+	tests := []struct {
+		target Target
+		want   []string
+	}{
+		{TmuxWindow, []string{"tmux", "new-window", "-n", "db1", "/usr/bin/ohmyssh", "connect", "db1"}},
+		{TmuxPane, []string{"tmux", "split-window", "-h", "/usr/bin/ohmyssh", "connect", "db1"}},
+		{Screen, []string{"screen", "-X", "screen", "-t", "db1", "/usr/bin/ohmyssh", "connect", "db1"}},
+		{Terminal, []string{"xterm", "-T", "db1", "-e", "/usr/bin/ohmyssh", "connect", "db1"}},
+	}
+	for _, test := range tests {
+		got, err := l.Command(test.target, "db1", session)
+		if err != nil || !reflect.DeepEqual(got, test.want) {
+			t.Errorf("Command(%s) = %q, %v, want %q", test.target, got, err, test.want)
+		}
+	}
+	if _, err := l.Command(Current, "db1", session); err == nil {
+		t.Error("Expected no command for the current terminal")
+	}
+}
+
+func TestExpandTemplate(t *testing.T) {
+	session := []string{"/opt/my apps/ohmyssh", "connect", "db1"}
+	tests := []struct {
+		template string
+		want     []string
+	}{
+		{"wezterm start --", []string{"wezterm", "start", "--", "/opt/my apps/ohmyssh", "connect", "db1"}},
+		{`osascript -e 'tell app "Terminal" to do script "{cmd}"'`, []string{"osascript", "-e", `tell app "Terminal" to do script "'/opt/my apps/ohmyssh' connect db1"`}},
+		{`gnome-terminal --title="ssh {title}" -- {cmd}`, []string{"gnome-terminal", "--title=ssh db1", "--", "/opt/my apps/ohmyssh", "connect", "db1"}},
+	}
+	for _, test := range tests {
+		got, err := expandTemplate(test.template, "db1", session)
+		if err != nil || !reflect.DeepEqual(got, test.want) {
+			t.Errorf("expandTemplate(%s) = %q, %v, want %q", test.template, got, err, test.want)
+		}
+	}
+	if _, err := expandTemplate(`xterm -e "{cmd}`, "db1", session); err == nil {
+		t.Error("Expected an error for an unterminated quote")
+	}
+}
+
+func TestShellQuote(t *testing.T) {
+	tests := map[string]string{
+		"db1":        "db1",
+		"":           "''",
+		"it's":       `'it'\''s'`,
+		"a b":        "'a b'",
+		"user@h:/x=": "user@h:/x=",
+	}
+	for in, want := range tests {
+		if got := shellQuote(in); got != want {
+			t.Errorf("shellQuote(%q) = %s, want %s", in, got, want)
+		}
+	}
+}
+
+func TestOptions(t *testing.T) {
+	o := Options{User: "root", Port: "2222", Options: []string{"StrictHostKeyChecking=no", "ProxyCommand ssh -W %h:%p gw"}, Verbose: 2, ForwardAgent: true}
+	if err := o.Validate(); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+	want := []string{"-l", "root", "-p", "2222", "-o", "StrictHostKeyChecking=no", "-o", "ProxyCommand ssh -W %h:%p gw", "-vv", "-A"}
+	if got := o.Args(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Args = %q, want %q", got, want)
+	}
+	if got := CommandLine(append([]string{"ssh"}, o.Args()...)); got != "ssh -l root -p 2222 -o StrictHostKeyChecking=no -o 'ProxyCommand ssh -W %h:%p gw' -vv -A" {
+		t.Errorf("CommandLine = %s", got)
+	}
+	if o.IsZero() || !(Options{}).IsZero() {
+		t.Error("IsZero is wrong")
+	}
+
+	invalid := []Options{
+		{User: "-oProxyCommand=x"},
+		{User: "bob smith"},
+		{Port: "ssh"},
+		{Port: "70000"},
+		{Options: []string{"=yes"}},
+		{Options: []string{"-F/tmp/x"}},
+		{Verbose: 4},
+		{Client: "-oProxyCommand=x"},
+	}
+	for _, o := range invalid {
+		if o.Validate() == nil {
+			t.Errorf("Expected %+v to be invalid", o)
+		}
+	}
+}
+
+func TestClientCommand(t *testing.T) {
+	e := Endpoint{Alias: "db1", HostName: "10.0.0.5", User: "deploy", Port: "22"}
+	custom := map[string]string{
+		"et":   "et {user}@{hostname}:{port}",
+		"tsh":  "tsh ssh",
+		"wrap": "wrapper --target {alias} -- {args}",
+	}
+	o := Options{User: "root", Port: "2222"}
+	tests := []struct {
+		client  string
+		options Options
+		want    []string
+	}{
+		{"", o, []string{"ssh", "-l", "root", "-p", "2222", "--", "db1"}},
+		{"mosh", Options{}, []string{"mosh", "--", "db1"}},
+		{"mosh", o, []string{"mosh", "--ssh=ssh -l root -p 2222", "--", "db1"}},
+		{"autossh", Options{ForwardAgent: true}, []string{"autossh", "-M", "0", "-A", "-o", "ServerAliveInterval=30", "-o", "ServerAliveCountMax=3", "--", "db1"}},
+		{"et", Options{}, []string{"et", "deploy@10.0.0.5:22"}},
+		{"et", o, []string{"et", "root@10.0.0.5:2222"}},
+		{"et", Options{User: "a;rm -rf ~"}, []string{"et", "a;rm -rf ~@10.0.0.5:22"}},
+		{"tsh", o, []string{"tsh", "ssh", "-l", "root", "-p", "2222", "db1"}},
+		{"wrap", Options{Verbose: 1}, []string{"wrapper", "--target", "db1", "--", "-v"}},
+	}
+	for _, test := range tests {
+		got, err := ClientCommand(test.client, custom, e, test.options)
+		if err != nil || !reflect.DeepEqual(got, test.want) {
+			t.Errorf("ClientCommand(%s, %+v) = %q, %v, want %q", test.client, test.options, got, err, test.want)
+		}
+	}
+
+	_, err := ClientCommand("kitten", custom, e, Options{})
+	if err == nil || !strings.Contains(err.Error(), "ssh, mosh, autossh, et, tsh, wrap") {
+		t.Errorf("Expected an error listing the clients, got %v", err)
+	}
+	if _, err := ClientCommand("bad", map[string]string{"bad": `x "y`}, e, Options{}); err == nil {
+		t.Error("Expected an error for an unterminated quote")
+	}
+}
@@ -0,0 +1,83 @@
+package launch
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// MaxVerbose is the highest verbosity ssh knows, -vvv.
+const MaxVerbose = 3
+
+// Options change how ssh connects for one session, on top of the host's
+// settings in the SSH config.
+type Options struct {
+	Client       string   `json:"client,omitempty"` // ssh when empty, see ClientCommand
+	User         string   `json:"user,omitempty"`
+	Port         string   `json:"port,omitempty"`
+	Options      []string `json:"options,omitempty"` // -o values such as "ServerAliveInterval=30"
+	Verbose      int      `json:"verbose,omitempty"` // number of -v
+	ForwardAgent bool     `json:"forward_agent,omitempty"`
+}
+
+// IsZero reports whether the options change nothing.
+func (o Options) IsZero() bool {
+	return o.Client == "" && o.User == "" && o.Port == "" && len(o.Options) == 0 && o.Verbose == 0 && !o.ForwardAgent
+}
+
+// Validate checks that every value is one ssh accepts and that none could
+// be taken for another option.
+func (o Options) Validate() error {
+	if strings.HasPrefix(o.Client, "-") || strings.ContainsAny(o.Client, " \t\n") {
+		return fmt.Errorf("invalid client %q", o.Client)
+	}
+	if o.User != "" && (strings.HasPrefix(o.User, "-") || strings.ContainsAny(o.User, " \t\n")) {
+		return fmt.Errorf("invalid user %q", o.User)
+	}
+	if o.Port != "" {
+		if port, err := strconv.Atoi(o.Port); err != nil || port < 1 || port > 65535 {
+			return fmt.Errorf("invalid port %q, expected 1-65535", o.Port)
+		}
+	}
+	for _, option := range o.Options {
+		key, _, ok := strings.Cut(strings.Replace(option, " ", "=", 1), "=")
+		if !ok || key == "" || strings.Trim(key, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789") != "" {
+			return fmt.Errorf("invalid option %q, expected Key=Value", option)
+		}
+	}
+	if o.Verbose < 0 || o.Verbose > MaxVerbose {
+		return fmt.Errorf("invalid verbosity %d, expected 0-%d", o.Verbose, MaxVerbose)
+	}
+	return nil
+}
+
+// Args returns the ssh arguments for the options, to go before the host.
+func (o Options) Args() []string {
+	var args []string
+	if o.User != "" {
+		args = append(args, "-l", o.User)
+	}
+	if o.Port != "" {
+		args = append(args, "-p", o.Port)
+	}
+	for _, option := range o.Options {
+		args = append(args, "-o", option)
+	}
+	if o.Verbose > 0 {
+		args = append(args, "-"+strings.Repeat("v", o.Verbose))
+	}
+	if o.ForwardAgent {
+		args = append(args, "-A")
+	}
+	return args
+}
+
+// CommandLine returns argv as a command line for a POSIX shell, quoting
+// what needs it.
+func CommandLine(argv []string) string {
+	quoted := make([]string, len(argv))
+	for i, arg := range argv {
+		quoted[i] = shellQuote(arg)
+	}
+	return strings.Join(quoted, " ")
+}
@@ -0,0 +1,14 @@
+//go:build !windows
+
+package launch
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// detach starts the process in its own session, so that the terminal stays
+// open when ohmyssh and its terminal go away.
+func detach(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+}
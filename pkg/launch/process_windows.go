@@ -0,0 +1,16 @@
+//go:build windows
+
+package launch
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// createNewProcessGroup keeps the terminal open when the console of
+// ohmyssh is closed.
+const createNewProcessGroup = 0x00000200
+
+func detach(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{CreationFlags: createNewProcessGroup}
+}
@@ -0,0 +1,62 @@
+//go:build !windows
+
+package launch
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeTool puts a shell script named name first on PATH.
+func fakeTool(t *testing.T, name, body string) {
+	t.Helper()
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, name), []byte("#!/bin/sh\n"+body+"\n"), 0755); err != nil {
+		t.Fatalf("Failed to write fake %s: %v", name, err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+}
+
+func TestStartTmux(t *testing.T) {
+	args := filepath.Join(t.TempDir(), "args")
+	fakeTool(t, "tmux", `echo "$@" > `+args)
+	l := &Launcher{Getenv: env(map[string]string{"TMUX": "x"})}
+
+	if err := l.Start(TmuxWindow, "db1", []string{"ohmyssh", "connect", "db1"}); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	got, _ := os.ReadFile(args)
+	if string(got) != "new-window -n db1 ohmyssh connect db1\n" {
+		t.Errorf("tmux got %q", got)
+	}
+
+	fakeTool(t, "tmux", "echo 'no server running on /tmp/tmux-1000/default' >&2\nexit 1")
+	err := l.Start(TmuxPane, "db1", []string{"ohmyssh", "connect", "db1"})
+	if err == nil || !strings.Contains(err.Error(), "no server running") {
+		t.Errorf("Expected tmux's complaint, got %v", err)
+	}
+}
+
+func TestStartTerminal(t *testing.T) {
+	startupGrace = 100 * time.Millisecond
+	fakeTool(t, "myterm", `case $1 in --bad) echo "unknown option --bad" >&2; exit 2;; esac
+sleep 5`)
+
+	l := &Launcher{Terminal: "myterm -e {cmd}"}
+	start := time.Now()
+	if err := l.Start(Terminal, "db1", []string{"ohmyssh", "connect", "db1"}); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	if time.Since(start) > 2*time.Second {
+		t.Error("Expected Start not to wait for the terminal to close")
+	}
+
+	l.Terminal = "myterm --bad {cmd}"
+	err := l.Start(Terminal, "db1", []string{"ohmyssh", "connect", "db1"})
+	if err == nil || !strings.Contains(err.Error(), "unknown option --bad") {
+		t.Errorf("Expected the terminal's complaint, got %v", err)
+	}
+}
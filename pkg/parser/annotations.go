@@ -0,0 +1,254 @@
+package parser
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// annotationPrefix marks comments that carry ohmyssh metadata, e.g.
+//
+//	# ohmyssh: group=prod/db tags=postgres,critical
+const annotationPrefix = "ohmyssh:"
+
+// Meta holds the ohmyssh annotations attached to a Host block, either in
+// comments directly above the Host line or inside the block.
+type Meta struct {
+	Group string
+	Tags  []string
+	Owner string
+	Notes string            // one line per note= annotation
+	Extra map[string]string // any other key=value pairs
+}
+
+// IsZero reports whether no annotation is set.
+func (m Meta) IsZero() bool {
+	return m.Group == "" && len(m.Tags) == 0 && m.Owner == "" && m.Notes == "" && len(m.Extra) == 0
+}
+
+// GroupPath splits a slash separated group into its nested parts.
+func (m Meta) GroupPath() []string {
+	if m.Group == "" {
+		return nil
+	}
+	var parts []string
+	for _, part := range strings.Split(m.Group, "/") {
+		if part = strings.TrimSpace(part); part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return parts
+}
+
+func (m *Meta) apply(fields map[string]string) {
+	for key, value := range fields {
+		switch key {
+		case "group":
+			m.Group = strings.Join(Meta{Group: value}.GroupPath(), "/")
+		case "tags", "tag":
+			for _, tag := range strings.Split(value, ",") {
+				m.addTag(strings.TrimSpace(tag))
+			}
+		case "owner":
+			m.Owner = value
+		case "note", "notes":
+			if m.Notes != "" {
+				m.Notes += "\n"
+			}
+			m.Notes += value
+		default:
+			if m.Extra == nil {
+				m.Extra = make(map[string]string)
+			}
+			m.Extra[key] = value
+		}
+	}
+}
+
+func (m *Meta) addTag(tag string) {
+	if tag == "" {
+		return
+	}
+	for _, existing := range m.Tags {
+		if existing == tag {
+			return
+		}
+	}
+	m.Tags = append(m.Tags, tag)
+}
+
+// parseAnnotation extracts key=value pairs from an ohmyssh comment. Values may
+// be double quoted to include spaces.
+func parseAnnotation(line string) (map[string]string, bool) {
+	text := strings.TrimSpace(strings.TrimLeft(strings.TrimSpace(line), "#"))
+	if len(text) < len(annotationPrefix) || !strings.EqualFold(text[:len(annotationPrefix)], annotationPrefix) {
+		return nil, false
+	}
+	text = text[len(annotationPrefix):]
+
+	fields := make(map[string]string)
+	for {
+		text = strings.TrimLeft(text, " \t")
+		if text == "" {
+			break
+		}
+
+		// Read the key up to '=' or whitespace
+		end := strings.IndexAny(text, "= \t")
+		if end < 0 {
+			break
+		}
+		key := strings.ToLower(text[:end])
+		text = text[end:]
+		if !strings.HasPrefix(text, "=") {
+			continue
+		}
+		text = text[1:]
+
+		var value string
+		if strings.HasPrefix(text, `"`) {
+			value, text = readQuoted(text[1:])
+		} else {
+			end = strings.IndexAny(text, " \t")
+			if end < 0 {
+				end = len(text)
+			}
+			value, text = text[:end], text[end:]
+		}
+		if key != "" {
+			fields[key] = value
+		}
+	}
+	return fields, true
+}
+
+// readQuoted reads a double quoted string whose opening quote has already been
+// consumed, handling \" and \\ escapes.
+func readQuoted(text string) (string, string) {
+	var b strings.Builder
+	for i := 0; i < len(text); i++ {
+		switch text[i] {
+		case '\\':
+			if i+1 < len(text) {
+				i++
+				b.WriteByte(text[i])
+			}
+		case '"':
+			return b.String(), text[i+1:]
+		default:
+			b.WriteByte(text[i])
+		}
+	}
+	return b.String(), ""
+}
+
+// FormatMeta renders meta as annotation comments: one line with the short
+// fields followed by one line per note.
+func FormatMeta(meta Meta) []string {
+	var fields []string
+	if meta.Group != "" {
+		fields = append(fields, "group="+quoteAnnotation(meta.Group))
+	}
+	if len(meta.Tags) > 0 {
+		fields = append(fields, "tags="+quoteAnnotation(strings.Join(meta.Tags, ",")))
+	}
+	if meta.Owner != "" {
+		fields = append(fields, "owner="+quoteAnnotation(meta.Owner))
+	}
+
+	keys := make([]string, 0, len(meta.Extra))
+	for key := range meta.Extra {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		fields = append(fields, key+"="+quoteAnnotation(meta.Extra[key]))
+	}
+
+	var lines []string
+	if len(fields) > 0 {
+		lines = append(lines, "# "+annotationPrefix+" "+strings.Join(fields, " "))
+	}
+	for _, note := range strings.Split(meta.Notes, "\n") {
+		if note = strings.TrimSpace(note); note != "" {
+			lines = append(lines, "# "+annotationPrefix+" note="+quoteAnnotation(note))
+		}
+	}
+	return lines
+}
+
+// ValidateMeta checks that every annotation stays inside its comment line.
+// Notes are the exception and may span several lines, one comment each.
+func ValidateMeta(meta Meta) error {
+	values := map[string]string{"group": meta.Group, "tags": strings.Join(meta.Tags, ","), "owner": meta.Owner}
+	for key, value := range meta.Extra {
+		if err := ValidateDirective(key, value); err != nil {
+			return err
+		}
+	}
+	for _, note := range strings.Split(meta.Notes, "\n") {
+		if err := validateValue("note", strings.TrimSpace(note)); err != nil {
+			return err
+		}
+	}
+	for key, value := range values {
+		if err := validateValue(key, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func quoteAnnotation(value string) string {
+	if value != "" && !strings.ContainsAny(value, " \t\"\\=") {
+		return value
+	}
+	escaped := strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(value)
+	return fmt.Sprintf(`"%s"`, escaped)
+}
+
+func isAnnotation(line string) bool {
+	_, ok := parseAnnotation(line)
+	return ok
+}
+
+// SetHostMeta replaces the annotation comments of the Host block named name
+// with meta. The new annotations go where the first old one was, or directly
+// above the Host line; every other comment is left untouched.
+func SetHostMeta(content, name string, meta Meta) (string, error) {
+	if err := ValidateMeta(meta); err != nil {
+		return "", err
+	}
+	lines := strings.Split(content, "\n")
+	start, end, found := findHostBlock(lines, name)
+	if !found {
+		return "", fmt.Errorf("%w: %s", ErrHostNotFound, name)
+	}
+
+	insertAt, indent := start, ""
+	kept := make([]string, 0, len(lines))
+	kept = append(kept, lines[:headerStart(lines, start)]...)
+	placed := false
+	for i := headerStart(lines, start); i < end; i++ {
+		if isAnnotation(lines[i]) {
+			if !placed {
+				insertAt = len(kept)
+				indent = lines[i][:len(lines[i])-len(strings.TrimLeft(lines[i], " \t"))]
+				placed = true
+			}
+			continue
+		}
+		if i == start && !placed {
+			insertAt = len(kept)
+		}
+		kept = append(kept, lines[i])
+	}
+	kept = append(kept, lines[end:]...)
+
+	var annotations []string
+	for _, line := range FormatMeta(meta) {
+		annotations = append(annotations, indent+line)
+	}
+	kept = append(kept[:insertAt], append(annotations, kept[insertAt:]...)...)
+	return strings.Join(kept, "\n"), nil
+}
@@ -0,0 +1,117 @@
+package parser
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseAnnotation(t *testing.T) {
+	fields, ok := parseAnnotation(`#   OhMySSH: group=prod/db note="restart with \"systemctl\"" tags=a,b stray`)
+	if !ok {
+		t.Fatal("Expected annotation to be recognised")
+	}
+
+	expected := map[string]string{
+		"group": "prod/db",
+		"note":  `restart with "systemctl"`,
+		"tags":  "a,b",
+	}
+	if !reflect.DeepEqual(fields, expected) {
+		t.Errorf("Expected %v, got %v", expected, fields)
+	}
+
+	if _, ok := parseAnnotation("# just a comment"); ok {
+		t.Error("Expected plain comment not to be an annotation")
+	}
+}
+
+func TestFormatMeta(t *testing.T) {
+	lines := FormatMeta(Meta{
+		Group: "prod/db",
+		Tags:  []string{"postgres", "critical"},
+		Owner: "Jane Doe",
+		Notes: "Primary\nFailover: run \"pg_ctl promote\"",
+		Extra: map[string]string{"runbook": "https://wiki/db"},
+	})
+
+	expected := []string{
+		`# ohmyssh: group=prod/db tags=postgres,critical owner="Jane Doe" runbook=https://wiki/db`,
+		`# ohmyssh: note=Primary`,
+		`# ohmyssh: note="Failover: run \"pg_ctl promote\""`,
+	}
+	if !reflect.DeepEqual(lines, expected) {
+		t.Errorf("Unexpected annotations:\n%s", strings.Join(lines, "\n"))
+	}
+}
+
+func TestSetHostMetaRoundTrip(t *testing.T) {
+	content := `# Database primary
+# ohmyssh: group=prod/db
+Host db1
+    HostName db1.example.com
+    # ohmyssh: tags=postgres
+    User postgres
+
+Host web1
+    HostName web1.example.com
+`
+	meta := Meta{
+		Group: "prod/db",
+		Tags:  []string{"postgres", "critical"},
+		Owner: "dba-team",
+		Notes: "Check replication lag first\nPaged via #dba",
+	}
+
+	updated, err := SetHostMeta(content, "db1", meta)
+	if err != nil {
+		t.Fatalf("SetHostMeta failed: %v", err)
+	}
+
+	expected := `# Database primary
+# ohmyssh: group=prod/db tags=postgres,critical owner=dba-team
+# ohmyssh: note="Check replication lag first"
+# ohmyssh: note="Paged via #dba"
+Host db1
+    HostName db1.example.com
+    User postgres
+
+Host web1
+    HostName web1.example.com
+`
+	if updated != expected {
+		t.Errorf("Unexpected result:\n%s", updated)
+	}
+
+	// New annotations on a bare block go directly above the Host line
+	updated, err = SetHostMeta(updated, "web1", Meta{Tags: []string{"nginx"}})
+	if err != nil {
+		t.Fatalf("SetHostMeta failed: %v", err)
+	}
+	if !strings.Contains(updated, "\n\n# ohmyssh: tags=nginx\nHost web1\n") {
+		t.Errorf("Expected annotation above web1:\n%s", updated)
+	}
+
+	configPath := filepath.Join(t.TempDir(), "config")
+	if err := os.WriteFile(configPath, []byte(updated), 0600); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	config := &SSHConfig{Path: configPath}
+	if err := config.Load(); err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	if got := config.GetHosts()[0].Meta; !reflect.DeepEqual(got, meta) {
+		t.Errorf("Expected metadata to round-trip, got %+v", got)
+	}
+
+	// Clearing metadata removes the annotations but keeps other comments
+	cleared, err := SetHostMeta(updated, "db1", Meta{})
+	if err != nil {
+		t.Fatalf("SetHostMeta failed: %v", err)
+	}
+	if strings.Contains(cleared, "group=prod/db") || !strings.Contains(cleared, "# Database primary\nHost db1") {
+		t.Errorf("Unexpected result after clearing:\n%s", cleared)
+	}
+}
@@ -0,0 +1,115 @@
+package parser
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+type Severity int
+
+const (
+	SeverityWarning Severity = iota
+	SeverityError
+)
+
+func (s Severity) String() string {
+	if s == SeverityError {
+		return "error"
+	}
+	return "warning"
+}
+
+// Problem is something in the config that is wrong or likely a mistake.
+type Problem struct {
+	Severity Severity
+	Host     string
+	Source   string
+	Line     int
+	Message  string
+}
+
+func (p Problem) String() string {
+	if p.Host == "" {
+		return fmt.Sprintf("%s:%d: %s: %s", p.Source, p.Line, p.Severity, p.Message)
+	}
+	return fmt.Sprintf("%s:%d: %s: %s: %s", p.Source, p.Line, p.Severity, p.Host, p.Message)
+}
+
+// Check looks for problems in the loaded hosts, in file order, after those
+// found in the Include directives.
+func (c *SSHConfig) Check() []Problem {
+	problems := append([]Problem(nil), c.includeProblems...)
+	add := func(host Host, severity Severity, format string, args ...interface{}) {
+		problems = append(problems, Problem{
+			Severity: severity,
+			Host:     host.Name,
+			Source:   host.Source,
+			Line:     host.Line,
+			Message:  fmt.Sprintf(format, args...),
+		})
+	}
+
+	seen := make(map[string]Host)
+	for i, host := range c.Hosts {
+		if first, ok := seen[host.Name]; ok {
+			add(host, SeverityWarning, "duplicate Host block, first defined at %s:%d", first.Source, first.Line)
+		} else {
+			seen[host.Name] = host
+		}
+
+		if host.Port != "" {
+			if port, err := strconv.Atoi(host.Port); err != nil || port < 1 || port > 65535 {
+				add(host, SeverityError, "invalid port %q", host.Port)
+			}
+		}
+
+		if identity := host.Options["identityfile"]; identity != "" && !strings.Contains(identity, "%") {
+			if _, err := os.Stat(ExpandHome(strings.Trim(identity, `"`))); err != nil {
+				add(host, SeverityWarning, "identity file %s does not exist", identity)
+			}
+		}
+
+		selfJump := false
+		for _, hop := range strings.Split(host.Options["proxyjump"], ",") {
+			if _, jumpHost, _ := splitJumpHop(strings.TrimSpace(hop)); jumpHost == host.Name {
+				add(host, SeverityError, "ProxyJump refers to the host itself")
+				selfJump = true
+			}
+		}
+
+		if !host.IsWildcard() {
+			chain, err := c.JumpChain(host.Patterns()[0])
+			switch {
+			case errors.Is(err, ErrJumpLoop):
+				if !selfJump {
+					add(host, SeverityError, "%v", err)
+				}
+			case err == nil:
+				for _, hop := range chain[:len(chain)-1] {
+					if hop.Missing() {
+						add(host, SeverityWarning, "jump host %s has no Host entry", hop.Name)
+					}
+				}
+			}
+		}
+
+		// ssh uses the first value it finds, so defaults must come last
+		if host.Name == "*" && i < len(c.Hosts)-1 {
+			add(host, SeverityWarning, "options in Host * override the same options of every host below it")
+		}
+	}
+	return problems
+}
+
+// HasErrors reports whether any problem is an error.
+func HasErrors(problems []Problem) bool {
+	for _, p := range problems {
+		if p.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,74 @@
+package parser
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCheck(t *testing.T) {
+	tempDir := t.TempDir()
+	keyPath := filepath.Join(tempDir, "id_ed25519")
+	if err := os.WriteFile(keyPath, []byte("key"), 0600); err != nil {
+		t.Fatalf("Failed to create key: %v", err)
+	}
+
+	configPath := filepath.Join(tempDir, "config")
+	configContent := `Host *
+    ServerAliveInterval 60
+
+Host good
+    HostName good.example.com
+    Port 2222
+    IdentityFile ` + keyPath + `
+
+Host badport
+    Port 70000
+
+Host loop
+    ProxyJump gw,ops@loop:22
+
+Host nokey
+    IdentityFile ` + filepath.Join(tempDir, "missing") + `
+
+Host good
+    User again
+`
+	if err := os.WriteFile(configPath, []byte(configContent), 0600); err != nil {
+		t.Fatalf("Failed to create test config file: %v", err)
+	}
+
+	config := &SSHConfig{Path: configPath}
+	if err := config.Load(); err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	problems := config.Check()
+	expected := []struct {
+		host     string
+		severity Severity
+		message  string
+	}{
+		{"*", SeverityWarning, "override"},
+		{"badport", SeverityError, "invalid port"},
+		{"loop", SeverityError, "itself"},
+		{"nokey", SeverityWarning, "does not exist"},
+		{"good", SeverityWarning, "duplicate"},
+	}
+	if len(problems) != len(expected) {
+		t.Fatalf("Expected %d problems, got %d: %v", len(expected), len(problems), problems)
+	}
+	for i, e := range expected {
+		p := problems[i]
+		if p.Host != e.host || p.Severity != e.severity || !strings.Contains(p.Message, e.message) {
+			t.Errorf("Problem %d: expected %s %s containing %q, got %v", i, e.host, e.severity, e.message, p)
+		}
+	}
+	if problems[1].Line != 9 || problems[1].Source != configPath {
+		t.Errorf("Expected badport at %s:9, got %s:%d", configPath, problems[1].Source, problems[1].Line)
+	}
+	if !HasErrors(problems) || HasErrors(problems[:1]) {
+		t.Error("Unexpected HasErrors result")
+	}
+}
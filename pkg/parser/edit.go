@@ -0,0 +1,406 @@
+package parser
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+var (
+	ErrHostNotFound = errors.New("host not found")
+	ErrHostExists   = errors.New("host already exists")
+)
+
+// Directive is a single "Key Value" line inside a Host block.
+type Directive struct {
+	Key   string
+	Value string
+}
+
+// canonicalKeys maps lowercase option names to the casing used by ssh_config(5)
+// so that newly written lines look like the ones users write by hand.
+var canonicalKeys = map[string]string{
+	"hostname":              "HostName",
+	"user":                  "User",
+	"port":                  "Port",
+	"identityfile":          "IdentityFile",
+	"identitiesonly":        "IdentitiesOnly",
+	"proxyjump":             "ProxyJump",
+	"proxycommand":          "ProxyCommand",
+	"forwardagent":          "ForwardAgent",
+	"forwardx11":            "ForwardX11",
+	"localforward":          "LocalForward",
+	"remoteforward":         "RemoteForward",
+	"dynamicforward":        "DynamicForward",
+	"serveraliveinterval":   "ServerAliveInterval",
+	"stricthostkeychecking": "StrictHostKeyChecking",
+}
+
+// CanonicalKey returns the conventional spelling of an ssh_config keyword.
+func CanonicalKey(key string) string {
+	if canonical, ok := canonicalKeys[strings.ToLower(key)]; ok {
+		return canonical
+	}
+	return key
+}
+
+// ValidateAlias checks that name can be used as a single Host pattern that
+// matches only itself. Pattern characters would turn the block into one
+// that applies to other hosts, and a leading dash would make ssh read the
+// alias as an option.
+func ValidateAlias(name string) error {
+	if name == "" {
+		return errors.New("alias must not be empty")
+	}
+	if strings.ContainsAny(name, " \t\r\n#") {
+		return fmt.Errorf("alias %q must not contain whitespace or '#'", name)
+	}
+	if strings.ContainsAny(name, "*?!,") {
+		return fmt.Errorf("alias %q must not contain the pattern characters '*', '?', '!' or ','", name)
+	}
+	if strings.HasPrefix(name, "-") {
+		return fmt.Errorf("alias %q must not start with '-'", name)
+	}
+	return nil
+}
+
+// ValidateDirective checks that a directive stays on its own line: a key or
+// value with a newline would start another directive, such as a
+// ProxyCommand, when the file is written.
+func ValidateDirective(key, value string) error {
+	if key == "" || strings.IndexFunc(key, func(r rune) bool { return unicode.IsSpace(r) || unicode.IsControl(r) }) >= 0 {
+		return fmt.Errorf("invalid option name %q", key)
+	}
+	return validateValue(key, value)
+}
+
+// validateValue rejects values with control characters other than tabs.
+func validateValue(name, value string) error {
+	if strings.IndexFunc(value, func(r rune) bool { return r != '\t' && unicode.IsControl(r) }) >= 0 {
+		return fmt.Errorf("invalid %s %q: control characters are not allowed", name, value)
+	}
+	return nil
+}
+
+// splitDirective splits a config line into its leading part (indentation, key
+// and separator), the lowercase key and the value.
+func splitDirective(line string) (prefix, key, value string, ok bool) {
+	trimmed := strings.TrimLeft(line, " \t")
+	if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+		return "", "", "", false
+	}
+	indent := line[:len(line)-len(trimmed)]
+
+	end := strings.IndexAny(trimmed, " \t=")
+	if end < 0 {
+		return "", "", "", false
+	}
+	sepEnd := end
+	for sepEnd < len(trimmed) && strings.ContainsRune(" \t=", rune(trimmed[sepEnd])) {
+		sepEnd++
+	}
+	return indent + trimmed[:sepEnd], strings.ToLower(trimmed[:end]), strings.TrimRight(trimmed[sepEnd:], " \t"), true
+}
+
+func isBlockStart(line string) bool {
+	_, key, _, ok := splitDirective(line)
+	return ok && (key == "host" || key == "match")
+}
+
+func isComment(line string) bool {
+	return strings.HasPrefix(strings.TrimSpace(line), "#")
+}
+
+func isBlank(line string) bool {
+	return strings.TrimSpace(line) == ""
+}
+
+// findHostBlock returns the half-open line range of the Host block named name.
+// Patterns are compared the way the parser joins them, with single spaces,
+// so "Host a  b" is found as "a b". Comments directly above the next block
+// belong to that block and trailing blank lines are not part of the range.
+func findHostBlock(lines []string, name string) (start, end int, found bool) {
+	start = -1
+	name = strings.Join(strings.Fields(name), " ")
+	for i, line := range lines {
+		_, key, value, ok := splitDirective(line)
+		if ok && key == "host" && strings.Join(strings.Fields(value), " ") == name {
+			start = i
+			break
+		}
+	}
+	if start < 0 {
+		return 0, 0, false
+	}
+
+	end = len(lines)
+	for i := start + 1; i < len(lines); i++ {
+		if isBlockStart(lines[i]) {
+			end = i
+			break
+		}
+	}
+
+	// Leave the next block's leading comments alone
+	if end < len(lines) {
+		for end-1 > start && isComment(lines[end-1]) {
+			end--
+		}
+	}
+	for end-1 > start && isBlank(lines[end-1]) {
+		end--
+	}
+	return start, end, true
+}
+
+// headerStart returns the first line of the comment block attached directly
+// above the Host line at start (no blank line in between).
+func headerStart(lines []string, start int) int {
+	for start > 0 && isComment(lines[start-1]) {
+		start--
+	}
+	return start
+}
+
+// blockIndent returns the indentation used by the options of a block.
+func blockIndent(lines []string, start, end int) string {
+	for i := start + 1; i < end; i++ {
+		if _, _, _, ok := splitDirective(lines[i]); ok {
+			trimmed := strings.TrimLeft(lines[i], " \t")
+			return lines[i][:len(lines[i])-len(trimmed)]
+		}
+	}
+	return "    "
+}
+
+func hostExists(lines []string, name string) bool {
+	_, _, found := findHostBlock(lines, name)
+	return found
+}
+
+// UpdateHost applies changes to the Host block named name. Existing lines keep
+// their position, indentation and key spelling; new keys are appended to the
+// end of the block and keys with an empty value are removed.
+func UpdateHost(content, name string, changes []Directive) (string, error) {
+	lines := strings.Split(content, "\n")
+	start, end, found := findHostBlock(lines, name)
+	if !found {
+		return "", fmt.Errorf("%w: %s", ErrHostNotFound, name)
+	}
+	indent := blockIndent(lines, start, end)
+	for _, change := range changes {
+		if err := ValidateDirective(change.Key, change.Value); err != nil {
+			return "", err
+		}
+	}
+
+	for _, change := range changes {
+		key := strings.ToLower(change.Key)
+		value := strings.TrimSpace(change.Value)
+		replaced := false
+
+		for i := start + 1; i < end; i++ {
+			prefix, lineKey, _, ok := splitDirective(lines[i])
+			if !ok || lineKey != key {
+				continue
+			}
+			if value == "" {
+				lines = append(lines[:i], lines[i+1:]...)
+				end--
+				i--
+				continue
+			}
+			if !replaced {
+				lines[i] = prefix + value
+				replaced = true
+			}
+		}
+
+		if !replaced && value != "" {
+			newLine := indent + CanonicalKey(change.Key) + " " + value
+			lines = append(lines[:end], append([]string{newLine}, lines[end:]...)...)
+			end++
+		}
+	}
+
+	return strings.Join(lines, "\n"), nil
+}
+
+// DuplicateHost copies the Host block named name, including the comments
+// directly above it, and inserts the copy under newName right after the
+// original block.
+func DuplicateHost(content, name, newName string) (string, error) {
+	if err := ValidateAlias(newName); err != nil {
+		return "", err
+	}
+	lines := strings.Split(content, "\n")
+	start, end, found := findHostBlock(lines, name)
+	if !found {
+		return "", fmt.Errorf("%w: %s", ErrHostNotFound, name)
+	}
+	if hostExists(lines, newName) {
+		return "", fmt.Errorf("%w: %s", ErrHostExists, newName)
+	}
+
+	header := headerStart(lines, start)
+	block := make([]string, 0, end-header+1)
+	block = append(block, "")
+	block = append(block, lines[header:start]...)
+	prefix, _, _, _ := splitDirective(lines[start])
+	block = append(block, prefix+newName)
+	block = append(block, lines[start+1:end]...)
+
+	lines = append(lines[:end], append(block, lines[end:]...)...)
+	return strings.Join(lines, "\n"), nil
+}
+
+// RenameHost changes the alias of the Host block named oldName and updates
+// every ProxyJump hop in the file that refers to the old alias. The alias is
+// the first pattern of the block; any other patterns are kept.
+func RenameHost(content, oldName, newName string) (string, error) {
+	if err := ValidateAlias(newName); err != nil {
+		return "", err
+	}
+	lines := strings.Split(content, "\n")
+	start, _, found := findHostBlock(lines, oldName)
+	if !found {
+		return "", fmt.Errorf("%w: %s", ErrHostNotFound, oldName)
+	}
+	if newName != oldName && hostExists(lines, newName) {
+		return "", fmt.Errorf("%w: %s", ErrHostExists, newName)
+	}
+
+	prefix, _, value, _ := splitDirective(lines[start])
+	alias := strings.Fields(value)[0]
+	lines[start] = prefix + newName + value[len(alias):]
+
+	return RenameJumpReferences(strings.Join(lines, "\n"), alias, newName), nil
+}
+
+// RenameJumpReferences rewrites every ProxyJump hop in content that refers to
+// oldName. It is used on files other than the one holding the renamed block.
+func RenameJumpReferences(content, oldName, newName string) string {
+	lines := strings.Split(content, "\n")
+	for i, line := range lines {
+		prefix, key, value, ok := splitDirective(line)
+		if !ok || key != "proxyjump" {
+			continue
+		}
+		if renamed, changed := renameJumpHops(value, oldName, newName); changed {
+			lines[i] = prefix + renamed
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// renameJumpHops rewrites a ProxyJump value of the form
+// [user@]host[:port][,[user@]host[:port]...].
+func renameJumpHops(value, oldName, newName string) (string, bool) {
+	hops := strings.Split(value, ",")
+	changed := false
+	for i, hop := range hops {
+		user, host, port := splitJumpHop(strings.TrimSpace(hop))
+		if host != oldName {
+			continue
+		}
+		hops[i] = joinJumpHop(user, newName, port)
+		changed = true
+	}
+	return strings.Join(hops, ","), changed
+}
+
+func splitJumpHop(hop string) (user, host, port string) {
+	if at := strings.LastIndex(hop, "@"); at >= 0 {
+		user, hop = hop[:at], hop[at+1:]
+	}
+	if colon := strings.LastIndex(hop, ":"); colon >= 0 && !strings.Contains(hop[:colon], ":") {
+		hop, port = hop[:colon], hop[colon+1:]
+	}
+	return user, hop, port
+}
+
+func joinJumpHop(user, host, port string) string {
+	if user != "" {
+		host = user + "@" + host
+	}
+	if port != "" {
+		host += ":" + port
+	}
+	return host
+}
+
+// DeleteHost removes the Host block named name together with the comments
+// directly above it and one adjoining blank line.
+func DeleteHost(content, name string) (string, error) {
+	lines := strings.Split(content, "\n")
+	start, end, found := findHostBlock(lines, name)
+	if !found {
+		return "", fmt.Errorf("%w: %s", ErrHostNotFound, name)
+	}
+	start = headerStart(lines, start)
+
+	// Drop one separating blank line so we don't leave a double gap behind
+	if end < len(lines) && isBlank(lines[end]) && end+1 < len(lines) {
+		end++
+	} else if start > 0 && isBlank(lines[start-1]) {
+		start--
+	}
+
+	lines = append(lines[:start], lines[end:]...)
+	return strings.Join(lines, "\n"), nil
+}
+
+// AddHost appends a new Host block with the given options. When the file ends
+// with a catch-all "Host *" block the new block goes before it, since ssh uses
+// the first value it finds and those defaults would otherwise win.
+func AddHost(content, name string, options []Directive) (string, error) {
+	if err := ValidateAlias(name); err != nil {
+		return "", err
+	}
+	lines := strings.Split(content, "\n")
+	if hostExists(lines, name) {
+		return "", fmt.Errorf("%w: %s", ErrHostExists, name)
+	}
+
+	for _, option := range options {
+		if err := ValidateDirective(option.Key, option.Value); err != nil {
+			return "", err
+		}
+	}
+
+	block := []string{"Host " + name}
+	for _, option := range options {
+		if value := strings.TrimSpace(option.Value); value != "" {
+			block = append(block, "    "+CanonicalKey(option.Key)+" "+value)
+		}
+	}
+
+	if start, end, found := findHostBlock(lines, "*"); found && !hasBlockAfter(lines, end) {
+		at := headerStart(lines, start)
+		block = append(block, "")
+		lines = append(lines[:at], append(block, lines[at:]...)...)
+		return strings.Join(lines, "\n"), nil
+	}
+
+	// Append after the last non-blank line, separated by one blank line
+	end := len(lines)
+	for end > 0 && isBlank(lines[end-1]) {
+		end--
+	}
+	lines = lines[:end]
+	if end > 0 {
+		lines = append(lines, "")
+	}
+	lines = append(lines, block...)
+	return strings.Join(lines, "\n") + "\n", nil
+}
+
+func hasBlockAfter(lines []string, from int) bool {
+	for _, line := range lines[from:] {
+		if isBlockStart(line) {
+			return true
+		}
+	}
+	return false
+}
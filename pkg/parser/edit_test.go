@@ -0,0 +1,204 @@
+package parser
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const editFixture = `# Global settings
+Host *
+    ServerAliveInterval 60
+
+# Jump box
+Host gateway
+    HostName gw.example.com
+    User ops
+
+Host bastion
+    HostName bastion.example.com
+    ProxyJump ops@gateway:2222
+
+# App servers
+Host app
+    HostName app.example.com
+    ProxyJump gateway,bastion
+`
+
+func TestUpdateHost(t *testing.T) {
+	updated, err := UpdateHost(editFixture, "gateway", []Directive{
+		{Key: "HostName", Value: "gw2.example.com"},
+		{Key: "User", Value: ""},
+		{Key: "port", Value: "2222"},
+	})
+	if err != nil {
+		t.Fatalf("UpdateHost failed: %v", err)
+	}
+
+	expected := strings.Replace(editFixture,
+		"    HostName gw.example.com\n    User ops\n",
+		"    HostName gw2.example.com\n    Port 2222\n", 1)
+	if updated != expected {
+		t.Errorf("Unexpected result:\n%s", updated)
+	}
+}
+
+func TestUpdateHostNotFound(t *testing.T) {
+	_, err := UpdateHost(editFixture, "missing", []Directive{{Key: "User", Value: "x"}})
+	if !errors.Is(err, ErrHostNotFound) {
+		t.Errorf("Expected ErrHostNotFound, got %v", err)
+	}
+}
+
+func TestEditRejectsControlCharacters(t *testing.T) {
+	injected := "gw.example.com\nProxyCommand sh -c 'touch /tmp/pwned'"
+	if _, err := UpdateHost(editFixture, "gateway", []Directive{{Key: "HostName", Value: injected}}); err == nil {
+		t.Error("Expected UpdateHost to refuse a newline")
+	}
+	if _, err := UpdateHost(editFixture, "gateway", []Directive{{Key: "User", Value: "ops\r"}}); err == nil {
+		t.Error("Expected UpdateHost to refuse a carriage return")
+	}
+	if _, err := AddHost(editFixture, "new", []Directive{{Key: "HostName\nProxyCommand", Value: "x"}}); err == nil {
+		t.Error("Expected AddHost to refuse a newline in a key")
+	}
+	if _, err := SetHostMeta(editFixture, "gateway", Meta{Group: "ops\nProxyCommand x"}); err == nil {
+		t.Error("Expected SetHostMeta to refuse a newline in the group")
+	}
+	if _, err := SetHostMeta(editFixture, "gateway", Meta{Notes: "first\nsecond"}); err != nil {
+		t.Errorf("Expected notes to span lines, got %v", err)
+	}
+	if _, err := AddHost(editFixture, "new", []Directive{{Key: "ProxyCommand", Value: "ssh\t-W %h:%p gw"}}); err != nil {
+		t.Errorf("Expected tabs to be allowed, got %v", err)
+	}
+}
+
+func TestDuplicateHost(t *testing.T) {
+	updated, err := DuplicateHost(editFixture, "gateway", "gateway2")
+	if err != nil {
+		t.Fatalf("DuplicateHost failed: %v", err)
+	}
+
+	expected := strings.Replace(editFixture,
+		"    User ops\n",
+		"    User ops\n\n# Jump box\nHost gateway2\n    HostName gw.example.com\n    User ops\n", 1)
+	if updated != expected {
+		t.Errorf("Unexpected result:\n%s", updated)
+	}
+
+	if _, err := DuplicateHost(editFixture, "gateway", "bastion"); !errors.Is(err, ErrHostExists) {
+		t.Errorf("Expected ErrHostExists, got %v", err)
+	}
+}
+
+func TestRenameHostUpdatesProxyJump(t *testing.T) {
+	updated, err := RenameHost(editFixture, "gateway", "jump")
+	if err != nil {
+		t.Fatalf("RenameHost failed: %v", err)
+	}
+
+	if !strings.Contains(updated, "\nHost jump\n") {
+		t.Error("Expected Host line to be renamed")
+	}
+	if !strings.Contains(updated, "ProxyJump ops@jump:2222") {
+		t.Error("Expected user@host:port hop to be renamed")
+	}
+	if !strings.Contains(updated, "ProxyJump jump,bastion") {
+		t.Error("Expected first hop of chain to be renamed")
+	}
+	if !strings.Contains(updated, "# Jump box") {
+		t.Error("Expected comments to be preserved")
+	}
+
+	for _, alias := range []string{"bad name", "*", "web?", "!gateway", "a,b", "-oProxyCommand=evil"} {
+		if _, err := RenameHost(editFixture, "gateway", alias); err == nil {
+			t.Errorf("Expected an error for alias %q", alias)
+		}
+	}
+}
+
+func TestDeleteHost(t *testing.T) {
+	updated, err := DeleteHost(editFixture, "gateway")
+	if err != nil {
+		t.Fatalf("DeleteHost failed: %v", err)
+	}
+
+	expected := strings.Replace(editFixture,
+		"# Jump box\nHost gateway\n    HostName gw.example.com\n    User ops\n\n", "", 1)
+	if updated != expected {
+		t.Errorf("Unexpected result:\n%s", updated)
+	}
+
+	// Deleting the last block keeps the preceding block's trailing newline
+	updated, err = DeleteHost(editFixture, "app")
+	if err != nil {
+		t.Fatalf("DeleteHost failed: %v", err)
+	}
+	if !strings.HasSuffix(updated, "ProxyJump ops@gateway:2222\n") {
+		t.Errorf("Unexpected result:\n%s", updated)
+	}
+}
+
+func TestEditHostWithExtraWhitespace(t *testing.T) {
+	content := "Host web1  web1.internal\n    HostName 10.0.0.1\n\nHost db1\tdb1.internal\n    User postgres\n"
+	configPath := filepath.Join(t.TempDir(), "config")
+	if err := os.WriteFile(configPath, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	config := &SSHConfig{Path: configPath}
+	if err := config.Load(); err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	web, db := config.GetHosts()[0].Name, config.GetHosts()[1].Name
+
+	updated, err := UpdateHost(content, web, []Directive{{Key: "User", Value: "deploy"}})
+	if err != nil || !strings.Contains(updated, "Host web1  web1.internal\n    HostName 10.0.0.1\n    User deploy\n") {
+		t.Errorf("UpdateHost(%q) = %v:\n%s", web, err, updated)
+	}
+	if updated, err := RenameHost(content, db, "db2"); err != nil || !strings.Contains(updated, "Host db2\tdb1.internal\n") {
+		t.Errorf("RenameHost(%q) = %v:\n%s", db, err, updated)
+	}
+	if updated, err := RenameHost(content+"\nHost app\n    ProxyJump db1\n", db, "db2"); err != nil || !strings.Contains(updated, "ProxyJump db2\n") {
+		t.Errorf("Expected the alias in ProxyJump to be renamed, got %v:\n%s", err, updated)
+	}
+	if updated, err := DeleteHost(content, db); err != nil || strings.Contains(updated, "db1") {
+		t.Errorf("DeleteHost(%q) = %v:\n%s", db, err, updated)
+	}
+}
+
+func TestAddHost(t *testing.T) {
+	options := []Directive{{Key: "hostname", Value: "new.example.com"}, {Key: "User", Value: "deploy"}, {Key: "Port", Value: ""}}
+
+	// Defaults at the top stay there and the host is appended
+	updated, err := AddHost(editFixture, "new", options)
+	if err != nil {
+		t.Fatalf("AddHost failed: %v", err)
+	}
+	if expected := editFixture + "\nHost new\n    HostName new.example.com\n    User deploy\n"; updated != expected {
+		t.Errorf("Unexpected result:\n%s", updated)
+	}
+
+	// A trailing catch-all block keeps applying last
+	content := "Host web\n    HostName web.example.com\n\n# Defaults\nHost *\n    User root\n"
+	updated, err = AddHost(content, "new", options)
+	if err != nil {
+		t.Fatalf("AddHost failed: %v", err)
+	}
+	expected := "Host web\n    HostName web.example.com\n\nHost new\n    HostName new.example.com\n    User deploy\n\n# Defaults\nHost *\n    User root\n"
+	if updated != expected {
+		t.Errorf("Unexpected result:\n%s", updated)
+	}
+
+	if _, err := AddHost(editFixture, "gateway", nil); !errors.Is(err, ErrHostExists) {
+		t.Errorf("Expected ErrHostExists, got %v", err)
+	}
+	for _, alias := range []string{"bad alias", "*", "-oFoo=bar"} {
+		if _, err := AddHost("", alias, nil); err == nil {
+			t.Errorf("Expected alias %q to be rejected", alias)
+		}
+	}
+	if updated, err := AddHost("", "first", nil); err != nil || updated != "Host first\n" {
+		t.Errorf("Unexpected result for an empty file: %q, %v", updated, err)
+	}
+}
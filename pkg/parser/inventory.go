@@ -0,0 +1,257 @@
+package parser
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// InventorySchemaVersion is bumped whenever a field of the inventory changes
+// meaning or is removed. Adding fields does not change the version.
+const InventorySchemaVersion = 1
+
+// Inventory is the machine readable form of the parsed config.
+type Inventory struct {
+	SchemaVersion int             `json:"schema_version" yaml:"schema_version"`
+	Hosts         []InventoryHost `json:"hosts" yaml:"hosts"`
+}
+
+// InventoryHost describes one Host block. HostName, User, Port and Options
+// are the effective values ssh would use for the alias, taking every matching
+// Host block into account; for wildcard blocks they are the block's own.
+type InventoryHost struct {
+	Alias       string            `json:"alias" yaml:"alias"`
+	Patterns    []string          `json:"patterns" yaml:"patterns"`
+	Wildcard    bool              `json:"wildcard" yaml:"wildcard"`
+	HostName    string            `json:"hostname" yaml:"hostname"`
+	User        string            `json:"user,omitempty" yaml:"user,omitempty"`
+	Port        int               `json:"port,omitempty" yaml:"port,omitempty"` // left out for wildcard blocks without a Port
+	Options     map[string]string `json:"options,omitempty" yaml:"options,omitempty"`
+	Group       string            `json:"group,omitempty" yaml:"group,omitempty"`
+	Tags        []string          `json:"tags" yaml:"tags"`
+	Owner       string            `json:"owner,omitempty" yaml:"owner,omitempty"`
+	Notes       string            `json:"notes,omitempty" yaml:"notes,omitempty"`
+	Annotations map[string]string `json:"annotations,omitempty" yaml:"annotations,omitempty"`
+	Source      SourceLocation    `json:"source" yaml:"source"`
+}
+
+// SourceLocation points at the Host line of a block.
+type SourceLocation struct {
+	File string `json:"file" yaml:"file"`
+	Line int    `json:"line" yaml:"line"`
+}
+
+// Patterns splits the Host line into its patterns.
+func (h Host) Patterns() []string {
+	return strings.Fields(h.Name)
+}
+
+// IsWildcard reports whether every pattern of the block is a wildcard or a
+// negation, i.e. the block does not name a host you can connect to.
+func (h Host) IsWildcard() bool {
+	for _, pattern := range h.Patterns() {
+		if !strings.HasPrefix(pattern, "!") && !strings.ContainsAny(pattern, "*?") {
+			return false
+		}
+	}
+	return true
+}
+
+// Matches reports whether the block applies to alias: one of its patterns
+// matches and none of its negated patterns do.
+func (h Host) Matches(alias string) bool {
+	matched := false
+	for _, pattern := range h.Patterns() {
+		if strings.HasPrefix(pattern, "!") {
+			if matchPattern(pattern[1:], alias) {
+				return false
+			}
+			continue
+		}
+		if matchPattern(pattern, alias) {
+			matched = true
+		}
+	}
+	return matched
+}
+
+// matchPattern matches ssh_config patterns, where * matches any run of
+// characters and ? exactly one.
+func matchPattern(pattern, name string) bool {
+	for len(pattern) > 0 {
+		switch pattern[0] {
+		case '*':
+			for i := len(name); i >= 0; i-- {
+				if matchPattern(pattern[1:], name[i:]) {
+					return true
+				}
+			}
+			return false
+		case '?':
+			if name == "" {
+				return false
+			}
+			name = name[1:]
+		default:
+			if name == "" || !strings.EqualFold(pattern[:1], name[:1]) {
+				return false
+			}
+			name = name[1:]
+		}
+		pattern = pattern[1:]
+	}
+	return name == ""
+}
+
+// Effective returns the options ssh would use for alias, keyed by lowercase
+// keyword, including hostname, user and port. Like ssh, the first value found
+// in file order wins.
+func (c *SSHConfig) Effective(alias string) map[string]string {
+	values := make(map[string]string)
+	set := func(key, value string) {
+		if _, ok := values[key]; !ok && value != "" {
+			values[key] = value
+		}
+	}
+	for _, host := range c.Hosts {
+		if !host.Matches(alias) {
+			continue
+		}
+		set("hostname", host.Hostname)
+		set("user", host.User)
+		set("port", host.Port)
+		for key, value := range host.Options {
+			set(key, value)
+		}
+	}
+	return values
+}
+
+// ResolveHostname returns the address ssh connects to for alias: the
+// effective HostName with %h replaced by the alias, or the alias itself when
+// no block sets one.
+func (c *SSHConfig) ResolveHostname(alias string) string {
+	hostname := strings.ReplaceAll(c.Effective(alias)["hostname"], "%h", alias)
+	if hostname == "" {
+		return alias
+	}
+	return hostname
+}
+
+// Covers reports whether connecting to name is already described by a Host
+// block: a block uses it as an alias or as its effective HostName, or a
+// wildcard block other than a catch-all "*" matches it.
+func (c *SSHConfig) Covers(name string) bool {
+	for _, host := range c.Hosts {
+		if host.IsWildcard() {
+			if host.Matches(name) && !isCatchAll(host) {
+				return true
+			}
+			continue
+		}
+		for _, pattern := range host.Patterns() {
+			if strings.EqualFold(pattern, name) {
+				return true
+			}
+		}
+		if strings.EqualFold(c.Effective(host.Patterns()[0])["hostname"], name) {
+			return true
+		}
+	}
+	return false
+}
+
+func isCatchAll(host Host) bool {
+	for _, pattern := range host.Patterns() {
+		if pattern == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+// Inventory builds the inventory of the given hosts, which should come from
+// this config.
+func (c *SSHConfig) Inventory(hosts []Host) Inventory {
+	inventory := Inventory{SchemaVersion: InventorySchemaVersion, Hosts: make([]InventoryHost, 0, len(hosts))}
+	for _, host := range hosts {
+		inventory.Hosts = append(inventory.Hosts, c.inventoryHost(host))
+	}
+	return inventory
+}
+
+func (c *SSHConfig) inventoryHost(host Host) InventoryHost {
+	item := InventoryHost{
+		Alias:       host.Name,
+		Patterns:    host.Patterns(),
+		Wildcard:    host.IsWildcard(),
+		Group:       host.Meta.Group,
+		Tags:        host.Meta.Tags,
+		Owner:       host.Meta.Owner,
+		Notes:       host.Meta.Notes,
+		Annotations: host.Meta.Extra,
+		Source:      SourceLocation{File: host.Source, Line: host.Line},
+	}
+	if item.Tags == nil {
+		item.Tags = []string{}
+	}
+
+	var values map[string]string
+	if item.Wildcard {
+		values = map[string]string{"hostname": host.Hostname, "user": host.User, "port": host.Port}
+		for key, value := range host.Options {
+			values[key] = value
+		}
+	} else {
+		values = c.Effective(host.Patterns()[0])
+		if values["hostname"] == "" {
+			values["hostname"] = host.Patterns()[0]
+		}
+		if values["port"] == "" {
+			values["port"] = "22"
+		}
+	}
+
+	item.HostName = values["hostname"]
+	item.User = values["user"]
+	item.Port, _ = strconv.Atoi(values["port"])
+	for key, value := range values {
+		if key == "hostname" || key == "user" || key == "port" || value == "" {
+			continue
+		}
+		if item.Options == nil {
+			item.Options = make(map[string]string)
+		}
+		item.Options[key] = value
+	}
+	return item
+}
+
+// Marshal encodes the inventory as "json" or "yaml".
+func (inv Inventory) Marshal(format string) ([]byte, error) {
+	switch format {
+	case "json":
+		data, err := json.MarshalIndent(inv, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		return append(data, '\n'), nil
+	case "yaml":
+		var buf bytes.Buffer
+		encoder := yaml.NewEncoder(&buf)
+		encoder.SetIndent(2)
+		if err := encoder.Encode(inv); err != nil {
+			return nil, err
+		}
+		if err := encoder.Close(); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	default:
+		return nil, fmt.Errorf("unsupported output format %q", format)
+	}
+}
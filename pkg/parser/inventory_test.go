@@ -0,0 +1,194 @@
+package parser
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+const inventoryFixture = `Host web1 web1.internal
+    HostName 10.0.0.1
+    IdentityFile ~/.ssh/web
+
+# ohmyssh: group=prod tags=db owner=dba runbook=https://wiki/db
+Host db1
+    User postgres
+
+Host *.internal !db*
+    User ops
+    Port 2222
+
+Host *
+    User root
+    ServerAliveInterval 60
+`
+
+func loadInventoryFixture(t *testing.T) *SSHConfig {
+	t.Helper()
+	configPath := filepath.Join(t.TempDir(), "config")
+	if err := os.WriteFile(configPath, []byte(inventoryFixture), 0600); err != nil {
+		t.Fatalf("Failed to create test config file: %v", err)
+	}
+	config := &SSHConfig{Path: configPath}
+	if err := config.Load(); err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	return config
+}
+
+func TestMatchPattern(t *testing.T) {
+	cases := []struct {
+		pattern, name string
+		match         bool
+	}{
+		{"*", "anything", true},
+		{"web?", "web1", true},
+		{"web?", "web10", false},
+		{"*.internal", "web1.internal", true},
+		{"*.internal", "web1.internal.example", false},
+		{"DB*", "db1", true},
+	}
+	for _, c := range cases {
+		if got := matchPattern(c.pattern, c.name); got != c.match {
+			t.Errorf("matchPattern(%q, %q) = %v, expected %v", c.pattern, c.name, got, c.match)
+		}
+	}
+
+	host := Host{Name: "*.internal !db*"}
+	if !host.Matches("web1.internal") || host.Matches("db1.internal") || host.Matches("web1") {
+		t.Error("Unexpected result for negated pattern")
+	}
+	if !host.IsWildcard() || (Host{Name: "web1 *.web"}).IsWildcard() {
+		t.Error("Unexpected IsWildcard result")
+	}
+}
+
+func TestEffective(t *testing.T) {
+	config := loadInventoryFixture(t)
+
+	expected := map[string]string{
+		"hostname":            "10.0.0.1",
+		"user":                "ops",
+		"port":                "2222",
+		"identityfile":        "~/.ssh/web",
+		"serveraliveinterval": "60",
+	}
+	if got := config.Effective("web1.internal"); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %v, got %v", expected, got)
+	}
+
+	// The first value wins, so the catch-all User does not override db1
+	if got := config.Effective("db1")["user"]; got != "postgres" {
+		t.Errorf("Expected user postgres for db1, got %q", got)
+	}
+}
+
+func TestResolveHostname(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config")
+	content := "Host gw\n    HostName 10.0.0.9\n\nHost api.prod\n    User deploy\n\nHost *.prod\n    HostName %h.example.com\n"
+	if err := os.WriteFile(configPath, []byte(content), 0600); err != nil {
+		t.Fatalf("Failed to create test config file: %v", err)
+	}
+	config := &SSHConfig{Path: configPath}
+	if err := config.Load(); err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	// The HostName may come from another matching block and use %h
+	for alias, expected := range map[string]string{"gw": "10.0.0.9", "api.prod": "api.prod.example.com", "db1": "db1"} {
+		if got := config.ResolveHostname(alias); got != expected {
+			t.Errorf("ResolveHostname(%q) = %q, want %q", alias, got, expected)
+		}
+	}
+}
+
+func TestCovers(t *testing.T) {
+	config := loadInventoryFixture(t)
+
+	for _, name := range []string{"web1", "DB1", "10.0.0.1", "api.internal"} {
+		if !config.Covers(name) {
+			t.Errorf("Expected %s to be covered", name)
+		}
+	}
+	// Host * matches everything but does not describe a server
+	for _, name := range []string{"10.0.0.2", "db2.internal", "example.com"} {
+		if config.Covers(name) {
+			t.Errorf("Expected %s not to be covered", name)
+		}
+	}
+}
+
+func TestInventoryMarshal(t *testing.T) {
+	config := loadInventoryFixture(t)
+	inventory := config.Inventory(config.GetHosts())
+
+	if inventory.SchemaVersion != InventorySchemaVersion || len(inventory.Hosts) != 4 {
+		t.Fatalf("Unexpected inventory: %+v", inventory)
+	}
+
+	web := inventory.Hosts[0]
+	if web.Alias != "web1 web1.internal" || !reflect.DeepEqual(web.Patterns, []string{"web1", "web1.internal"}) {
+		t.Errorf("Unexpected alias and patterns: %+v", web)
+	}
+	// Effective values of web1: the *.internal block does not match "web1"
+	if web.HostName != "10.0.0.1" || web.User != "root" || web.Port != 22 || web.Wildcard {
+		t.Errorf("Unexpected effective values for web1: %+v", web)
+	}
+
+	db := inventory.Hosts[1]
+	if db.HostName != "db1" || db.User != "postgres" || db.Group != "prod" || db.Owner != "dba" {
+		t.Errorf("Unexpected values for db1: %+v", db)
+	}
+	if db.Source.Line != 6 || db.Annotations["runbook"] != "https://wiki/db" {
+		t.Errorf("Unexpected source or annotations for db1: %+v", db)
+	}
+
+	wildcard := inventory.Hosts[2]
+	if !wildcard.Wildcard || wildcard.User != "ops" || wildcard.Port != 2222 || wildcard.HostName != "" {
+		t.Errorf("Expected wildcard block to keep its own values, got %+v", wildcard)
+	}
+
+	data, err := inventory.Marshal("json")
+	if err != nil {
+		t.Fatalf("Failed to marshal JSON: %v", err)
+	}
+	var decoded map[string]interface{}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Invalid JSON: %v", err)
+	}
+	if decoded["schema_version"] != float64(InventorySchemaVersion) {
+		t.Errorf("Expected schema_version in JSON, got %v", decoded["schema_version"])
+	}
+	if !strings.Contains(string(data), `"tags": []`) {
+		t.Error("Expected hosts without tags to have an empty tags list")
+	}
+	// Host * sets no Port, so its document must not claim port 0
+	var hosts struct {
+		Hosts []map[string]interface{} `json:"hosts"`
+	}
+	json.Unmarshal(data, &hosts)
+	if _, ok := hosts.Hosts[3]["port"]; ok || hosts.Hosts[0]["port"] != float64(22) {
+		t.Errorf("Expected port only where one is known, got %v and %v", hosts.Hosts[0]["port"], hosts.Hosts[3]["port"])
+	}
+
+	data, err = inventory.Marshal("yaml")
+	if err != nil {
+		t.Fatalf("Failed to marshal YAML: %v", err)
+	}
+	var roundTrip Inventory
+	if err := yaml.Unmarshal(data, &roundTrip); err != nil {
+		t.Fatalf("Invalid YAML: %v", err)
+	}
+	if !reflect.DeepEqual(roundTrip, inventory) {
+		t.Errorf("Expected YAML to round-trip, got:\n%s", data)
+	}
+
+	if _, err := inventory.Marshal("xml"); err == nil {
+		t.Error("Expected an error for an unknown format")
+	}
+}
@@ -0,0 +1,186 @@
+package parser
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+)
+
+// ErrJumpLoop is returned for a jump chain that leads back to a host that is
+// already part of it.
+var ErrJumpLoop = errors.New("ProxyJump loop")
+
+// sshArgFlags are the ssh options that take an argument.
+const sshArgFlags = "BbcDEeFIiJLlmOoPpQRSWw"
+
+// Hop is one host on the way to a destination. A hop reached with a
+// ProxyCommand that does not run ssh only has Command set.
+type Hop struct {
+	Name    string // alias or host name as written
+	User    string
+	Port    string
+	Defined bool // a Host block describes it
+	Command string
+}
+
+func (h Hop) String() string {
+	if h.Command != "" {
+		return "ProxyCommand " + path.Base(strings.Fields(h.Command)[0])
+	}
+	return joinJumpHop(h.User, h.Name, h.Port)
+}
+
+// Missing reports whether the hop looks like an alias, not a host name or
+// address, but no Host block describes it. ssh would try to resolve it in
+// DNS, which is rarely what was meant.
+func (h Hop) Missing() bool {
+	return h.Command == "" && !h.Defined && !strings.ContainsAny(h.Name, ".:")
+}
+
+// jumpVia returns the hops in front of a host with the given effective
+// options: the ProxyJump list, the destination of a ProxyCommand running
+// ssh, or else the ProxyCommand itself.
+func jumpVia(values map[string]string) (hops []string, command string) {
+	if jump := values["proxyjump"]; jump != "" && !strings.EqualFold(jump, "none") {
+		for _, hop := range strings.Split(jump, ",") {
+			if hop = strings.TrimSpace(hop); hop != "" {
+				hops = append(hops, hop)
+			}
+		}
+		return hops, ""
+	}
+	command = values["proxycommand"]
+	if command == "" || strings.EqualFold(command, "none") {
+		return nil, ""
+	}
+	if hop, ok := proxyCommandJump(command); ok {
+		return []string{hop}, ""
+	}
+	return nil, command
+}
+
+// proxyCommandJump returns the destination of a ProxyCommand such as
+// "ssh -W %h:%p gw" or "ssh gw nc %h %p", with -l and -p folded in.
+func proxyCommandJump(command string) (string, bool) {
+	fields := strings.Fields(command)
+	if len(fields) == 0 || path.Base(fields[0]) != "ssh" {
+		return "", false
+	}
+	var user, port string
+	for i := 1; i < len(fields); i++ {
+		field := fields[i]
+		if !strings.HasPrefix(field, "-") || len(field) < 2 {
+			hopUser, host, hopPort := splitJumpHop(field)
+			if hopUser == "" {
+				hopUser = user
+			}
+			if hopPort == "" {
+				hopPort = port
+			}
+			return joinJumpHop(hopUser, host, hopPort), true
+		}
+		// Options may be grouped (-qW %h:%p) and take their argument
+		// attached (-p2222) or as the next word
+		for j := 1; j < len(field); j++ {
+			if strings.IndexByte(sshArgFlags, field[j]) < 0 {
+				continue
+			}
+			arg := field[j+1:]
+			if arg == "" && i+1 < len(fields) {
+				i++
+				arg = fields[i]
+			}
+			switch field[j] {
+			case 'l':
+				user = arg
+			case 'p':
+				port = arg
+			}
+			break
+		}
+	}
+	return "", false
+}
+
+// JumpChain returns the hops ssh passes through to reach alias, in the
+// order it connects to them and ending with alias itself. Like ssh, the
+// first jump host's own ProxyJump applies, so chains are followed; later
+// hops of a ProxyJump list are connected to in turn.
+func (c *SSHConfig) JumpChain(alias string) ([]Hop, error) {
+	return c.jumpChain(Hop{Name: alias, Defined: c.Covers(alias)}, nil)
+}
+
+func (c *SSHConfig) jumpChain(target Hop, path []string) ([]Hop, error) {
+	path, err := extendPath(path, target.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	var chain []Hop
+	hops, command := jumpVia(c.Effective(target.Name))
+	if command != "" {
+		chain = append(chain, Hop{Command: command})
+	}
+	for i, spec := range hops {
+		user, host, port := splitJumpHop(spec)
+		hop := Hop{Name: host, User: user, Port: port, Defined: c.Covers(host)}
+		if i == 0 {
+			if chain, err = c.jumpChain(hop, path); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if path, err = extendPath(path, host); err != nil {
+			return nil, err
+		}
+		chain = append(chain, hop)
+	}
+	return append(chain, target), nil
+}
+
+// extendPath adds name to the hosts visited so far, failing if it is
+// already there.
+func extendPath(path []string, name string) ([]string, error) {
+	extended := append(append([]string(nil), path...), name)
+	for _, seen := range path {
+		if strings.EqualFold(seen, name) {
+			return nil, fmt.Errorf("%w: %s", ErrJumpLoop, strings.Join(extended, " → "))
+		}
+	}
+	return extended, nil
+}
+
+// JumpGraph maps every host to the jump hosts it connects through directly,
+// from ProxyJump or from a ProxyCommand that runs ssh. Hosts without a jump
+// host are left out.
+func (c *SSHConfig) JumpGraph() map[string][]string {
+	graph := make(map[string][]string)
+	for _, host := range c.Hosts {
+		if host.IsWildcard() {
+			continue
+		}
+		hops, _ := jumpVia(c.Effective(host.Patterns()[0]))
+		for _, spec := range hops {
+			_, name, _ := splitJumpHop(spec)
+			graph[host.Name] = append(graph[host.Name], name)
+		}
+	}
+	return graph
+}
+
+// JumpUsers returns the hosts that connect through name directly, sorted.
+func (c *SSHConfig) JumpUsers(name string) []string {
+	var users []string
+	for host, hops := range c.JumpGraph() {
+		for _, hop := range hops {
+			if strings.EqualFold(hop, name) {
+				users = append(users, host)
+				break
+			}
+		}
+	}
+	sort.Strings(users)
+	return users
+}
@@ -0,0 +1,123 @@
+package parser
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const jumpFixture = `Host gateway
+    HostName gw.example.com
+
+Host bastion
+    HostName bastion.company.com
+    ProxyJump gateway
+
+Host db
+    ProxyJump ops@bastion:2222,internal.example.com
+
+Host legacy
+    ProxyCommand ssh -q -l admin -W %h:%p bastion
+
+Host corp
+    ProxyCommand nc -X connect -x proxy:8080 %h %p
+
+Host orphan
+    ProxyJump gatway
+
+Host a
+    ProxyJump b
+
+Host b
+    ProxyJump c
+
+Host c
+    ProxyJump a
+`
+
+func loadJumpFixture(t *testing.T) *SSHConfig {
+	t.Helper()
+	configPath := filepath.Join(t.TempDir(), "config")
+	if err := os.WriteFile(configPath, []byte(jumpFixture), 0600); err != nil {
+		t.Fatalf("Failed to create test config file: %v", err)
+	}
+	config := &SSHConfig{Path: configPath}
+	if err := config.Load(); err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	return config
+}
+
+func chainNames(chain []Hop) []string {
+	var names []string
+	for _, hop := range chain {
+		names = append(names, hop.String())
+	}
+	return names
+}
+
+func TestJumpChain(t *testing.T) {
+	config := loadJumpFixture(t)
+	cases := map[string][]string{
+		"gateway": {"gateway"},
+		"bastion": {"gateway", "bastion"},
+		// The first hop's own ProxyJump is followed, later hops are not
+		"db":     {"gateway", "ops@bastion:2222", "internal.example.com", "db"},
+		"legacy": {"gateway", "admin@bastion", "legacy"},
+		"corp":   {"ProxyCommand nc", "corp"},
+	}
+	for alias, expected := range cases {
+		chain, err := config.JumpChain(alias)
+		if err != nil {
+			t.Errorf("%s: JumpChain failed: %v", alias, err)
+			continue
+		}
+		if names := chainNames(chain); !reflect.DeepEqual(names, expected) {
+			t.Errorf("%s: expected %v, got %v", alias, expected, names)
+		}
+	}
+
+	chain, _ := config.JumpChain("orphan")
+	if !chain[0].Missing() || chain[1].Missing() {
+		t.Errorf("Expected only gatway to be missing, got %+v", chain)
+	}
+
+	_, err := config.JumpChain("a")
+	if !errors.Is(err, ErrJumpLoop) || !strings.Contains(err.Error(), "a → b → c → a") {
+		t.Errorf("Expected a loop error, got %v", err)
+	}
+}
+
+func TestJumpGraph(t *testing.T) {
+	config := loadJumpFixture(t)
+	graph := config.JumpGraph()
+	if !reflect.DeepEqual(graph["db"], []string{"bastion", "internal.example.com"}) {
+		t.Errorf("Unexpected edges of db: %v", graph["db"])
+	}
+	if _, ok := graph["gateway"]; ok {
+		t.Errorf("Expected gateway to have no jump host, got %v", graph["gateway"])
+	}
+	if users := config.JumpUsers("bastion"); !reflect.DeepEqual(users, []string{"db", "legacy"}) {
+		t.Errorf("Unexpected users of bastion: %v", users)
+	}
+}
+
+func TestCheckJumps(t *testing.T) {
+	config := loadJumpFixture(t)
+	var messages []string
+	for _, problem := range config.Check() {
+		messages = append(messages, problem.Host+": "+problem.Message)
+	}
+	expected := []string{
+		"orphan: jump host gatway has no Host entry",
+		"a: ProxyJump loop: a → b → c → a",
+		"b: ProxyJump loop: b → c → a → b",
+		"c: ProxyJump loop: c → a → b → c",
+	}
+	if !reflect.DeepEqual(messages, expected) {
+		t.Errorf("Expected %v, got %v", expected, messages)
+	}
+}
@@ -2,22 +2,34 @@ package parser
 
 import (
 	"bufio"
+	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
 )
 
+// maxIncludeDepth matches the recursion limit ssh applies to Include.
+const maxIncludeDepth = 16
+
 type Host struct {
 	Name     string
 	Hostname string
 	Port     string
 	User     string
 	Options  map[string]string
+	Meta     Meta
+	// InheritedGroup is set when Meta.Group comes from the name of the
+	// Include file rather than from an annotation
+	InheritedGroup bool
+	Source         string // file the Host block was read from
+	Line           int    // line number of the Host keyword in Source
 }
 
 type SSHConfig struct {
 	Hosts []Host
 	Path  string
+
+	includeProblems []Problem // Include cycles found by Load
 }
 
 func NewSSHConfig() *SSHConfig {
@@ -36,22 +48,66 @@ func getDefaultSSHConfigPath() string {
 }
 
 func (c *SSHConfig) Load() error {
-	file, err := os.Open(c.Path)
+	c.includeProblems = nil
+	hosts, err := c.loadFile(c.Path, []string{absPath(c.Path)})
 	if err != nil {
 		return err
 	}
+
+	// Replace existing hosts to prevent duplicates
+	c.Hosts = hosts
+	return nil
+}
+
+// loadFile parses the file at path. stack holds the absolute paths of the
+// files being read, the including ones first and path last.
+func (c *SSHConfig) loadFile(path string, stack []string) ([]Host, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
 	defer file.Close()
 
-	// Clear existing hosts to prevent duplicates
-	c.Hosts = make([]Host, 0)
-	
+	hosts := make([]Host, 0)
 	scanner := bufio.NewScanner(file)
 	var currentHost *Host
+	// Hosts pulled in by an Include inside the current block are added
+	// after it so the file order stays intact
+	var included []Host
+	// Annotation comments seen since the last directive or blank line
+	var annotations []map[string]string
+	lineNo := 0
+
+	flush := func() {
+		if currentHost != nil {
+			hosts = append(hosts, *currentHost)
+		}
+		hosts = append(hosts, included...)
+		included = nil
+	}
+	applyAnnotations := func(host *Host) {
+		if host != nil {
+			for _, fields := range annotations {
+				host.Meta.apply(fields)
+			}
+		}
+		annotations = nil
+	}
 
 	for scanner.Scan() {
+		lineNo++
 		line := strings.TrimSpace(scanner.Text())
-		
-		if line == "" || strings.HasPrefix(line, "#") {
+
+		if line == "" {
+			// Only annotations directly above a Host line start the next
+			// block; the editor in edit.go draws the same line
+			applyAnnotations(currentHost)
+			continue
+		}
+		if strings.HasPrefix(line, "#") {
+			if fields, ok := parseAnnotation(line); ok {
+				annotations = append(annotations, fields)
+			}
 			continue
 		}
 
@@ -63,14 +119,34 @@ func (c *SSHConfig) Load() error {
 		key := strings.ToLower(parts[0])
 		value := strings.Join(parts[1:], " ")
 
+		// Annotations above a Host or Match line belong to the new block
+		if key != "host" && key != "match" {
+			applyAnnotations(currentHost)
+		}
+
 		switch key {
 		case "host":
-			if currentHost != nil {
-				c.Hosts = append(c.Hosts, *currentHost)
-			}
+			flush()
 			currentHost = &Host{
 				Name:    value,
 				Options: make(map[string]string),
+				Source:  path,
+				Line:    lineNo,
+			}
+			applyAnnotations(currentHost)
+		case "match":
+			// Options under Match apply to whatever host the criteria
+			// select, not to the Host block above, which ends here as it
+			// does for the editor
+			flush()
+			currentHost = nil
+			applyAnnotations(nil)
+		case "include":
+			includedHosts := c.loadIncludes(value, path, lineNo, stack)
+			if currentHost != nil {
+				included = append(included, includedHosts...)
+			} else {
+				hosts = append(hosts, includedHosts...)
 			}
 		case "hostname":
 			if currentHost != nil {
@@ -91,13 +167,124 @@ func (c *SSHConfig) Load() error {
 		}
 	}
 
-	if currentHost != nil {
-		c.Hosts = append(c.Hosts, *currentHost)
+	applyAnnotations(currentHost)
+	flush()
+
+	return hosts, scanner.Err()
+}
+
+// loadIncludes parses the files matched by the patterns of an Include
+// directive at line of source. Relative patterns are resolved against the
+// directory of the main config, like ssh does for ~/.ssh/config. Unreadable
+// files are skipped, and so are files already on the stack, which would
+// otherwise be read again until the depth limit.
+func (c *SSHConfig) loadIncludes(value, source string, line int, stack []string) []Host {
+	if len(stack) > maxIncludeDepth {
+		return nil
 	}
 
-	return scanner.Err()
+	var hosts []Host
+	for _, pattern := range strings.Fields(value) {
+		pattern = ExpandHome(pattern)
+		if !filepath.IsAbs(pattern) {
+			pattern = filepath.Join(filepath.Dir(c.Path), pattern)
+		}
+
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			continue
+		}
+		for _, match := range matches {
+			abs := absPath(match)
+			if includesFile(stack, abs) {
+				c.includeProblems = append(c.includeProblems, Problem{
+					Severity: SeverityError,
+					Source:   source,
+					Line:     line,
+					Message:  fmt.Sprintf("Include of %s is a cycle, the file is already being read", match),
+				})
+				continue
+			}
+			fileHosts, err := c.loadFile(match, append(stack[:len(stack):len(stack)], abs))
+			if err != nil {
+				continue
+			}
+			group := includeGroup(match)
+			for i := range fileHosts {
+				if fileHosts[i].Meta.Group == "" {
+					fileHosts[i].Meta.Group = group
+					fileHosts[i].InheritedGroup = true
+				}
+			}
+			hosts = append(hosts, fileHosts...)
+		}
+	}
+	return hosts
+}
+
+func includesFile(stack []string, path string) bool {
+	for _, file := range stack {
+		if file == path {
+			return true
+		}
+	}
+	return false
+}
+
+// absPath returns path made absolute with symlinks resolved, so that a file
+// reached through a link is recognised, or path itself if that fails.
+func absPath(path string) string {
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		path = resolved
+	}
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return path
+}
+
+// includeGroup names the group for hosts from an included file after the
+// file itself, e.g. ~/.ssh/config.d/prod.conf becomes "prod".
+func includeGroup(path string) string {
+	base := filepath.Base(path)
+	return strings.TrimSuffix(base, filepath.Ext(base))
+}
+
+// ExpandHome replaces a leading ~ with the user's home directory.
+func ExpandHome(path string) string {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(homeDir, strings.TrimPrefix(path, "~"))
+}
+
+// Annotations returns the metadata written in the config for the host,
+// without a group inherited from its Include file.
+func (h Host) Annotations() Meta {
+	meta := h.Meta
+	if h.InheritedGroup {
+		meta.Group = ""
+	}
+	return meta
 }
 
 func (c *SSHConfig) GetHosts() []Host {
 	return c.Hosts
-}
\ No newline at end of file
+}
+
+// Files returns every config file that contributed hosts, main file first.
+func (c *SSHConfig) Files() []string {
+	files := []string{c.Path}
+	seen := map[string]bool{c.Path: true}
+	for _, host := range c.Hosts {
+		if host.Source != "" && !seen[host.Source] {
+			seen[host.Source] = true
+			files = append(files, host.Source)
+		}
+	}
+	return files
+}
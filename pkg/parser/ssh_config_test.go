@@ -3,6 +3,7 @@ package parser
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -106,4 +107,239 @@ func TestNewSSHConfig(t *testing.T) {
 	if config.Path == "" {
 		t.Error("NewSSHConfig should set default path")
 	}
-}
\ No newline at end of file
+}
+
+func TestSSHConfigLoadAnnotations(t *testing.T) {
+	tempDir := t.TempDir()
+	configPath := filepath.Join(tempDir, "config")
+
+	configContent := `# ohmyssh: group=stray tags=ignored
+
+# Primary database
+# ohmyssh: group=prod/db tags=postgres,critical
+Host db1
+    HostName db1.example.com
+
+Host web1
+    HostName web1.example.com
+    # ohmyssh: group=prod/web tags="nginx"
+    User www
+
+Host dev
+    HostName dev.example.com
+`
+
+	if err := os.WriteFile(configPath, []byte(configContent), 0644); err != nil {
+		t.Fatalf("Failed to create test config file: %v", err)
+	}
+
+	config := &SSHConfig{Path: configPath}
+	if err := config.Load(); err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	hosts := config.GetHosts()
+	if len(hosts) != 3 {
+		t.Fatalf("Expected 3 hosts, got %d", len(hosts))
+	}
+
+	if hosts[0].Meta.Group != "prod/db" {
+		t.Errorf("Expected group 'prod/db', got '%s'", hosts[0].Meta.Group)
+	}
+	if len(hosts[0].Meta.Tags) != 2 || hosts[0].Meta.Tags[0] != "postgres" || hosts[0].Meta.Tags[1] != "critical" {
+		t.Errorf("Expected tags [postgres critical], got %v", hosts[0].Meta.Tags)
+	}
+	if hosts[0].Line != 5 || hosts[0].Source != configPath {
+		t.Errorf("Expected db1 at %s:5, got %s:%d", configPath, hosts[0].Source, hosts[0].Line)
+	}
+
+	if hosts[1].Meta.Group != "prod/web" || len(hosts[1].Meta.Tags) != 1 {
+		t.Errorf("Expected annotation inside block to apply, got %+v", hosts[1].Meta)
+	}
+	if hosts[1].User != "www" {
+		t.Errorf("Expected user 'www', got '%s'", hosts[1].User)
+	}
+
+	if hosts[2].Meta.Group != "" {
+		t.Errorf("Expected no group for dev, got '%s'", hosts[2].Meta.Group)
+	}
+}
+
+func TestSSHConfigLoadInclude(t *testing.T) {
+	tempDir := t.TempDir()
+	configPath := filepath.Join(tempDir, "config")
+	includeDir := filepath.Join(tempDir, "config.d")
+
+	if err := os.Mkdir(includeDir, 0755); err != nil {
+		t.Fatalf("Failed to create include dir: %v", err)
+	}
+
+	files := map[string]string{
+		configPath: "Include config.d/*\n\nHost main\n    HostName main.example.com\n",
+		filepath.Join(includeDir, "staging.conf"): "Host stage1\n    HostName stage1.example.com\n",
+		filepath.Join(includeDir, "prod"):         "# ohmyssh: group=prod/web\nHost web\n    HostName web.example.com\n",
+	}
+	for path, content := range files {
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to create %s: %v", path, err)
+		}
+	}
+
+	config := &SSHConfig{Path: configPath}
+	if err := config.Load(); err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	hosts := config.GetHosts()
+	if len(hosts) != 3 {
+		t.Fatalf("Expected 3 hosts, got %d", len(hosts))
+	}
+
+	// Glob matches are loaded in lexical order before the main file's hosts
+	expected := []struct{ name, group, source string }{
+		{"web", "prod/web", filepath.Join(includeDir, "prod")},
+		{"stage1", "staging", filepath.Join(includeDir, "staging.conf")},
+		{"main", "", configPath},
+	}
+	for i, want := range expected {
+		if hosts[i].Name != want.name || hosts[i].Meta.Group != want.group || hosts[i].Source != want.source {
+			t.Errorf("Host %d: expected %s in group '%s' from %s, got %s in '%s' from %s",
+				i, want.name, want.group, want.source, hosts[i].Name, hosts[i].Meta.Group, hosts[i].Source)
+		}
+	}
+
+	// Only the group from the file name is inherited; an edit must not
+	// write it back as an annotation
+	if !hosts[1].InheritedGroup || hosts[1].Annotations().Group != "" || hosts[1].Meta.Group != "staging" {
+		t.Errorf("Expected stage1 to inherit its group, got %+v", hosts[1])
+	}
+	if hosts[0].InheritedGroup || hosts[0].Annotations().Group != "prod/web" {
+		t.Errorf("Expected web to keep its annotated group, got %+v", hosts[0])
+	}
+
+	if files := config.Files(); len(files) != 3 || files[0] != configPath {
+		t.Errorf("Expected main config first in Files(), got %v", files)
+	}
+}
+
+func TestSSHConfigLoadIncludeCycle(t *testing.T) {
+	tempDir := t.TempDir()
+	configPath := filepath.Join(tempDir, "config")
+	files := map[string]string{
+		configPath:                       "Include config\nInclude a.conf\n\nHost main\n    HostName main.example.com\n",
+		filepath.Join(tempDir, "a.conf"): "Include b.conf\nHost a\n    HostName a.example.com\n",
+		filepath.Join(tempDir, "b.conf"): "Include a.conf\nHost b\n    HostName b.example.com\n",
+	}
+	for path, content := range files {
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to create %s: %v", path, err)
+		}
+	}
+
+	config := &SSHConfig{Path: configPath}
+	if err := config.Load(); err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	var names []string
+	for _, host := range config.GetHosts() {
+		names = append(names, host.Name)
+	}
+	if strings.Join(names, " ") != "b a main" {
+		t.Errorf("Expected every host once, got %v", names)
+	}
+
+	problems := config.Check()
+	if len(problems) != 2 {
+		t.Fatalf("Expected 2 problems, got %v", problems)
+	}
+	if p := problems[0]; p.Source != configPath || p.Line != 1 || p.Severity != SeverityError || !strings.Contains(p.Message, "cycle") {
+		t.Errorf("Unexpected problem for the self include: %s", p)
+	}
+	if p := problems[1]; p.Source != filepath.Join(tempDir, "b.conf") || p.Line != 1 {
+		t.Errorf("Unexpected problem for the mutual include: %s", p)
+	}
+	if s := problems[0].String(); s != configPath+":1: error: Include of "+configPath+" is a cycle, the file is already being read" {
+		t.Errorf("Unexpected message: %s", s)
+	}
+
+	// Loading again must not report the cycles twice
+	config.Load()
+	if len(config.Check()) != 2 {
+		t.Errorf("Expected the problems of the last load only")
+	}
+}
+
+func TestSSHConfigLoadAnnotationPlacement(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config")
+	configContent := `Host web1
+    HostName web1.example.com
+# ohmyssh: group=web
+
+# ohmyssh: group=db
+Host db1
+    HostName db1.example.com
+`
+	if err := os.WriteFile(configPath, []byte(configContent), 0644); err != nil {
+		t.Fatalf("Failed to create test config file: %v", err)
+	}
+	config := &SSHConfig{Path: configPath}
+	if err := config.Load(); err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	// Followed by a blank line, an annotation ends the block above it;
+	// directly above a Host line it starts the next block
+	hosts := config.GetHosts()
+	if hosts[0].Meta.Group != "web" || hosts[1].Meta.Group != "db" {
+		t.Fatalf("Expected groups web and db, got %q and %q", hosts[0].Meta.Group, hosts[1].Meta.Group)
+	}
+
+	// Editing agrees on which block an annotation belongs to
+	updated, err := SetHostMeta(configContent, "web1", Meta{Group: "frontend"})
+	if err != nil {
+		t.Fatalf("SetHostMeta failed: %v", err)
+	}
+	if strings.Contains(updated, "group=web\n") || !strings.Contains(updated, "group=frontend") || !strings.Contains(updated, "# ohmyssh: group=db\nHost db1") {
+		t.Errorf("Unexpected result:\n%s", updated)
+	}
+}
+
+func TestSSHConfigLoadMatch(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config")
+	configContent := `Host db1
+    HostName db1.example.com
+
+# ohmyssh: group=ci
+Match exec "test -n \"$CI\""
+    User ci
+    Port 2222
+
+Host web1
+    HostName web1.example.com
+`
+	if err := os.WriteFile(configPath, []byte(configContent), 0644); err != nil {
+		t.Fatalf("Failed to create test config file: %v", err)
+	}
+	config := &SSHConfig{Path: configPath}
+	if err := config.Load(); err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	// A Match line ends the Host block above it
+	hosts := config.GetHosts()
+	if len(hosts) != 2 || hosts[0].Name != "db1" || hosts[1].Name != "web1" {
+		t.Fatalf("Expected hosts db1 and web1, got %+v", hosts)
+	}
+	if hosts[0].User != "" || hosts[0].Port != "" || hosts[0].Meta.Group != "" {
+		t.Errorf("Expected the Match block to stay out of db1, got %+v", hosts[0])
+	}
+
+	// Editing agrees and adds the option to the Host block itself
+	updated, err := UpdateHost(configContent, "db1", []Directive{{Key: "User", Value: "postgres"}})
+	if err != nil {
+		t.Fatalf("UpdateHost failed: %v", err)
+	}
+	if !strings.HasPrefix(updated, "Host db1\n    HostName db1.example.com\n    User postgres\n\n# ohmyssh: group=ci\nMatch") {
+		t.Errorf("Unexpected result:\n%s", updated)
+	}
+}
@@ -0,0 +1,164 @@
+package probe
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// msgKexInit is the SSH message that lists the algorithms a side supports.
+const msgKexInit = 20
+
+// recordLimit caps how much of the server's output is kept to find its
+// version and KEXINIT message; both come first and are a few KB at most.
+const recordLimit = 64 * 1024
+
+// errKeyReceived stops the handshake once the server has proven its host
+// key, before any authentication.
+var errKeyReceived = errors.New("host key received")
+
+// Handshake is what an SSH server reveals before login: its version, the
+// host key algorithms it offers and the host key it presented.
+type Handshake struct {
+	Address           string
+	Version           string
+	HostKeyAlgorithms []string
+	KeyType           string
+	Key               string // base64, as in known_hosts
+	Fingerprint       string
+}
+
+// recordingConn keeps a copy of what is read from the connection.
+type recordingConn struct {
+	net.Conn
+	read bytes.Buffer
+}
+
+func (c *recordingConn) Read(p []byte) (int, error) {
+	n, err := c.Conn.Read(p)
+	if room := recordLimit - c.read.Len(); room > 0 {
+		if n < room {
+			room = n
+		}
+		c.read.Write(p[:room])
+	}
+	return n, err
+}
+
+// hostKeyAlgorithms orders the supported host key algorithms so that those
+// of preferred key types come first, like ssh does for keys it already
+// knows, so that the server presents a key that can be compared.
+func hostKeyAlgorithms(preferred []string) []string {
+	supported := ssh.SupportedAlgorithms().HostKeys
+	var first, rest []string
+	for _, algorithm := range supported {
+		keyType := algorithm
+		if algorithm == ssh.KeyAlgoRSASHA256 || algorithm == ssh.KeyAlgoRSASHA512 {
+			keyType = ssh.KeyAlgoRSA
+		}
+		matched := false
+		for _, want := range preferred {
+			if want == keyType {
+				matched = true
+			}
+		}
+		if matched {
+			first = append(first, algorithm)
+		} else {
+			rest = append(rest, algorithm)
+		}
+	}
+	return append(first, rest...)
+}
+
+// ProbeHandshake connects to address and runs the SSH handshake up to the
+// key exchange, then hangs up without authenticating. Host keys of the
+// preferred types, e.g. those already in known_hosts, are asked for first.
+func ProbeHandshake(ctx context.Context, address string, timeout time.Duration, preferred []string) (Handshake, error) {
+	result := Handshake{Address: address}
+	dialer := &net.Dialer{Timeout: timeout}
+	raw, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return result, err
+	}
+	defer raw.Close()
+	raw.SetDeadline(time.Now().Add(timeout))
+	conn := &recordingConn{Conn: raw}
+
+	var key ssh.PublicKey
+	config := &ssh.ClientConfig{
+		HostKeyAlgorithms: hostKeyAlgorithms(preferred),
+		HostKeyCallback: func(hostname string, remote net.Addr, offered ssh.PublicKey) error {
+			key = offered
+			return errKeyReceived
+		},
+	}
+	_, _, _, err = ssh.NewClientConn(conn, address, config)
+
+	result.Version, result.HostKeyAlgorithms = parseServerHello(conn.read.Bytes())
+	if key == nil {
+		if err == nil {
+			err = errors.New("server sent no host key")
+		}
+		if result.Version == "" {
+			return result, fmt.Errorf("not an SSH server: %v", err)
+		}
+		return result, fmt.Errorf("handshake failed: %v", err)
+	}
+	result.KeyType = key.Type()
+	result.Key = base64.StdEncoding.EncodeToString(key.Marshal())
+	result.Fingerprint = ssh.FingerprintSHA256(key)
+	return result, nil
+}
+
+// parseServerHello reads the server's version line and the host key
+// algorithms from its KEXINIT message, the first packet after the version.
+// Servers may send other lines before the version, which are skipped.
+func parseServerHello(data []byte) (version string, algorithms []string) {
+	for len(data) > 0 {
+		end := bytes.IndexByte(data, '\n')
+		if end < 0 {
+			return "", nil
+		}
+		line := strings.TrimRight(string(data[:end]), "\r")
+		data = data[end+1:]
+		if strings.HasPrefix(line, "SSH-") {
+			version = line
+			break
+		}
+	}
+
+	// uint32 packet length, byte padding length, then the payload
+	if len(data) < 6 || data[5] != msgKexInit {
+		return version, nil
+	}
+	payload := data[6:]
+	if len(payload) < 16 {
+		return version, nil
+	}
+	payload = payload[16:] // cookie
+	for i := 0; i < 2; i++ {
+		if len(payload) < 4 {
+			return version, nil
+		}
+		size := int(binary.BigEndian.Uint32(payload))
+		if len(payload) < 4+size {
+			return version, nil
+		}
+		// The first list is the key exchange methods, the second the host
+		// key algorithms
+		if i == 1 && size > 0 {
+			algorithms = strings.Split(string(payload[4:4+size]), ",")
+		}
+		payload = payload[4+size:]
+	}
+	return version, algorithms
+}
@@ -0,0 +1,99 @@
+package probe
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// startSSHServer serves the SSH handshake with the given host keys. No
+// client ever logs in.
+func startSSHServer(t *testing.T, keys ...interface{}) (string, []ssh.Signer) {
+	t.Helper()
+	config := &ssh.ServerConfig{NoClientAuth: true, ServerVersion: "SSH-2.0-TestServer_1.0"}
+	var signers []ssh.Signer
+	for _, key := range keys {
+		signer, err := ssh.NewSignerFromKey(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		config.AddHostKey(signer)
+		signers = append(signers, signer)
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				ssh.NewServerConn(conn, config)
+				conn.Close()
+			}()
+		}
+	}()
+	return listener.Addr().String(), signers
+}
+
+func TestProbeHandshake(t *testing.T) {
+	_, edKey, _ := ed25519.GenerateKey(rand.Reader)
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	address, signers := startSSHServer(t, edKey, ecKey)
+
+	result, err := ProbeHandshake(context.Background(), address, 5*time.Second, nil)
+	if err != nil {
+		t.Fatalf("ProbeHandshake failed: %v", err)
+	}
+	if result.Version != "SSH-2.0-TestServer_1.0" {
+		t.Errorf("Unexpected version %q", result.Version)
+	}
+	algorithms := strings.Join(result.HostKeyAlgorithms, ",")
+	if !strings.Contains(algorithms, ssh.KeyAlgoED25519) || !strings.Contains(algorithms, ssh.KeyAlgoECDSA256) {
+		t.Errorf("Expected both host key algorithms, got %v", result.HostKeyAlgorithms)
+	}
+	if result.Fingerprint == "" || result.Key == "" {
+		t.Errorf("Expected a host key, got %+v", result)
+	}
+
+	// A known key type is asked for first
+	result, err = ProbeHandshake(context.Background(), address, 5*time.Second, []string{ssh.KeyAlgoECDSA256})
+	if err != nil {
+		t.Fatalf("ProbeHandshake failed: %v", err)
+	}
+	if result.KeyType != ssh.KeyAlgoECDSA256 || result.Fingerprint != ssh.FingerprintSHA256(signers[1].PublicKey()) {
+		t.Errorf("Expected the ECDSA key, got %+v", result)
+	}
+}
+
+func TestProbeHandshakeNotSSH(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	go func() {
+		conn, err := listener.Accept()
+		if err == nil {
+			conn.Write([]byte("HTTP/1.1 400 Bad Request\r\n\r\n"))
+			conn.Close()
+		}
+	}()
+
+	if _, err := ProbeHandshake(context.Background(), listener.Addr().String(), 5*time.Second, nil); err == nil || !strings.Contains(err.Error(), "not an SSH server") {
+		t.Errorf("Expected a not an SSH server error, got %v", err)
+	}
+}
@@ -0,0 +1,88 @@
+// Package probe checks whether hosts accept TCP connections, with a limit
+// on how many dials run at the same time.
+package probe
+
+import (
+	"context"
+	"net"
+	"sync"
+	"time"
+)
+
+// Target is a host to check: a name to report it under and the address to
+// dial as host:port.
+type Target struct {
+	Name    string
+	Address string
+}
+
+// Result is the outcome of one check. Latency is the time the TCP handshake
+// took and is only set when Up.
+type Result struct {
+	Name    string
+	Address string
+	Up      bool
+	Latency time.Duration
+	Err     error
+	Checked time.Time
+}
+
+// Prober dials targets with a timeout. It is safe for concurrent use; at
+// most the configured number of dials are in flight at once.
+type Prober struct {
+	timeout time.Duration
+	sem     chan struct{}
+	dial    func(ctx context.Context, network, address string) (net.Conn, error)
+}
+
+// New returns a prober that gives up on a dial after timeout and runs at
+// most concurrency dials at a time.
+func New(timeout time.Duration, concurrency int) *Prober {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	dialer := &net.Dialer{}
+	return &Prober{timeout: timeout, sem: make(chan struct{}, concurrency), dial: dialer.DialContext}
+}
+
+// Check dials target once, waiting for a free slot first.
+func (p *Prober) Check(ctx context.Context, target Target) Result {
+	result := Result{Name: target.Name, Address: target.Address}
+	select {
+	case p.sem <- struct{}{}:
+		defer func() { <-p.sem }()
+	case <-ctx.Done():
+		result.Err = ctx.Err()
+		result.Checked = time.Now()
+		return result
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+	start := time.Now()
+	conn, err := p.dial(ctx, "tcp", target.Address)
+	result.Checked = time.Now()
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	conn.Close()
+	result.Up = true
+	result.Latency = result.Checked.Sub(start)
+	return result
+}
+
+// CheckAll checks every target and returns the results in the same order.
+func (p *Prober) CheckAll(ctx context.Context, targets []Target) []Result {
+	results := make([]Result, len(targets))
+	var wg sync.WaitGroup
+	for i, target := range targets {
+		wg.Add(1)
+		go func(i int, target Target) {
+			defer wg.Done()
+			results[i] = p.Check(ctx, target)
+		}(i, target)
+	}
+	wg.Wait()
+	return results
+}
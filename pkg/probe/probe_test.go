@@ -0,0 +1,109 @@
+package probe
+
+import (
+	"context"
+	"net"
+	"sync"
+	"testing"
+	"time"
+)
+
+// listen starts a local listener that accepts and closes connections.
+func listen(t *testing.T) string {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+	return listener.Addr().String()
+}
+
+// closedAddress returns an address nothing listens on.
+func closedAddress(t *testing.T) string {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	address := listener.Addr().String()
+	listener.Close()
+	return address
+}
+
+func TestCheckAll(t *testing.T) {
+	up := listen(t)
+	down := closedAddress(t)
+	prober := New(time.Second, 4)
+
+	results := prober.CheckAll(context.Background(), []Target{{"web", up}, {"db", down}})
+	if len(results) != 2 {
+		t.Fatalf("Expected 2 results, got %+v", results)
+	}
+	if !results[0].Up || results[0].Name != "web" || results[0].Latency <= 0 || results[0].Err != nil {
+		t.Errorf("Expected web to be up, got %+v", results[0])
+	}
+	if results[1].Up || results[1].Name != "db" || results[1].Err == nil {
+		t.Errorf("Expected db to be down, got %+v", results[1])
+	}
+}
+
+func TestCheckTimeout(t *testing.T) {
+	prober := New(50*time.Millisecond, 1)
+	prober.dial = func(ctx context.Context, network, address string) (net.Conn, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+
+	start := time.Now()
+	result := prober.Check(context.Background(), Target{"slow", "192.0.2.1:22"})
+	if result.Up || result.Err == nil {
+		t.Errorf("Expected a timeout, got %+v", result)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected the dial to give up after the timeout, took %v", elapsed)
+	}
+}
+
+func TestConcurrencyLimit(t *testing.T) {
+	address := listen(t)
+	prober := New(time.Second, 2)
+	var mu sync.Mutex
+	running, peak := 0, 0
+	dialer := &net.Dialer{}
+	prober.dial = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		mu.Lock()
+		running++
+		if running > peak {
+			peak = running
+		}
+		mu.Unlock()
+		time.Sleep(20 * time.Millisecond)
+		mu.Lock()
+		running--
+		mu.Unlock()
+		return dialer.DialContext(ctx, network, addr)
+	}
+
+	targets := make([]Target, 8)
+	for i := range targets {
+		targets[i] = Target{Name: "host", Address: address}
+	}
+	for _, result := range prober.CheckAll(context.Background(), targets) {
+		if !result.Up {
+			t.Errorf("Expected all targets to be up, got %+v", result)
+		}
+	}
+	if peak != 2 {
+		t.Errorf("Expected at most 2 concurrent dials, got %d", peak)
+	}
+}
@@ -0,0 +1,170 @@
+// Package remote runs one-off commands on hosts through ssh, without a
+// terminal, and captures what they print.
+package remote
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os/exec"
+	"sync"
+	"time"
+)
+
+// MaxOutput is how much of stdout and of stderr is kept per command; the
+// rest is dropped so that a runaway command cannot use up memory.
+const MaxOutput = 1 << 20
+
+// SSHFailure is the exit code of ssh when it failed itself, e.g. because it
+// could not connect or log in, rather than the command failing.
+const SSHFailure = 255
+
+// Result is the outcome of a command on one host.
+type Result struct {
+	Host      string
+	Command   string
+	Stdout    string
+	Stderr    string
+	Truncated bool // output beyond MaxOutput was dropped
+	ExitCode  int  // -1 when the command did not finish
+	Started   time.Time
+	Duration  time.Duration
+	Err       error // ssh could not be started or the run was cancelled
+}
+
+// OK reports whether the command ran and exited with 0.
+func (r Result) OK() bool {
+	return r.Err == nil && r.ExitCode == 0
+}
+
+// Runner runs commands with an ssh binary.
+type Runner struct {
+	SSH string // ssh binary, "ssh" from PATH if empty
+}
+
+// NewRunner returns a runner using ssh from PATH.
+func NewRunner() *Runner {
+	return &Runner{SSH: "ssh"}
+}
+
+// Args returns the ssh arguments that run command on alias. Nobody could
+// answer a password or host key prompt, so ssh fails instead of asking.
+func Args(alias, command string) []string {
+	return []string{"-o", "BatchMode=yes", alias, "--", command}
+}
+
+// Run runs command on alias and waits for it, or until ctx is done.
+func (r *Runner) Run(ctx context.Context, alias, command string) Result {
+	return r.run(ctx, alias, command, nil)
+}
+
+// Update is the progress of a broadcast on one host: output as it arrives,
+// and the result once the host is done.
+type Update struct {
+	Host   string
+	Stdout string // output since the previous update
+	Stderr string
+	Done   bool
+	Result Result // set when Done
+}
+
+// Broadcast runs command on all hosts, at most concurrency at a time, and
+// sends their output to updates as it arrives. Every host ends with a Done
+// update, also when ctx is cancelled before it started. updates is closed
+// once all hosts are done.
+func (r *Runner) Broadcast(ctx context.Context, hosts []string, command string, concurrency int, updates chan<- Update) {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for _, host := range hosts {
+		wg.Add(1)
+		go func(host string) {
+			defer wg.Done()
+			select {
+			case sem <- struct{}{}:
+				defer func() { <-sem }()
+			case <-ctx.Done():
+				updates <- Update{Host: host, Done: true, Result: Result{Host: host, Command: command, ExitCode: -1, Err: ctx.Err()}}
+				return
+			}
+			result := r.run(ctx, host, command, func(stdout, stderr string) {
+				updates <- Update{Host: host, Stdout: stdout, Stderr: stderr}
+			})
+			updates <- Update{Host: host, Done: true, Result: result}
+		}(host)
+	}
+	wg.Wait()
+	close(updates)
+}
+
+// run runs command on alias, passing output to progress as it is written
+// when progress is set.
+func (r *Runner) run(ctx context.Context, alias, command string, progress func(stdout, stderr string)) Result {
+	binary := r.SSH
+	if binary == "" {
+		binary = "ssh"
+	}
+	result := Result{Host: alias, Command: command, ExitCode: -1, Started: time.Now()}
+	stdout := &limitedBuffer{limit: MaxOutput}
+	stderr := &limitedBuffer{limit: MaxOutput}
+	if progress != nil {
+		stdout.progress = func(p string) { progress(p, "") }
+		stderr.progress = func(p string) { progress("", p) }
+	}
+	cmd := exec.CommandContext(ctx, binary, Args(alias, command)...)
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	// Do not wait for children of ssh that keep the output open
+	cmd.WaitDelay = time.Second
+
+	err := cmd.Run()
+	result.Duration = time.Since(result.Started)
+	result.Stdout = stdout.String()
+	result.Stderr = stderr.String()
+	result.Truncated = stdout.truncated || stderr.truncated
+
+	var exitErr *exec.ExitError
+	switch {
+	case ctx.Err() != nil:
+		result.Err = ctx.Err()
+	case errors.As(err, &exitErr):
+		result.ExitCode = exitErr.ExitCode()
+	case err != nil:
+		result.Err = err
+	default:
+		result.ExitCode = 0
+	}
+	return result
+}
+
+// limitedBuffer keeps the first limit bytes written to it and drops the
+// rest, without failing the writer. The buffer is not embedded, so that
+// io.Copy cannot bypass Write through its ReadFrom.
+type limitedBuffer struct {
+	buf       bytes.Buffer
+	limit     int
+	truncated bool
+	progress  func(string) // called with what is kept of each write
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	kept := p
+	if room := b.limit - b.buf.Len(); len(p) > room {
+		b.truncated = true
+		if room < 0 {
+			room = 0
+		}
+		kept = p[:room]
+	}
+	b.buf.Write(kept)
+	if b.progress != nil && len(kept) > 0 {
+		b.progress(string(kept))
+	}
+	return len(p), nil
+}
+
+func (b *limitedBuffer) String() string {
+	return b.buf.String()
+}
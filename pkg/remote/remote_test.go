@@ -0,0 +1,140 @@
+//go:build !windows
+
+package remote
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeSSH writes a shell script standing in for ssh and returns its path.
+// The command is the last argument, as with real ssh.
+func fakeSSH(t *testing.T, body string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "ssh")
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"+body+"\n"), 0755); err != nil {
+		t.Fatalf("Failed to write fake ssh: %v", err)
+	}
+	return path
+}
+
+func TestRun(t *testing.T) {
+	r := &Runner{SSH: fakeSSH(t, `for arg; do last=$arg; done
+echo "args: $*"
+echo "running $last" >&2
+exit 3`)}
+
+	result := r.Run(context.Background(), "db1", "df -h")
+	if result.Err != nil {
+		t.Fatalf("Run: %v", result.Err)
+	}
+	if result.ExitCode != 3 || result.OK() {
+		t.Errorf("Expected exit code 3, got %d", result.ExitCode)
+	}
+	if want := "args: -o BatchMode=yes db1 -- df -h\n"; result.Stdout != want {
+		t.Errorf("Stdout = %q, want %q", result.Stdout, want)
+	}
+	if result.Stderr != "running df -h\n" {
+		t.Errorf("Stderr = %q", result.Stderr)
+	}
+	if result.Host != "db1" || result.Command != "df -h" || result.Started.IsZero() {
+		t.Errorf("Unexpected result %+v", result)
+	}
+}
+
+func TestRunSuccessAndTruncation(t *testing.T) {
+	r := &Runner{SSH: fakeSSH(t, `head -c 1100000 /dev/zero`)}
+	result := r.Run(context.Background(), "db1", "cat big")
+	if !result.OK() {
+		t.Fatalf("Expected success, got %+v", result.Err)
+	}
+	if len(result.Stdout) != MaxOutput || !result.Truncated {
+		t.Errorf("Expected output cut at %d bytes, got %d (truncated %v)", MaxOutput, len(result.Stdout), result.Truncated)
+	}
+}
+
+func TestRunCancel(t *testing.T) {
+	r := &Runner{SSH: fakeSSH(t, "exec sleep 10")}
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	result := r.Run(ctx, "db1", "sleep")
+	if result.Err == nil || result.ExitCode != -1 || result.Duration > 5*time.Second {
+		t.Errorf("Expected a cancelled run, got %+v", result)
+	}
+}
+
+func TestRunMissingBinary(t *testing.T) {
+	r := &Runner{SSH: filepath.Join(t.TempDir(), "nossh")}
+	result := r.Run(context.Background(), "db1", "uptime")
+	if result.Err == nil || !strings.Contains(result.Err.Error(), "nossh") {
+		t.Errorf("Expected an error naming the binary, got %v", result.Err)
+	}
+}
+
+func TestBroadcast(t *testing.T) {
+	// Hosts sleep briefly so that the concurrency limit shows in the timing
+	r := &Runner{SSH: fakeSSH(t, `host=$3
+echo "hello from $host"
+sleep 0.2
+case $host in bad) echo "no route" >&2; exit 255;; esac`)}
+
+	hosts := []string{"a", "b", "bad", "c"}
+	updates := make(chan Update, 16)
+	start := time.Now()
+	go r.Broadcast(context.Background(), hosts, "uptime", 2, updates)
+
+	output := make(map[string]string)
+	results := make(map[string]Result)
+	for update := range updates {
+		output[update.Host] += update.Stdout + update.Stderr
+		if update.Done {
+			if _, seen := results[update.Host]; seen {
+				t.Errorf("Got two results for %s", update.Host)
+			}
+			results[update.Host] = update.Result
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 400*time.Millisecond {
+		t.Errorf("Expected two rounds of two hosts, took only %v", elapsed)
+	}
+	if len(results) != len(hosts) {
+		t.Fatalf("Expected a result per host, got %v", results)
+	}
+	for _, host := range hosts {
+		if !strings.Contains(output[host], "hello from "+host) {
+			t.Errorf("Expected streamed output for %s, got %q", host, output[host])
+		}
+		if output[host] != results[host].Stdout+results[host].Stderr {
+			t.Errorf("Streamed output of %s differs from its result", host)
+		}
+	}
+	if !results["a"].OK() || results["bad"].ExitCode != SSHFailure {
+		t.Errorf("Unexpected exit codes: a=%d bad=%d", results["a"].ExitCode, results["bad"].ExitCode)
+	}
+}
+
+func TestBroadcastCancel(t *testing.T) {
+	r := &Runner{SSH: fakeSSH(t, "exec sleep 10")}
+	ctx, cancel := context.WithCancel(context.Background())
+	updates := make(chan Update, 16)
+	go r.Broadcast(ctx, []string{"a", "b", "c"}, "sleep", 1, updates)
+	time.AfterFunc(100*time.Millisecond, cancel)
+
+	done := 0
+	for update := range updates {
+		if update.Done {
+			done++
+			if update.Result.Err == nil {
+				t.Errorf("Expected %s to be cancelled", update.Host)
+			}
+		}
+	}
+	if done != 3 {
+		t.Errorf("Expected every host to finish, got %d", done)
+	}
+}
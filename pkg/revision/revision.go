@@ -0,0 +1,112 @@
+package revision
+
+import "time"
+
+// FileChange is the content of one file before and after a revision.
+type FileChange struct {
+	Path   string
+	Before string
+	After  string
+}
+
+// Rename is a host alias changed by a revision. Data kept by alias outside
+// the config files, like favorites and tunnels, has to follow it on undo and
+// redo.
+type Rename struct {
+	From string
+	To   string
+}
+
+// Revision is a single step made during the session. One step may touch
+// several files, e.g. a rename that updates ProxyJump references.
+type Revision struct {
+	Label   string
+	Changes []FileChange
+	Renames []Rename
+	Time    time.Time
+}
+
+// History keeps undo and redo stacks of config revisions in memory.
+type History struct {
+	undo  []Revision
+	redo  []Revision
+	limit int
+}
+
+// NewHistory creates a history that keeps at most limit undo steps.
+// A limit of zero or less means unlimited.
+func NewHistory(limit int) *History {
+	return &History{limit: limit}
+}
+
+// Record pushes a new revision and discards anything that could be redone.
+// Files that did not change are dropped from the revision.
+func (h *History) Record(rev Revision) {
+	var changes []FileChange
+	for _, change := range rev.Changes {
+		if change.Before != change.After {
+			changes = append(changes, change)
+		}
+	}
+	if len(changes) == 0 {
+		return
+	}
+	rev.Changes = changes
+
+	if rev.Time.IsZero() {
+		rev.Time = time.Now()
+	}
+
+	h.undo = append(h.undo, rev)
+	if h.limit > 0 && len(h.undo) > h.limit {
+		h.undo = h.undo[len(h.undo)-h.limit:]
+	}
+	h.redo = nil
+}
+
+// Undo pops the latest revision and makes it available to Redo. The caller
+// restores the Before content of each change.
+func (h *History) Undo() (Revision, bool) {
+	if len(h.undo) == 0 {
+		return Revision{}, false
+	}
+	rev := h.undo[len(h.undo)-1]
+	h.undo = h.undo[:len(h.undo)-1]
+	h.redo = append(h.redo, rev)
+	return rev, true
+}
+
+// Redo re-applies the most recently undone revision. The caller restores
+// the After content of each change.
+func (h *History) Redo() (Revision, bool) {
+	if len(h.redo) == 0 {
+		return Revision{}, false
+	}
+	rev := h.redo[len(h.redo)-1]
+	h.redo = h.redo[:len(h.redo)-1]
+	h.undo = append(h.undo, rev)
+	return rev, true
+}
+
+func (h *History) CanUndo() bool {
+	return len(h.undo) > 0
+}
+
+func (h *History) CanRedo() bool {
+	return len(h.redo) > 0
+}
+
+// Applied returns the revisions that can be undone, oldest first.
+func (h *History) Applied() []Revision {
+	return append([]Revision(nil), h.undo...)
+}
+
+// Undone returns the revisions that can be redone, in the order Redo would
+// apply them.
+func (h *History) Undone() []Revision {
+	undone := make([]Revision, 0, len(h.redo))
+	for i := len(h.redo) - 1; i >= 0; i-- {
+		undone = append(undone, h.redo[i])
+	}
+	return undone
+}
@@ -0,0 +1,81 @@
+package revision
+
+import "testing"
+
+func TestHistoryUndoRedo(t *testing.T) {
+	h := NewHistory(0)
+	h.Record(Revision{Label: "first", Changes: []FileChange{{Path: "config", Before: "a", After: "b"}}})
+	h.Record(Revision{Label: "second", Changes: []FileChange{{Path: "config", Before: "b", After: "c"}}})
+	h.Record(Revision{Label: "no-op", Changes: []FileChange{{Path: "config", Before: "c", After: "c"}}})
+	h.Record(Revision{Label: "partial", Changes: []FileChange{
+		{Path: "config", Before: "c", After: "c"},
+		{Path: "config.d/prod", Before: "x", After: "y"},
+	}})
+
+	applied := h.Applied()
+	if len(applied) != 3 {
+		t.Fatalf("Expected 3 applied revisions, got %d", len(applied))
+	}
+	if len(applied[2].Changes) != 1 || applied[2].Changes[0].Path != "config.d/prod" {
+		t.Errorf("Expected unchanged files to be dropped, got %+v", applied[2].Changes)
+	}
+	h.Undo()
+
+	rev, ok := h.Undo()
+	if !ok || rev.Label != "second" || rev.Changes[0].Before != "b" {
+		t.Errorf("Expected to undo 'second', got %+v", rev)
+	}
+
+	rev, ok = h.Redo()
+	if !ok || rev.Label != "second" || rev.Changes[0].After != "c" {
+		t.Errorf("Expected to redo 'second', got %+v", rev)
+	}
+
+	h.Undo()
+	h.Undo()
+	if h.CanUndo() {
+		t.Error("Expected nothing left to undo")
+	}
+	if undone := h.Undone(); len(undone) != 3 || undone[0].Label != "first" || undone[2].Label != "partial" {
+		t.Errorf("Expected redo order [first second partial], got %+v", undone)
+	}
+
+	// A new change clears the redo stack
+	h.Record(Revision{Label: "third", Changes: []FileChange{{Path: "config", Before: "a", After: "d"}}})
+	if h.CanRedo() {
+		t.Error("Expected redo stack to be cleared after a new revision")
+	}
+}
+
+func TestHistoryLimit(t *testing.T) {
+	h := NewHistory(2)
+	h.Record(Revision{Label: "1", Changes: []FileChange{{Path: "config", Before: "a", After: "b"}}})
+	h.Record(Revision{Label: "2", Changes: []FileChange{{Path: "config", Before: "b", After: "c"}}})
+	h.Record(Revision{Label: "3", Changes: []FileChange{{Path: "config", Before: "c", After: "d"}}})
+
+	applied := h.Applied()
+	if len(applied) != 2 || applied[0].Label != "2" {
+		t.Errorf("Expected oldest revision to be dropped, got %+v", applied)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	before := "Host a\n    User x\n\nHost b\n    User y\n"
+	after := "Host a\n    User z\n\nHost b\n    User y\n    Port 2\n"
+
+	diff := Diff(before, after)
+	inserted, deleted := Stat(diff)
+	if inserted != 2 || deleted != 1 {
+		t.Errorf("Expected +2 -1, got +%d -%d", inserted, deleted)
+	}
+
+	var rebuilt []string
+	for _, line := range diff {
+		if line.Op != OpDelete {
+			rebuilt = append(rebuilt, line.Text)
+		}
+	}
+	if got := len(rebuilt); got != 7 {
+		t.Errorf("Expected 7 lines after applying diff, got %d", got)
+	}
+}
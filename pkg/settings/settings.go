@@ -0,0 +1,63 @@
+// Package settings reads the preferences of the user from the ohmyssh
+// configuration file. Unlike the state, ohmyssh never writes this file.
+package settings
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Settings are the preferences read from config.yaml. Zero values mean the
+// built-in defaults.
+type Settings struct {
+	// Launch is where sessions open by default: current, tmux-window,
+	// tmux-pane, screen or terminal.
+	Launch string `yaml:"launch,omitempty"`
+	// Terminal is the command that opens a new terminal window for the
+	// terminal target, such as "alacritty -e {cmd}".
+	Terminal string `yaml:"terminal,omitempty"`
+	// Client is the program sessions connect with unless a host says
+	// otherwise: ssh, mosh, autossh or a name from Clients.
+	Client string `yaml:"client,omitempty"`
+	// Clients are custom clients by name, as command templates such as
+	// "et {user}@{hostname}:{port}".
+	Clients map[string]string `yaml:"clients,omitempty"`
+}
+
+// Dir returns the directory of the configuration file, following the XDG
+// base directory spec: $XDG_CONFIG_HOME/ohmyssh or ~/.config/ohmyssh.
+func Dir() string {
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		return filepath.Join(dir, "ohmyssh")
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(homeDir, ".config", "ohmyssh")
+}
+
+// DefaultPath returns the location of the configuration file.
+func DefaultPath() string {
+	return filepath.Join(Dir(), "config.yaml")
+}
+
+// Load reads the configuration file at path. A missing file is not an
+// error and yields the defaults.
+func Load(path string) (Settings, error) {
+	var s Settings
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return s, fmt.Errorf("failed to read settings: %v", err)
+	}
+	if err := yaml.Unmarshal(data, &s); err != nil {
+		return Settings{}, fmt.Errorf("failed to parse settings %s: %v", path, err)
+	}
+	return s, nil
+}
@@ -0,0 +1,44 @@
+package settings
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestDirFollowsXDG(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", "/tmp/xdg-config")
+	if dir := Dir(); dir != "/tmp/xdg-config/ohmyssh" {
+		t.Errorf("Expected XDG config dir, got %s", dir)
+	}
+
+	t.Setenv("XDG_CONFIG_HOME", "")
+	t.Setenv("HOME", "/home/test")
+	if path := DefaultPath(); path != "/home/test/.config/ohmyssh/config.yaml" {
+		t.Errorf("Expected fallback config path, got %s", path)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	s, err := Load(filepath.Join(dir, "missing.yaml"))
+	if err != nil || !reflect.DeepEqual(s, Settings{}) {
+		t.Errorf("Expected defaults for a missing file, got %+v, %v", s, err)
+	}
+
+	path := filepath.Join(dir, "config.yaml")
+	os.WriteFile(path, []byte("# where enter opens sessions\nlaunch: tmux-window\nterminal: kitty -- {cmd}\nclient: mosh\nclients:\n  et: et {user}@{hostname}:8080\n"), 0644)
+	s, err = Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if s.Launch != "tmux-window" || s.Terminal != "kitty -- {cmd}" || s.Client != "mosh" || s.Clients["et"] != "et {user}@{hostname}:8080" {
+		t.Errorf("Unexpected settings %+v", s)
+	}
+
+	os.WriteFile(path, []byte("launch: [oops\n"), 0644)
+	if _, err := Load(path); err == nil {
+		t.Error("Expected an error for invalid YAML")
+	}
+}
@@ -0,0 +1,121 @@
+// Package sshagent talks to a running ssh-agent to list the loaded keys and
+// add new ones.
+package sshagent
+
+import (
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+)
+
+var (
+	// ErrNoAgent is returned by Dial when SSH_AUTH_SOCK is not set.
+	ErrNoAgent = errors.New("no ssh-agent running (SSH_AUTH_SOCK is not set)")
+	// ErrPassphraseRequired is returned by AddKey for a protected key when
+	// no passphrase was given.
+	ErrPassphraseRequired = errors.New("key is protected by a passphrase")
+	// ErrWrongPassphrase is returned by AddKey when the passphrase does not
+	// decrypt the key.
+	ErrWrongPassphrase = errors.New("wrong passphrase")
+)
+
+// Identity is a key loaded in the agent.
+type Identity struct {
+	Type        string
+	Fingerprint string
+	Comment     string
+}
+
+// Client is a connection to an ssh-agent.
+type Client struct {
+	conn  net.Conn
+	agent agent.ExtendedAgent
+}
+
+// Dial connects to the agent named by SSH_AUTH_SOCK.
+func Dial() (*Client, error) {
+	path := os.Getenv("SSH_AUTH_SOCK")
+	if path == "" {
+		return nil, ErrNoAgent
+	}
+	return DialPath(path)
+}
+
+// DialPath connects to the agent listening on the unix socket at path.
+func DialPath(path string) (*Client, error) {
+	conn, err := net.Dial("unix", path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to ssh-agent: %v", err)
+	}
+	return &Client{conn: conn, agent: agent.NewClient(conn)}, nil
+}
+
+// Close closes the connection to the agent.
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
+
+// Identities lists the keys loaded in the agent.
+func (c *Client) Identities() ([]Identity, error) {
+	keys, err := c.agent.List()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list agent keys: %v", err)
+	}
+	identities := make([]Identity, 0, len(keys))
+	for _, key := range keys {
+		identities = append(identities, Identity{
+			Type:        key.Type(),
+			Fingerprint: ssh.FingerprintSHA256(key),
+			Comment:     key.Comment,
+		})
+	}
+	return identities, nil
+}
+
+// Fingerprints returns the SHA256 fingerprints of the loaded keys as a set.
+func (c *Client) Fingerprints() (map[string]bool, error) {
+	identities, err := c.Identities()
+	if err != nil {
+		return nil, err
+	}
+	loaded := make(map[string]bool, len(identities))
+	for _, identity := range identities {
+		loaded[identity.Fingerprint] = true
+	}
+	return loaded, nil
+}
+
+// AddKey loads the private key at path into the agent, decrypting it with
+// passphrase if it is protected. Like ssh-add, the path is the key comment.
+func (c *Client) AddKey(path string, passphrase []byte) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read key: %v", err)
+	}
+
+	key, err := ssh.ParseRawPrivateKey(data)
+	var missing *ssh.PassphraseMissingError
+	if errors.As(err, &missing) {
+		if len(passphrase) == 0 {
+			return ErrPassphraseRequired
+		}
+		key, err = ssh.ParseRawPrivateKeyWithPassphrase(data, passphrase)
+		if errors.Is(err, x509.IncorrectPasswordError) {
+			return ErrWrongPassphrase
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("failed to parse %s: %v", path, err)
+	}
+
+	if err := c.agent.Add(agent.AddedKey{PrivateKey: key, Comment: path}); err != nil {
+		return fmt.Errorf("failed to add key to ssh-agent: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,125 @@
+package sshagent
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"errors"
+	"io/ioutil"
+	"net"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+)
+
+// startAgent serves an in-memory keyring on a socket in a temp directory
+// and points SSH_AUTH_SOCK at it.
+func startAgent(t *testing.T) agent.Agent {
+	t.Helper()
+	keyring := agent.NewKeyring()
+	path := filepath.Join(t.TempDir(), "agent.sock")
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				agent.ServeAgent(keyring, conn)
+				conn.Close()
+			}()
+		}
+	}()
+	t.Setenv("SSH_AUTH_SOCK", path)
+	return keyring
+}
+
+func writeKey(t *testing.T, passphrase string) (string, ssh.PublicKey) {
+	t.Helper()
+	public, private, _ := ed25519.GenerateKey(rand.Reader)
+	var block *pem.Block
+	var err error
+	if passphrase != "" {
+		block, err = ssh.MarshalPrivateKeyWithPassphrase(private, "", []byte(passphrase))
+	} else {
+		block, err = ssh.MarshalPrivateKey(private, "")
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "id_ed25519")
+	if err := ioutil.WriteFile(path, pem.EncodeToMemory(block), 0600); err != nil {
+		t.Fatal(err)
+	}
+	sshPublic, err := ssh.NewPublicKey(public)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return path, sshPublic
+}
+
+func TestDialWithoutAgent(t *testing.T) {
+	t.Setenv("SSH_AUTH_SOCK", "")
+	if _, err := Dial(); !errors.Is(err, ErrNoAgent) {
+		t.Errorf("Expected ErrNoAgent, got %v", err)
+	}
+}
+
+func TestAddKey(t *testing.T) {
+	startAgent(t)
+	client, err := Dial()
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	defer client.Close()
+
+	identities, err := client.Identities()
+	if err != nil || len(identities) != 0 {
+		t.Fatalf("Expected an empty agent, got %v %v", identities, err)
+	}
+
+	path, public := writeKey(t, "")
+	if err := client.AddKey(path, nil); err != nil {
+		t.Fatalf("AddKey failed: %v", err)
+	}
+	identities, _ = client.Identities()
+	if len(identities) != 1 || identities[0].Fingerprint != ssh.FingerprintSHA256(public) || identities[0].Comment != path {
+		t.Errorf("Unexpected identities: %+v", identities)
+	}
+	loaded, _ := client.Fingerprints()
+	if !loaded[ssh.FingerprintSHA256(public)] {
+		t.Errorf("Expected the key to be loaded, got %v", loaded)
+	}
+}
+
+func TestAddKeyWithPassphrase(t *testing.T) {
+	keyring := startAgent(t)
+	client, err := Dial()
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	defer client.Close()
+
+	path, public := writeKey(t, "secret")
+	if err := client.AddKey(path, nil); !errors.Is(err, ErrPassphraseRequired) {
+		t.Errorf("Expected ErrPassphraseRequired, got %v", err)
+	}
+	if err := client.AddKey(path, []byte("wrong")); !errors.Is(err, ErrWrongPassphrase) {
+		t.Errorf("Expected ErrWrongPassphrase, got %v", err)
+	}
+	if err := client.AddKey(path, []byte("secret")); err != nil {
+		t.Fatalf("AddKey failed: %v", err)
+	}
+
+	keys, _ := keyring.List()
+	if len(keys) != 1 || ssh.FingerprintSHA256(keys[0]) != ssh.FingerprintSHA256(public) {
+		t.Errorf("Unexpected keys in agent: %v", keys)
+	}
+}
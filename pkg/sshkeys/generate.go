@@ -0,0 +1,74 @@
+package sshkeys
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// Generate creates an ed25519 key pair like ssh-keygen -t ed25519: the
+// private key at path, readable only by the owner and encrypted when
+// passphrase is set, and the public key at path.pub. Existing files are
+// never overwritten.
+func Generate(path, comment string, passphrase []byte) (Key, error) {
+	for _, file := range []string{path, path + ".pub"} {
+		if _, err := os.Lstat(file); err == nil {
+			return Key{}, fmt.Errorf("%s already exists", file)
+		}
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return Key{}, fmt.Errorf("failed to create key directory: %v", err)
+	}
+
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return Key{}, fmt.Errorf("failed to generate key: %v", err)
+	}
+	var block *pem.Block
+	if len(passphrase) > 0 {
+		block, err = ssh.MarshalPrivateKeyWithPassphrase(private, comment, passphrase)
+	} else {
+		block, err = ssh.MarshalPrivateKey(private, comment)
+	}
+	if err != nil {
+		return Key{}, fmt.Errorf("failed to encode key: %v", err)
+	}
+	sshPublic, err := ssh.NewPublicKey(public)
+	if err != nil {
+		return Key{}, fmt.Errorf("failed to encode public key: %v", err)
+	}
+	authorized := bytes.TrimSuffix(ssh.MarshalAuthorizedKey(sshPublic), []byte("\n"))
+	if comment != "" {
+		authorized = append(authorized, " "+comment...)
+	}
+	authorized = append(authorized, '\n')
+
+	if err := writeNew(path, pem.EncodeToMemory(block), 0600); err != nil {
+		return Key{}, err
+	}
+	if err := writeNew(path+".pub", authorized, 0644); err != nil {
+		os.Remove(path)
+		return Key{}, err
+	}
+	return Inspect(path)
+}
+
+// writeNew writes data to a file that must not exist yet.
+func writeNew(path string, data []byte, perm os.FileMode) error {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %v", path, err)
+	}
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		os.Remove(path)
+		return fmt.Errorf("failed to write %s: %v", path, err)
+	}
+	return file.Close()
+}
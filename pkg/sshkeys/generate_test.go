@@ -0,0 +1,55 @@
+package sshkeys
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+)
+
+func TestGenerate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys", "id_ed25519_web1")
+	key, err := Generate(path, "me@laptop", []byte("secret"))
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	if key.Type != ssh.KeyAlgoED25519 || !key.Encrypted || key.Comment != "me@laptop" {
+		t.Errorf("Unexpected key: %+v", key)
+	}
+	if key.Mode != 0600 {
+		t.Errorf("Expected 0600 permissions, got %04o", key.Mode)
+	}
+	if info, _ := os.Stat(filepath.Dir(path)); info.Mode().Perm() != 0700 {
+		t.Errorf("Expected the directory to be created with 0700, got %v", info.Mode())
+	}
+
+	data, _ := ioutil.ReadFile(path)
+	if _, err := ssh.ParsePrivateKeyWithPassphrase(data, []byte("secret")); err != nil {
+		t.Errorf("Expected the passphrase to decrypt the key: %v", err)
+	}
+	pub, _ := ioutil.ReadFile(path + ".pub")
+	if !strings.HasPrefix(string(pub), "ssh-ed25519 ") || !strings.HasSuffix(string(pub), " me@laptop\n") {
+		t.Errorf("Unexpected public key: %q", pub)
+	}
+
+	if _, err := Generate(path, "", nil); err == nil {
+		t.Error("Expected an existing key not to be overwritten")
+	}
+	if again, _ := ioutil.ReadFile(path); string(again) != string(data) {
+		t.Error("Existing key was changed")
+	}
+}
+
+func TestGenerateWithoutPassphrase(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "id_ed25519")
+	key, err := Generate(path, "", nil)
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	if key.Encrypted || key.Fingerprint == "" {
+		t.Errorf("Unexpected key: %+v", key)
+	}
+}
@@ -0,0 +1,209 @@
+// Package sshkeys inspects SSH key files: their type, size, fingerprint and
+// whether ssh will accept them.
+package sshkeys
+
+import (
+	"bytes"
+	"crypto/rsa"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+)
+
+var (
+	// ErrMissing is returned for an identity file that does not exist.
+	ErrMissing = errors.New("file does not exist")
+	// ErrUnsafePermissions is returned for a private key that others can
+	// read, which ssh refuses to use.
+	ErrUnsafePermissions = errors.New("permissions are too open")
+)
+
+// Key describes a key pair, or just one half of it. Path is the private key
+// and PublicPath the matching .pub file; either may be empty.
+type Key struct {
+	Path        string
+	PublicPath  string
+	Type        string
+	Bits        int
+	Fingerprint string
+	Comment     string
+	Encrypted   bool
+	Mode        os.FileMode // permissions of the private key
+}
+
+// Name returns the file name of the key without directory and .pub suffix.
+func (k Key) Name() string {
+	if k.Path != "" {
+		return filepath.Base(k.Path)
+	}
+	return strings.TrimSuffix(filepath.Base(k.PublicPath), ".pub")
+}
+
+// UnsafePermissions reports whether the private key is readable or writable
+// by group or others.
+func (k Key) UnsafePermissions() bool {
+	return k.Path != "" && k.Mode&0077 != 0
+}
+
+// isPrivateKey reports whether data looks like a PEM or OpenSSH private key.
+func isPrivateKey(data []byte) bool {
+	return bytes.Contains(data, []byte("-----BEGIN ")) && bytes.Contains(data, []byte("PRIVATE KEY-----"))
+}
+
+// Inspect reads the private key at path together with path.pub, if present.
+// For passphrase protected keys the public half comes from the key file's
+// unencrypted header or from the .pub file.
+func Inspect(path string) (Key, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return Key{}, err
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return Key{}, err
+	}
+	if !isPrivateKey(data) {
+		return Key{}, fmt.Errorf("%s is not a private key", path)
+	}
+	key := Key{Path: path, Mode: info.Mode().Perm()}
+
+	var public ssh.PublicKey
+	signer, err := ssh.ParsePrivateKey(data)
+	var missing *ssh.PassphraseMissingError
+	switch {
+	case err == nil:
+		public = signer.PublicKey()
+	case errors.As(err, &missing):
+		key.Encrypted = true
+		public = missing.PublicKey
+	case bytes.Contains(data, []byte("ENCRYPTED")):
+		// Encrypted PEM keys do not reveal their public half
+		key.Encrypted = true
+	default:
+		return key, fmt.Errorf("failed to parse %s: %v", path, err)
+	}
+
+	if pub, comment, pubErr := readPublicKey(path + ".pub"); pubErr == nil {
+		key.PublicPath = path + ".pub"
+		key.Comment = comment
+		if public == nil {
+			public = pub
+		}
+	}
+	if public != nil {
+		key.describe(public)
+	}
+	return key, nil
+}
+
+// InspectPublic reads a .pub file that has no private key next to it.
+func InspectPublic(path string) (Key, error) {
+	public, comment, err := readPublicKey(path)
+	if err != nil {
+		return Key{}, err
+	}
+	key := Key{PublicPath: path, Comment: comment}
+	key.describe(public)
+	return key, nil
+}
+
+func readPublicKey(path string) (ssh.PublicKey, string, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, "", err
+	}
+	public, comment, _, _, err := ssh.ParseAuthorizedKey(data)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to parse %s: %v", path, err)
+	}
+	return public, comment, nil
+}
+
+func (k *Key) describe(public ssh.PublicKey) {
+	k.Type = public.Type()
+	k.Fingerprint = ssh.FingerprintSHA256(public)
+	k.Bits = Bits(public)
+}
+
+// Bits returns the size of a public key: the modulus length for RSA keys,
+// the curve size for ECDSA and 256 for ed25519.
+func Bits(public ssh.PublicKey) int {
+	if crypto, ok := public.(ssh.CryptoPublicKey); ok {
+		if rsaKey, ok := crypto.CryptoPublicKey().(*rsa.PublicKey); ok {
+			return rsaKey.N.BitLen()
+		}
+	}
+	switch public.Type() {
+	case ssh.KeyAlgoED25519, ssh.KeyAlgoSKED25519:
+		return 256
+	case ssh.KeyAlgoECDSA256, ssh.KeyAlgoSKECDSA256:
+		return 256
+	case ssh.KeyAlgoECDSA384:
+		return 384
+	case ssh.KeyAlgoECDSA521:
+		return 521
+	case ssh.KeyAlgoDSA:
+		return 1024
+	}
+	return 0
+}
+
+// Scan lists the keys in dir: every private key, with its .pub file when
+// there is one, and .pub files without a private key. Files that are not
+// keys, such as config and known_hosts, are ignored.
+func Scan(dir string) ([]Key, error) {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var keys []Key
+	paired := make(map[string]bool)
+	for _, file := range files {
+		if file.IsDir() || strings.HasSuffix(file.Name(), ".pub") || file.Size() > 64*1024 {
+			continue
+		}
+		path := filepath.Join(dir, file.Name())
+		key, err := Inspect(path)
+		if err != nil {
+			continue
+		}
+		keys = append(keys, key)
+		paired[path+".pub"] = true
+	}
+	for _, file := range files {
+		path := filepath.Join(dir, file.Name())
+		if file.IsDir() || !strings.HasSuffix(file.Name(), ".pub") || paired[path] {
+			continue
+		}
+		if key, err := InspectPublic(path); err == nil {
+			keys = append(keys, key)
+		}
+	}
+
+	sort.Slice(keys, func(i, j int) bool { return keys[i].Name() < keys[j].Name() })
+	return keys, nil
+}
+
+// CheckIdentityFile reports why ssh could not use the private key at path:
+// ErrMissing if it does not exist and ErrUnsafePermissions if others can
+// read it.
+func CheckIdentityFile(path string) error {
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return ErrMissing
+	}
+	if err != nil {
+		return err
+	}
+	if info.Mode().Perm()&0077 != 0 {
+		return fmt.Errorf("%w (%04o)", ErrUnsafePermissions, info.Mode().Perm())
+	}
+	return nil
+}
@@ -0,0 +1,133 @@
+package sshkeys
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/pem"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// writeKey writes a private key in OpenSSH format, encrypted when
+// passphrase is set, and optionally its .pub file.
+func writeKey(t *testing.T, path string, private interface{}, passphrase string, withPub bool) ssh.PublicKey {
+	t.Helper()
+	var block *pem.Block
+	var err error
+	if passphrase != "" {
+		block, err = ssh.MarshalPrivateKeyWithPassphrase(private, "test key", []byte(passphrase))
+	} else {
+		block, err = ssh.MarshalPrivateKey(private, "test key")
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(path, pem.EncodeToMemory(block), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	signer, err := ssh.NewSignerFromKey(private)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if withPub {
+		line := bytes.TrimSuffix(ssh.MarshalAuthorizedKey(signer.PublicKey()), []byte("\n"))
+		line = append(line, " me@laptop\n"...)
+		if err := ioutil.WriteFile(path+".pub", line, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return signer.PublicKey()
+}
+
+func TestInspect(t *testing.T) {
+	dir := t.TempDir()
+	_, edKey, _ := ed25519.GenerateKey(rand.Reader)
+	public := writeKey(t, filepath.Join(dir, "id_ed25519"), edKey, "", true)
+
+	key, err := Inspect(filepath.Join(dir, "id_ed25519"))
+	if err != nil {
+		t.Fatalf("Inspect failed: %v", err)
+	}
+	if key.Type != ssh.KeyAlgoED25519 || key.Bits != 256 || key.Encrypted {
+		t.Errorf("Unexpected key: %+v", key)
+	}
+	if key.Fingerprint != ssh.FingerprintSHA256(public) || key.Comment != "me@laptop" {
+		t.Errorf("Unexpected fingerprint or comment: %+v", key)
+	}
+	if key.UnsafePermissions() {
+		t.Error("Expected 0600 to be safe")
+	}
+}
+
+func TestInspectEncrypted(t *testing.T) {
+	dir := t.TempDir()
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	public := writeKey(t, filepath.Join(dir, "deploy"), rsaKey, "secret", false)
+
+	key, err := Inspect(filepath.Join(dir, "deploy"))
+	if err != nil {
+		t.Fatalf("Inspect failed: %v", err)
+	}
+	if !key.Encrypted || key.Type != ssh.KeyAlgoRSA || key.Bits != 1024 {
+		t.Errorf("Unexpected key: %+v", key)
+	}
+	if key.Fingerprint != ssh.FingerprintSHA256(public) {
+		t.Errorf("Expected the fingerprint from the unencrypted header, got %q", key.Fingerprint)
+	}
+}
+
+func TestScan(t *testing.T) {
+	dir := t.TempDir()
+	_, edKey, _ := ed25519.GenerateKey(rand.Reader)
+	writeKey(t, filepath.Join(dir, "id_ed25519"), edKey, "", true)
+	_, otherKey, _ := ed25519.GenerateKey(rand.Reader)
+	writeKey(t, filepath.Join(dir, "work"), otherKey, "", true)
+	os.Chmod(filepath.Join(dir, "work"), 0644)
+	os.Rename(filepath.Join(dir, "work.pub"), filepath.Join(dir, "laptop.pub"))
+	ioutil.WriteFile(filepath.Join(dir, "config"), []byte("Host *\n"), 0600)
+	ioutil.WriteFile(filepath.Join(dir, "known_hosts"), []byte("github.com ssh-ed25519 AAAA\n"), 0600)
+
+	keys, err := Scan(dir)
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	if len(keys) != 3 {
+		t.Fatalf("Expected 3 keys, got %+v", keys)
+	}
+	if keys[0].Name() != "id_ed25519" || keys[0].PublicPath == "" {
+		t.Errorf("Expected id_ed25519 with its .pub, got %+v", keys[0])
+	}
+	if keys[1].Name() != "laptop" || keys[1].Path != "" || keys[1].Fingerprint == "" {
+		t.Errorf("Expected a public key without private key, got %+v", keys[1])
+	}
+	if keys[2].Name() != "work" || !keys[2].UnsafePermissions() {
+		t.Errorf("Expected work to have unsafe permissions, got %+v", keys[2])
+	}
+}
+
+func TestCheckIdentityFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "key")
+	if err := CheckIdentityFile(path); !errors.Is(err, ErrMissing) {
+		t.Errorf("Expected ErrMissing, got %v", err)
+	}
+	ioutil.WriteFile(path, []byte("x"), 0640)
+	if err := CheckIdentityFile(path); !errors.Is(err, ErrUnsafePermissions) {
+		t.Errorf("Expected ErrUnsafePermissions, got %v", err)
+	}
+	os.Chmod(path, 0400)
+	if err := CheckIdentityFile(path); err != nil {
+		t.Errorf("Expected no problem, got %v", err)
+	}
+}
@@ -0,0 +1,144 @@
+package state
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// sshErrorExitCode is what ssh exits with when the connection itself failed,
+// as opposed to passing on the exit status of the remote shell.
+const sshErrorExitCode = 255
+
+// Connection is one ssh session launched from ohmyssh.
+type Connection struct {
+	Alias    string    `json:"alias"`
+	Hostname string    `json:"hostname,omitempty"`
+	Start    time.Time `json:"start"`
+	End      time.Time `json:"end"`
+	ExitCode int       `json:"exit_code"`
+	Error    string    `json:"error,omitempty"` // set when ssh could not be started
+}
+
+// Duration is how long the session lasted.
+func (c Connection) Duration() time.Duration {
+	return c.End.Sub(c.Start)
+}
+
+// Failed reports whether the connection could not be made. A non-zero exit
+// status of the remote shell alone is not a failure.
+func (c Connection) Failed() bool {
+	return c.Error != "" || c.ExitCode == sshErrorExitCode
+}
+
+// HistoryPath returns the location of the connection log.
+func HistoryPath() string {
+	return filepath.Join(Dir(), "history.jsonl")
+}
+
+// AppendConnection adds a connection to the log at path, one JSON object per
+// line. Existing entries are never rewritten.
+func AppendConnection(path string, c Connection) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("failed to create state directory: %v", err)
+	}
+	data, err := json.Marshal(c)
+	if err != nil {
+		return fmt.Errorf("failed to encode connection: %v", err)
+	}
+
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open history: %v", err)
+	}
+	if _, err := file.Write(append(data, '\n')); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to write history: %v", err)
+	}
+	return file.Close()
+}
+
+// ReadHistory returns the logged connections, oldest first. A missing log is
+// empty and lines that cannot be parsed, such as one cut short by a crash,
+// are skipped.
+func ReadHistory(path string) ([]Connection, error) {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read history: %v", err)
+	}
+	defer file.Close()
+
+	var connections []Connection
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var c Connection
+		if err := json.Unmarshal(scanner.Bytes(), &c); err != nil || c.Alias == "" {
+			continue
+		}
+		connections = append(connections, c)
+	}
+	if err := scanner.Err(); err != nil {
+		return connections, fmt.Errorf("failed to read history: %v", err)
+	}
+	return connections, nil
+}
+
+// HostSummary aggregates the logged connections of one host.
+type HostSummary struct {
+	Alias         string
+	Hostname      string
+	Connections   int
+	Failures      int
+	LastConnected time.Time
+	TotalTime     time.Duration
+}
+
+// FailureRate is the share of connections that failed, between 0 and 1.
+func (s HostSummary) FailureRate() float64 {
+	if s.Connections == 0 {
+		return 0
+	}
+	return float64(s.Failures) / float64(s.Connections)
+}
+
+// Summarize groups connections by alias, most recently used host first.
+func Summarize(connections []Connection) []HostSummary {
+	byAlias := make(map[string]*HostSummary)
+	for _, c := range connections {
+		summary, ok := byAlias[c.Alias]
+		if !ok {
+			summary = &HostSummary{Alias: c.Alias}
+			byAlias[c.Alias] = summary
+		}
+		summary.Connections++
+		if c.Failed() {
+			summary.Failures++
+		}
+		if !c.Start.Before(summary.LastConnected) {
+			summary.LastConnected = c.Start
+			summary.Hostname = c.Hostname
+		}
+		if d := c.Duration(); d > 0 {
+			summary.TotalTime += d
+		}
+	}
+
+	summaries := make([]HostSummary, 0, len(byAlias))
+	for _, summary := range byAlias {
+		summaries = append(summaries, *summary)
+	}
+	sort.Slice(summaries, func(i, j int) bool {
+		if !summaries[i].LastConnected.Equal(summaries[j].LastConnected) {
+			return summaries[i].LastConnected.After(summaries[j].LastConnected)
+		}
+		return summaries[i].Alias < summaries[j].Alias
+	})
+	return summaries
+}
@@ -0,0 +1,80 @@
+package state
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestAppendAndReadHistory(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ohmyssh", "history.jsonl")
+
+	connections, err := ReadHistory(path)
+	if err != nil || len(connections) != 0 {
+		t.Fatalf("Expected empty history for a missing log, got %v, %v", connections, err)
+	}
+
+	start := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	entries := []Connection{
+		{Alias: "web1", Hostname: "web1.example.com", Start: start, End: start.Add(10 * time.Minute)},
+		{Alias: "db1", Hostname: "db1.example.com", Start: start.Add(time.Hour), End: start.Add(time.Hour), ExitCode: 255},
+	}
+	for _, c := range entries {
+		if err := AppendConnection(path, c); err != nil {
+			t.Fatalf("Failed to append connection: %v", err)
+		}
+	}
+
+	// A line cut short by a crash must not hide the rest of the log
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		t.Fatalf("Failed to open history: %v", err)
+	}
+	file.WriteString(`{"alias":"broken","sta` + "\n")
+	file.Close()
+	if err := AppendConnection(path, Connection{Alias: "web1", Start: start.Add(2 * time.Hour), End: start.Add(3 * time.Hour), ExitCode: 1}); err != nil {
+		t.Fatalf("Failed to append connection: %v", err)
+	}
+
+	connections, err = ReadHistory(path)
+	if err != nil {
+		t.Fatalf("Failed to read history: %v", err)
+	}
+	if len(connections) != 3 {
+		t.Fatalf("Expected 3 connections, got %d", len(connections))
+	}
+	if connections[0].Duration() != 10*time.Minute {
+		t.Errorf("Expected 10m session, got %v", connections[0].Duration())
+	}
+	if !connections[1].Failed() || connections[2].Failed() {
+		t.Error("Expected only the exit status 255 connection to count as failed")
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	start := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	summaries := Summarize([]Connection{
+		{Alias: "web1", Hostname: "old.example.com", Start: start, End: start.Add(time.Minute)},
+		{Alias: "db1", Start: start.Add(time.Hour), End: start.Add(time.Hour), Error: "exec: ssh not found"},
+		{Alias: "web1", Hostname: "web1.example.com", Start: start.Add(2 * time.Hour), End: start.Add(2*time.Hour + time.Minute), ExitCode: 255},
+		{Alias: "db1", Start: start.Add(30 * time.Minute), End: start.Add(40 * time.Minute)},
+	})
+
+	if len(summaries) != 2 {
+		t.Fatalf("Expected 2 hosts, got %d", len(summaries))
+	}
+	web := summaries[0]
+	if web.Alias != "web1" || web.Connections != 2 || web.Failures != 1 {
+		t.Errorf("Unexpected summary for web1: %+v", web)
+	}
+	if web.Hostname != "web1.example.com" || !web.LastConnected.Equal(start.Add(2*time.Hour)) {
+		t.Errorf("Expected the latest connection to win, got %+v", web)
+	}
+	if web.TotalTime != 2*time.Minute || web.FailureRate() != 0.5 {
+		t.Errorf("Unexpected totals for web1: %+v", web)
+	}
+	if db := summaries[1]; db.Alias != "db1" || db.Failures != 1 || !db.LastConnected.Equal(start.Add(time.Hour)) {
+		t.Errorf("Unexpected summary for db1: %+v", db)
+	}
+}
@@ -0,0 +1,239 @@
+// Package state stores what ohmyssh learns while it runs, such as favorite
+// hosts and how often each host is used, outside of the SSH config.
+package state
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// HostStats records how a host has been used.
+type HostStats struct {
+	Connections   int       `json:"connections"`
+	LastConnected time.Time `json:"last_connected"`
+}
+
+// Tunnel is a saved port forward of Host, opened with ssh -<Kind> <Spec>
+// where Kind is L, R or D.
+type Tunnel struct {
+	Host string `json:"host"`
+	Kind string `json:"kind"`
+	Spec string `json:"spec"`
+}
+
+// Overrides are the connect options last used for a host, field for field
+// the options of a launch.
+type Overrides struct {
+	Client       string   `json:"client,omitempty"`
+	User         string   `json:"user,omitempty"`
+	Port         string   `json:"port,omitempty"`
+	Options      []string `json:"options,omitempty"`
+	Verbose      int      `json:"verbose,omitempty"`
+	ForwardAgent bool     `json:"forward_agent,omitempty"`
+}
+
+// isZero reports whether the overrides change nothing.
+func (o Overrides) isZero() bool {
+	return o.Client == "" && o.User == "" && o.Port == "" && len(o.Options) == 0 && o.Verbose == 0 && !o.ForwardAgent
+}
+
+// State is the persisted application state. Hosts are keyed by alias.
+type State struct {
+	Favorites []string              `json:"favorites,omitempty"`
+	Hosts     map[string]*HostStats `json:"hosts,omitempty"`
+	SortMode  string                `json:"sort_mode,omitempty"`
+	Tunnels   []Tunnel              `json:"tunnels,omitempty"`
+	Commands  []string              `json:"commands,omitempty"`
+	Overrides map[string]Overrides  `json:"overrides,omitempty"` // last connect options per host
+
+	path string
+}
+
+// Dir returns the directory ohmyssh keeps its state in, following the XDG
+// base directory spec: $XDG_STATE_HOME/ohmyssh or ~/.local/state/ohmyssh.
+func Dir() string {
+	if dir := os.Getenv("XDG_STATE_HOME"); dir != "" {
+		return filepath.Join(dir, "ohmyssh")
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(homeDir, ".local", "state", "ohmyssh")
+}
+
+// DefaultPath returns the location of the state file.
+func DefaultPath() string {
+	return filepath.Join(Dir(), "state.json")
+}
+
+// Load reads the state file at path. A missing file is not an error and
+// yields an empty state that will be written to path on Save.
+func Load(path string) (*State, error) {
+	s := &State{Hosts: make(map[string]*HostStats), path: path}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return s, fmt.Errorf("failed to read state: %v", err)
+	}
+	if err := json.Unmarshal(data, s); err != nil {
+		return s, fmt.Errorf("failed to parse state %s: %v", path, err)
+	}
+	if s.Hosts == nil {
+		s.Hosts = make(map[string]*HostStats)
+	}
+	return s, nil
+}
+
+// Save writes the state atomically so a crash never leaves a truncated file.
+func (s *State) Save() error {
+	if err := os.MkdirAll(filepath.Dir(s.path), 0700); err != nil {
+		return fmt.Errorf("failed to create state directory: %v", err)
+	}
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode state: %v", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), ".state-*.json")
+	if err != nil {
+		return fmt.Errorf("failed to save state: %v", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to save state: %v", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to save state: %v", err)
+	}
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		return fmt.Errorf("failed to save state: %v", err)
+	}
+	return nil
+}
+
+// IsFavorite reports whether the host is pinned.
+func (s *State) IsFavorite(name string) bool {
+	for _, favorite := range s.Favorites {
+		if favorite == name {
+			return true
+		}
+	}
+	return false
+}
+
+// ToggleFavorite pins or unpins a host and reports whether it is now pinned.
+func (s *State) ToggleFavorite(name string) bool {
+	for i, favorite := range s.Favorites {
+		if favorite == name {
+			s.Favorites = append(s.Favorites[:i], s.Favorites[i+1:]...)
+			return false
+		}
+	}
+	s.Favorites = append(s.Favorites, name)
+	return true
+}
+
+// RecordConnection counts a connection to the host made at t.
+func (s *State) RecordConnection(name string, t time.Time) {
+	stats, ok := s.Hosts[name]
+	if !ok {
+		stats = &HostStats{}
+		s.Hosts[name] = stats
+	}
+	stats.Connections++
+	if t.After(stats.LastConnected) {
+		stats.LastConnected = t
+	}
+}
+
+// Stats returns the usage of a host; unknown hosts have zero stats.
+func (s *State) Stats(name string) HostStats {
+	if stats, ok := s.Hosts[name]; ok {
+		return *stats
+	}
+	return HostStats{}
+}
+
+// maxCommands is how many remote commands are remembered.
+const maxCommands = 20
+
+// RememberCommand puts a remote command first in the list of recent ones,
+// removing an earlier copy and the oldest beyond maxCommands.
+func (s *State) RememberCommand(command string) {
+	commands := []string{command}
+	for _, existing := range s.Commands {
+		if existing != command && len(commands) < maxCommands {
+			commands = append(commands, existing)
+		}
+	}
+	s.Commands = commands
+}
+
+// AddTunnel saves a tunnel definition and reports whether it was new.
+func (s *State) AddTunnel(t Tunnel) bool {
+	for _, existing := range s.Tunnels {
+		if existing == t {
+			return false
+		}
+	}
+	s.Tunnels = append(s.Tunnels, t)
+	return true
+}
+
+// RemoveTunnel forgets a tunnel definition.
+func (s *State) RemoveTunnel(t Tunnel) {
+	for i, existing := range s.Tunnels {
+		if existing == t {
+			s.Tunnels = append(s.Tunnels[:i], s.Tunnels[i+1:]...)
+			return
+		}
+	}
+}
+
+// LastOverrides returns the connect options last used for a host.
+func (s *State) LastOverrides(name string) Overrides {
+	return s.Overrides[name]
+}
+
+// RememberOverrides keeps the connect options used for a host; options
+// that change nothing forget them.
+func (s *State) RememberOverrides(name string, o Overrides) {
+	if o.isZero() {
+		delete(s.Overrides, name)
+		return
+	}
+	if s.Overrides == nil {
+		s.Overrides = make(map[string]Overrides)
+	}
+	s.Overrides[name] = o
+}
+
+// RenameHost moves the favorite flag, stats, tunnels and overrides of a
+// renamed host.
+func (s *State) RenameHost(oldName, newName string) {
+	for i, favorite := range s.Favorites {
+		if favorite == oldName {
+			s.Favorites[i] = newName
+		}
+	}
+	if stats, ok := s.Hosts[oldName]; ok {
+		delete(s.Hosts, oldName)
+		s.Hosts[newName] = stats
+	}
+	for i := range s.Tunnels {
+		if s.Tunnels[i].Host == oldName {
+			s.Tunnels[i].Host = newName
+		}
+	}
+	if o, ok := s.Overrides[oldName]; ok {
+		delete(s.Overrides, oldName)
+		s.Overrides[newName] = o
+	}
+}
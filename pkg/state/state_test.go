@@ -0,0 +1,176 @@
+package state
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestDirFollowsXDG(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", "/tmp/xdg-state")
+	if dir := Dir(); dir != "/tmp/xdg-state/ohmyssh" {
+		t.Errorf("Expected XDG state dir, got %s", dir)
+	}
+
+	t.Setenv("XDG_STATE_HOME", "")
+	t.Setenv("HOME", "/home/test")
+	if dir := Dir(); dir != "/home/test/.local/state/ohmyssh" {
+		t.Errorf("Expected fallback state dir, got %s", dir)
+	}
+}
+
+func TestLoadMissingFile(t *testing.T) {
+	s, err := Load(filepath.Join(t.TempDir(), "missing", "state.json"))
+	if err != nil {
+		t.Fatalf("Expected no error for a missing state file, got %v", err)
+	}
+	if len(s.Favorites) != 0 || len(s.Hosts) != 0 {
+		t.Errorf("Expected empty state, got %+v", s)
+	}
+}
+
+func TestSaveAndLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ohmyssh", "state.json")
+	s, err := Load(path)
+	if err != nil {
+		t.Fatalf("Failed to load state: %v", err)
+	}
+
+	first := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	s.RecordConnection("web1", first)
+	s.RecordConnection("web1", first.Add(time.Hour))
+	s.RecordConnection("db1", first)
+	s.ToggleFavorite("db1")
+	s.SortMode = "recent"
+	if err := s.Save(); err != nil {
+		t.Fatalf("Failed to save state: %v", err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("Expected state file to exist: %v", err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("Expected state file mode 0600, got %v", info.Mode().Perm())
+	}
+
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatalf("Failed to reload state: %v", err)
+	}
+	if !loaded.IsFavorite("db1") || loaded.IsFavorite("web1") {
+		t.Errorf("Unexpected favorites: %v", loaded.Favorites)
+	}
+	if loaded.SortMode != "recent" {
+		t.Errorf("Expected sort mode 'recent', got %q", loaded.SortMode)
+	}
+	stats := loaded.Stats("web1")
+	if stats.Connections != 2 || !stats.LastConnected.Equal(first.Add(time.Hour)) {
+		t.Errorf("Unexpected stats for web1: %+v", stats)
+	}
+}
+
+func TestToggleAndRename(t *testing.T) {
+	s := &State{Hosts: make(map[string]*HostStats)}
+	if !s.ToggleFavorite("web1") {
+		t.Error("Expected web1 to become a favorite")
+	}
+	s.RecordConnection("web1", time.Now())
+
+	s.RenameHost("web1", "web-prod")
+	if s.IsFavorite("web1") || !s.IsFavorite("web-prod") {
+		t.Errorf("Expected favorite to follow the rename, got %v", s.Favorites)
+	}
+	if s.Stats("web-prod").Connections != 1 || s.Stats("web1").Connections != 0 {
+		t.Errorf("Expected stats to follow the rename, got %v", s.Hosts)
+	}
+
+	if s.ToggleFavorite("web-prod") {
+		t.Error("Expected web-prod to be unpinned")
+	}
+}
+
+func TestLoadCorruptFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	if err := os.WriteFile(path, []byte("{not json"), 0600); err != nil {
+		t.Fatalf("Failed to write state: %v", err)
+	}
+	s, err := Load(path)
+	if err == nil {
+		t.Error("Expected an error for a corrupt state file")
+	}
+	if s == nil || s.Hosts == nil {
+		t.Error("Expected a usable empty state alongside the error")
+	}
+}
+
+func TestTunnels(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	s, _ := Load(path)
+	db := Tunnel{Host: "db1", Kind: "L", Spec: "5432:localhost:5432"}
+	if !s.AddTunnel(db) {
+		t.Error("Expected the first tunnel to be added")
+	}
+	if s.AddTunnel(db) {
+		t.Error("Expected a duplicate tunnel to be ignored")
+	}
+	s.AddTunnel(Tunnel{Host: "web1", Kind: "D", Spec: "1080"})
+	s.RenameHost("db1", "db-prod")
+	if err := s.Save(); err != nil {
+		t.Fatalf("Failed to save: %v", err)
+	}
+
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatalf("Failed to load: %v", err)
+	}
+	if len(loaded.Tunnels) != 2 || loaded.Tunnels[0].Host != "db-prod" {
+		t.Fatalf("Expected both tunnels with db1 renamed, got %v", loaded.Tunnels)
+	}
+	loaded.RemoveTunnel(loaded.Tunnels[0])
+	if len(loaded.Tunnels) != 1 || loaded.Tunnels[0].Host != "web1" {
+		t.Errorf("Expected only the web1 tunnel left, got %v", loaded.Tunnels)
+	}
+}
+
+func TestRememberCommand(t *testing.T) {
+	s, _ := Load(filepath.Join(t.TempDir(), "state.json"))
+	for i := 0; i < maxCommands+5; i++ {
+		s.RememberCommand(fmt.Sprintf("cmd %d", i))
+	}
+	s.RememberCommand("uptime")
+	s.RememberCommand("df -h")
+	s.RememberCommand("uptime")
+
+	if len(s.Commands) != maxCommands {
+		t.Fatalf("Expected %d commands, got %d", maxCommands, len(s.Commands))
+	}
+	if s.Commands[0] != "uptime" || s.Commands[1] != "df -h" || s.Commands[2] != "cmd 24" {
+		t.Errorf("Expected most recent first without duplicates, got %v", s.Commands[:3])
+	}
+}
+
+func TestOverrides(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	s, _ := Load(path)
+	s.RememberOverrides("db1", Overrides{User: "root", Verbose: 1})
+	s.RememberOverrides("web1", Overrides{Port: "2222"})
+	s.RememberOverrides("web1", Overrides{})
+	s.RenameHost("db1", "db-primary")
+	if err := s.Save(); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if got := loaded.LastOverrides("db-primary"); got.User != "root" || got.Verbose != 1 {
+		t.Errorf("Expected the overrides to follow the rename, got %+v", got)
+	}
+	if !loaded.LastOverrides("db1").isZero() || !loaded.LastOverrides("web1").isZero() {
+		t.Errorf("Expected no overrides left for db1 and web1, got %v", loaded.Overrides)
+	}
+}
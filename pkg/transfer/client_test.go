@@ -0,0 +1,107 @@
+//go:build !windows
+
+package transfer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeTool writes a shell script standing in for scp or sftp and returns
+// its path.
+func fakeTool(t *testing.T, name, body string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"+body+"\n"), 0755); err != nil {
+		t.Fatalf("Failed to write fake %s: %v", name, err)
+	}
+	return path
+}
+
+func TestCopyDownloadProgress(t *testing.T) {
+	pollInterval = 20 * time.Millisecond
+	// The last argument is the local destination; write it in two steps
+	c := &Client{SCP: fakeTool(t, "scp", `for arg; do last=$arg; done
+head -c 1000 /dev/zero > "$last/app.log"
+sleep 0.3
+head -c 2000 /dev/zero > "$last/app.log"`)}
+	dir := t.TempDir()
+	tr := Transfer{Host: "db1", Direction: Download, Local: dir, Remote: "logs/app.log", Size: 2000}
+
+	var seen []Progress
+	if err := c.Copy(context.Background(), tr, func(p Progress) { seen = append(seen, p) }); err != nil {
+		t.Fatalf("Copy: %v", err)
+	}
+	if len(seen) < 2 {
+		t.Fatalf("Expected progress while copying, got %v", seen)
+	}
+	if last := seen[len(seen)-1]; last.Done != 2000 || last.Total != 2000 {
+		t.Errorf("Expected to end complete, got %+v", last)
+	}
+	halfway := false
+	for _, p := range seen {
+		halfway = halfway || p.Done == 1000
+	}
+	if !halfway {
+		t.Errorf("Expected to see the partial download, got %v", seen)
+	}
+}
+
+func TestCopyUploadFailure(t *testing.T) {
+	c := &Client{SCP: fakeTool(t, "scp", `echo "scp: /srv/x: Permission denied" >&2
+exit 1`)}
+	local := filepath.Join(t.TempDir(), "notes.txt")
+	os.WriteFile(local, []byte("hello"), 0644)
+
+	err := c.Copy(context.Background(), Transfer{Host: "db1", Local: local, Remote: "/srv/x"}, nil)
+	if err == nil || err.Error() != "scp: /srv/x: Permission denied" {
+		t.Errorf("Expected scp's complaint, got %v", err)
+	}
+
+	err = c.Copy(context.Background(), Transfer{Host: "db1", Local: local + ".missing"}, nil)
+	if err == nil || !strings.Contains(err.Error(), "failed to read") {
+		t.Errorf("Expected a missing local file to fail, got %v", err)
+	}
+}
+
+func TestCopyCancel(t *testing.T) {
+	c := &Client{SCP: fakeTool(t, "scp", "exec sleep 10")}
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	err := c.Copy(ctx, Transfer{Host: "db1", Direction: Download, Local: t.TempDir(), Remote: "x"}, nil)
+	if err != context.DeadlineExceeded || time.Since(start) > 5*time.Second {
+		t.Errorf("Expected a cancelled copy, got %v", err)
+	}
+}
+
+func TestList(t *testing.T) {
+	script := filepath.Join(t.TempDir(), "script")
+	c := &Client{SFTP: fakeTool(t, "sftp", `cat > `+script+`
+echo "sftp> pwd"
+echo "Remote working directory: /var/log"
+echo "sftp> ls -la"
+echo "-rw-r--r--    1 root     root          42 Mar  3 10:12 syslog"`)}
+
+	listing, err := c.List(context.Background(), "db1", `/var/"log"`)
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if listing.Dir != "/var/log" || len(listing.Entries) != 1 || listing.Entries[0].Name != "syslog" {
+		t.Errorf("Unexpected listing %+v", listing)
+	}
+	got, _ := os.ReadFile(script)
+	if want := "cd \"/var/\\\"log\\\"\"\npwd\nls -la\n"; string(got) != want {
+		t.Errorf("Batch script = %q, want %q", got, want)
+	}
+
+	c.SFTP = fakeTool(t, "sftp", `echo "Connection closed" >&2
+exit 255`)
+	if _, err := c.List(context.Background(), "db1", ""); err == nil || err.Error() != "Connection closed" {
+		t.Errorf("Expected sftp's complaint, got %v", err)
+	}
+}
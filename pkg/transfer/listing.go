@@ -0,0 +1,100 @@
+package transfer
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Entry is a file in a remote directory, as the server describes it.
+type Entry struct {
+	Name    string
+	Mode    string // as ls prints it, e.g. drwxr-xr-x
+	Size    int64
+	ModTime string // as the server prints it, e.g. "Jan  2 15:04"
+}
+
+// IsDir reports whether the entry is a directory.
+func (e Entry) IsDir() bool {
+	return strings.HasPrefix(e.Mode, "d")
+}
+
+// IsLink reports whether the entry is a symbolic link, which may point to a
+// directory or a file.
+func (e Entry) IsLink() bool {
+	return strings.HasPrefix(e.Mode, "l")
+}
+
+// Listing is the content of a remote directory.
+type Listing struct {
+	Dir     string // absolute path
+	Entries []Entry
+}
+
+const pwdPrefix = "Remote working directory: "
+
+// parseListing reads the output of the "pwd" and "ls -la" batch commands.
+// Lines are in the long format of ls: mode, links, owner, group, size,
+// three date fields and the name.
+func parseListing(output string) (Listing, error) {
+	var listing Listing
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimRight(line, "\r")
+		if strings.HasPrefix(line, "sftp>") || strings.TrimSpace(line) == "" {
+			continue
+		}
+		if dir, ok := strings.CutPrefix(line, pwdPrefix); ok {
+			listing.Dir = dir
+			continue
+		}
+		fields, name := cutFields(line, 8)
+		if len(fields) < 8 || name == "" || name == "." || name == ".." {
+			continue
+		}
+		size, err := strconv.ParseInt(fields[4], 10, 64)
+		if err != nil {
+			continue
+		}
+		listing.Entries = append(listing.Entries, Entry{
+			Name:    name,
+			Mode:    fields[0],
+			Size:    size,
+			ModTime: strings.Join(fields[5:8], " "),
+		})
+	}
+	if listing.Dir == "" {
+		return listing, fmt.Errorf("failed to read the remote directory from sftp")
+	}
+	sort.SliceStable(listing.Entries, func(i, j int) bool {
+		a, b := listing.Entries[i], listing.Entries[j]
+		if a.IsDir() != b.IsDir() {
+			return a.IsDir()
+		}
+		return a.Name < b.Name
+	})
+	return listing, nil
+}
+
+// cutFields splits the first n whitespace separated fields off line and
+// returns them with the rest, which keeps its inner spaces.
+func cutFields(line string, n int) ([]string, string) {
+	var fields []string
+	rest := line
+	for len(fields) < n {
+		rest = strings.TrimLeft(rest, " \t")
+		if rest == "" {
+			break
+		}
+		end := strings.IndexAny(rest, " \t")
+		if end < 0 {
+			end = len(rest)
+		}
+		fields = append(fields, rest[:end])
+		rest = rest[end:]
+	}
+	if len(rest) > 0 {
+		rest = rest[1:]
+	}
+	return fields, rest
+}
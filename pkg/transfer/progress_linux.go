@@ -0,0 +1,24 @@
+package transfer
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// bytesRead tells how much the process read so far, which for scp is
+// mostly the file it uploads.
+func bytesRead(pid int) (int64, bool) {
+	data, err := os.ReadFile(fmt.Sprintf("/proc/%d/io", pid))
+	if err != nil {
+		return 0, false
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if value, ok := strings.CutPrefix(line, "rchar: "); ok {
+			n, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+			return n, err == nil
+		}
+	}
+	return 0, false
+}
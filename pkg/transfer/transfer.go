@@ -0,0 +1,216 @@
+// Package transfer copies files between this machine and hosts with scp, and
+// lists remote directories with sftp. Both run with the host's settings from
+// the SSH config, as ssh itself would.
+package transfer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// pollInterval is how often the progress of a copy is measured.
+var pollInterval = 250 * time.Millisecond
+
+// Direction tells which way a transfer copies.
+type Direction int
+
+const (
+	Upload Direction = iota
+	Download
+)
+
+func (d Direction) String() string {
+	if d == Download {
+		return "download"
+	}
+	return "upload"
+}
+
+// Transfer is a copy between a local path and a path on a host. Directories
+// are copied with their contents.
+type Transfer struct {
+	Host      string
+	Direction Direction
+	Local     string
+	Remote    string // relative to the login directory unless absolute
+	Size      int64  // bytes to download when known from a listing, else 0
+}
+
+// Args returns the scp arguments of the transfer. Nobody could answer a
+// password prompt, so scp fails instead of asking.
+func (t Transfer) Args() []string {
+	args := []string{"-o", "BatchMode=yes", "-r", "-p", "--"}
+	remote := t.Host + ":" + t.Remote
+	if t.Direction == Download {
+		return append(args, remote, t.Local)
+	}
+	return append(args, t.Local, remote)
+}
+
+// Describe explains the transfer in a few words.
+func (t Transfer) Describe() string {
+	remote := t.Host + ":" + t.Remote
+	if t.Direction == Download {
+		return fmt.Sprintf("%s → %s", remote, t.Local)
+	}
+	return fmt.Sprintf("%s → %s", t.Local, remote)
+}
+
+// Progress is how far a transfer got. Total is 0 when it is not known.
+type Progress struct {
+	Done  int64
+	Total int64
+}
+
+// Client runs scp and sftp.
+type Client struct {
+	SCP  string // scp binary, "scp" from PATH if empty
+	SFTP string // sftp binary, "sftp" from PATH if empty
+}
+
+// NewClient returns a client using scp and sftp from PATH.
+func NewClient() *Client {
+	return &Client{SCP: "scp", SFTP: "sftp"}
+}
+
+// Copy runs the transfer and waits for it, or until ctx is done. While it
+// runs, progress is called with the bytes copied so far: the size of the
+// download as it grows, or what scp read of the upload where the platform
+// tells. progress may be nil.
+func (c *Client) Copy(ctx context.Context, t Transfer, progress func(Progress)) error {
+	binary := c.SCP
+	if binary == "" {
+		binary = "scp"
+	}
+	total := t.Size
+	destination := t.Local
+	if t.Direction == Upload {
+		size, err := diskUsage(t.Local)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %v", t.Local, err)
+		}
+		total = size
+	} else if info, err := os.Stat(t.Local); err == nil && info.IsDir() {
+		destination = filepath.Join(t.Local, path.Base(t.Remote))
+	}
+
+	stderr := &strings.Builder{}
+	cmd := exec.CommandContext(ctx, binary, t.Args()...)
+	cmd.Stderr = stderr
+	cmd.WaitDelay = time.Second
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start %s: %v", binary, err)
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- cmd.Wait() }()
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case err := <-done:
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if err != nil {
+				return commandError(err, stderr.String())
+			}
+			if progress != nil {
+				progress(Progress{Done: total, Total: total})
+			}
+			return nil
+		case <-ticker.C:
+			if progress == nil {
+				continue
+			}
+			var copied int64
+			var ok bool
+			if t.Direction == Upload {
+				copied, ok = bytesRead(cmd.Process.Pid)
+			} else {
+				copied, _ = diskUsage(destination)
+				ok = true
+			}
+			if !ok {
+				continue
+			}
+			if total > 0 && copied > total {
+				copied = total
+			}
+			progress(Progress{Done: copied, Total: total})
+		}
+	}
+}
+
+// List lists dir on host with sftp, hidden files included; "" lists the
+// login directory. Directories come first, "." and ".." are left out.
+func (c *Client) List(ctx context.Context, host, dir string) (Listing, error) {
+	binary := c.SFTP
+	if binary == "" {
+		binary = "sftp"
+	}
+	var script strings.Builder
+	if dir != "" {
+		fmt.Fprintf(&script, "cd %s\n", quote(dir))
+	}
+	script.WriteString("pwd\nls -la\n")
+
+	stdout := &strings.Builder{}
+	stderr := &strings.Builder{}
+	cmd := exec.CommandContext(ctx, binary, "-o", "BatchMode=yes", "-b", "-", host)
+	cmd.Stdin = strings.NewReader(script.String())
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	cmd.WaitDelay = time.Second
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return Listing{}, ctx.Err()
+		}
+		return Listing{}, commandError(err, stderr.String())
+	}
+	return parseListing(stdout.String())
+}
+
+// commandError prefers the last thing scp or sftp complained about over
+// its exit status.
+func commandError(err error, stderr string) error {
+	lines := strings.Split(strings.TrimSpace(stderr), "\n")
+	if last := strings.TrimSpace(lines[len(lines)-1]); last != "" {
+		return errors.New(last)
+	}
+	return err
+}
+
+// quote puts a path in double quotes for an sftp batch script.
+func quote(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	return `"` + strings.ReplaceAll(s, `"`, `\"`) + `"`
+}
+
+// diskUsage adds up the sizes of the files at path, which may be a file or
+// a directory.
+func diskUsage(root string) (int64, error) {
+	var total int64
+	err := filepath.WalkDir(root, func(_ string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.Type().IsRegular() {
+			info, err := entry.Info()
+			if err != nil {
+				return err
+			}
+			total += info.Size()
+		}
+		return nil
+	})
+	return total, err
+}
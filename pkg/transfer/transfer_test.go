@@ -0,0 +1,68 @@
+package transfer
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestArgs(t *testing.T) {
+	upload := Transfer{Host: "db1", Direction: Upload, Local: "-notes.txt", Remote: "/tmp/"}
+	want := []string{"-o", "BatchMode=yes", "-r", "-p", "--", "-notes.txt", "db1:/tmp/"}
+	if got := upload.Args(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Upload args = %v, want %v", got, want)
+	}
+
+	download := Transfer{Host: "db1", Direction: Download, Local: ".", Remote: "logs/app.log"}
+	want = []string{"-o", "BatchMode=yes", "-r", "-p", "--", "db1:logs/app.log", "."}
+	if got := download.Args(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Download args = %v, want %v", got, want)
+	}
+	if got := download.Describe(); got != "db1:logs/app.log → ." {
+		t.Errorf("Describe = %q", got)
+	}
+}
+
+func TestParseListing(t *testing.T) {
+	output := `sftp> pwd
+Remote working directory: /home/deploy
+sftp> ls -la
+drwxr-xr-x    5 deploy   deploy       4096 Mar  3 10:12 .
+drwxr-xr-x    4 root     root         4096 Jan  1  2024 ..
+-rw-r--r--    1 deploy   deploy        220 Jan  1  2024 .profile
+-rw-r--r--    1 deploy   deploy    1048576 Mar  3 10:12 my  notes.txt
+lrwxrwxrwx    1 deploy   deploy         12 Mar  3 10:12 current
+drwxr-xr-x    2 deploy   deploy       4096 Mar  3 10:12 logs
+`
+	listing, err := parseListing(output)
+	if err != nil {
+		t.Fatalf("parseListing: %v", err)
+	}
+	if listing.Dir != "/home/deploy" {
+		t.Errorf("Dir = %q", listing.Dir)
+	}
+	var names []string
+	for _, entry := range listing.Entries {
+		names = append(names, entry.Name)
+	}
+	want := []string{"logs", ".profile", "current", "my  notes.txt"}
+	if !reflect.DeepEqual(names, want) {
+		t.Fatalf("Names = %q, want %q", names, want)
+	}
+	notes := listing.Entries[3]
+	if notes.Size != 1048576 || notes.ModTime != "Mar 3 10:12" || notes.IsDir() {
+		t.Errorf("Unexpected entry %+v", notes)
+	}
+	if !listing.Entries[0].IsDir() || !listing.Entries[2].IsLink() {
+		t.Errorf("Expected a directory and a link, got %+v", listing.Entries)
+	}
+
+	if _, err := parseListing("sftp> pwd\n"); err == nil {
+		t.Error("Expected an error without a working directory")
+	}
+}
+
+func TestQuote(t *testing.T) {
+	if got := quote(`/srv/my "dir"\x`); got != `"/srv/my \"dir\"\\x"` {
+		t.Errorf("quote = %s", got)
+	}
+}
@@ -0,0 +1,50 @@
+//go:build !windows
+
+package tunnel
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"strconv"
+	"syscall"
+)
+
+// detach starts the process in its own session, so that it keeps running
+// when ohmyssh and its terminal go away.
+func detach(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+}
+
+// procDir is where Linux shows processes. Other systems have no /proc and
+// are asked with ps instead.
+var procDir = "/proc"
+
+// processRunning reports whether pid is alive and its command line mentions
+// marker, so that a recycled pid is never mistaken for the tunnel. A process
+// of another user cannot be a tunnel ohmyssh started, and a process whose
+// command line cannot be read is not assumed to be one.
+func processRunning(pid int, marker string) bool {
+	if err := syscall.Kill(pid, 0); err != nil {
+		return false
+	}
+	cmdline, err := os.ReadFile(fmt.Sprintf("%s/%d/cmdline", procDir, pid))
+	if err != nil {
+		if _, statErr := os.Stat(procDir); !errors.Is(statErr, fs.ErrNotExist) {
+			return false
+		}
+		// -ww keeps ps from cutting long command lines to the terminal width
+		cmdline, err = exec.Command("ps", "-ww", "-o", "command=", "-p", strconv.Itoa(pid)).Output()
+		if err != nil {
+			return false
+		}
+	}
+	return bytes.Contains(cmdline, []byte(marker))
+}
+
+func terminate(pid int) error {
+	return syscall.Kill(pid, syscall.SIGTERM)
+}
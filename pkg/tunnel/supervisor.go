@@ -0,0 +1,191 @@
+package tunnel
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// startupGrace is how long Start waits for ssh to fail, e.g. because the
+// port is taken or the login needs a password, before it counts as up.
+var startupGrace = 2 * time.Second
+
+// Status is what is known about a tunnel right now.
+type Status struct {
+	Running   bool
+	PID       int
+	Started   time.Time
+	PortInUse bool   // the local port is taken, by the tunnel or something else
+	LastError string // last line ssh wrote, if any
+}
+
+// process is the content of a tunnel's pid file.
+type process struct {
+	ID      string    `json:"id"`
+	PID     int       `json:"pid"`
+	Started time.Time `json:"started"`
+}
+
+// Supervisor starts tunnels as detached ssh processes and tracks them with
+// a pid file and a log per tunnel in Dir, so that they keep running after
+// ohmyssh exits and a later run can still report on and stop them.
+type Supervisor struct {
+	Dir string
+	SSH string // ssh binary, "ssh" from PATH if empty
+}
+
+// NewSupervisor returns a supervisor keeping its files in dir.
+func NewSupervisor(dir string) *Supervisor {
+	return &Supervisor{Dir: dir, SSH: "ssh"}
+}
+
+// LogPath is where the output of the tunnel's ssh process goes.
+func (s *Supervisor) LogPath(t Tunnel) string {
+	return s.file(t, ".log")
+}
+
+func (s *Supervisor) file(t Tunnel, ext string) string {
+	sum := sha1.Sum([]byte(t.ID()))
+	return filepath.Join(s.Dir, hex.EncodeToString(sum[:6])+ext)
+}
+
+func (s *Supervisor) readProcess(t Tunnel) (process, bool) {
+	data, err := os.ReadFile(s.file(t, ".pid"))
+	if err != nil {
+		return process{}, false
+	}
+	var p process
+	if err := json.Unmarshal(data, &p); err != nil || p.ID != t.ID() || p.PID <= 0 {
+		return process{}, false
+	}
+	return p, true
+}
+
+// Status reports whether the tunnel's ssh process is alive and whether its
+// local port is taken.
+func (s *Supervisor) Status(t Tunnel) Status {
+	var status Status
+	if p, ok := s.readProcess(t); ok && processRunning(p.PID, t.Spec) {
+		status.Running = true
+		status.PID = p.PID
+		status.Started = p.Started
+	}
+	if address := t.ListenAddress(); address != "" {
+		if listener, err := net.Listen("tcp", address); err != nil {
+			status.PortInUse = true
+		} else {
+			listener.Close()
+		}
+	}
+	if !status.Running {
+		status.LastError = lastLine(s.file(t, ".log"))
+	}
+	return status
+}
+
+// Start runs ssh for the tunnel in the background. It returns once ssh has
+// stayed up for a moment, or with the reason it exited.
+func (s *Supervisor) Start(t Tunnel) (int, error) {
+	if status := s.Status(t); status.Running {
+		return status.PID, fmt.Errorf("tunnel is already running (pid %d)", status.PID)
+	}
+	if err := os.MkdirAll(s.Dir, 0700); err != nil {
+		return 0, fmt.Errorf("failed to create tunnel directory: %v", err)
+	}
+	logFile, err := os.OpenFile(s.file(t, ".log"), os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create tunnel log: %v", err)
+	}
+	defer logFile.Close()
+
+	binary := s.SSH
+	if binary == "" {
+		binary = "ssh"
+	}
+	cmd := exec.Command(binary, t.Args()...)
+	cmd.Stdout = logFile
+	cmd.Stderr = logFile
+	detach(cmd)
+	if err := cmd.Start(); err != nil {
+		return 0, fmt.Errorf("failed to start ssh: %v", err)
+	}
+	exited := make(chan error, 1)
+	go func() { exited <- cmd.Wait() }()
+
+	select {
+	case err := <-exited:
+		reason := lastLine(s.file(t, ".log"))
+		if reason == "" && err != nil {
+			reason = err.Error()
+		}
+		return 0, fmt.Errorf("ssh exited: %s", reason)
+	case <-time.After(startupGrace):
+	}
+
+	data, err := json.Marshal(process{ID: t.ID(), PID: cmd.Process.Pid, Started: time.Now()})
+	if err == nil {
+		err = os.WriteFile(s.file(t, ".pid"), data, 0600)
+	}
+	if err != nil {
+		cmd.Process.Kill()
+		return 0, fmt.Errorf("failed to record tunnel: %v", err)
+	}
+	return cmd.Process.Pid, nil
+}
+
+// Stop ends the tunnel's ssh process, if it runs, and forgets it along with
+// its log, so that a stopped tunnel reports no error.
+func (s *Supervisor) Stop(t Tunnel) error {
+	p, ok := s.readProcess(t)
+	if ok && processRunning(p.PID, t.Spec) {
+		if err := terminate(p.PID); err != nil {
+			return fmt.Errorf("failed to stop tunnel (pid %d): %v", p.PID, err)
+		}
+	}
+	for _, ext := range []string{".pid", ".log"} {
+		if err := os.Remove(s.file(t, ext)); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove tunnel file: %v", err)
+		}
+	}
+	return nil
+}
+
+// Move keeps track of a running tunnel under a new definition, e.g. after
+// its host was renamed. The ssh process itself is left alone.
+func (s *Supervisor) Move(from, to Tunnel) error {
+	if err := os.Rename(s.file(from, ".log"), s.file(to, ".log")); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to move tunnel log: %v", err)
+	}
+	p, ok := s.readProcess(from)
+	if !ok {
+		return nil
+	}
+	p.ID = to.ID()
+	data, err := json.Marshal(p)
+	if err == nil {
+		err = os.WriteFile(s.file(to, ".pid"), data, 0600)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to move tunnel: %v", err)
+	}
+	os.Remove(s.file(from, ".pid"))
+	return nil
+}
+
+// lastLine returns the last non-empty line of a file.
+func lastLine(path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	lines := bytes.Split(bytes.TrimSpace(data), []byte("\n"))
+	return strings.TrimSpace(string(lines[len(lines)-1]))
+}
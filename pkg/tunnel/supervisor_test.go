@@ -0,0 +1,137 @@
+//go:build !windows
+
+package tunnel
+
+import (
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeSSH writes a shell script standing in for ssh and returns its path.
+func fakeSSH(t *testing.T, body string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "ssh")
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"+body+"\n"), 0755); err != nil {
+		t.Fatalf("Failed to write fake ssh: %v", err)
+	}
+	return path
+}
+
+func freePort(t *testing.T) string {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer listener.Close()
+	_, port, _ := net.SplitHostPort(listener.Addr().String())
+	return port
+}
+
+func TestSupervisorStartStop(t *testing.T) {
+	startupGrace = 200 * time.Millisecond
+	s := &Supervisor{Dir: t.TempDir(), SSH: fakeSSH(t, "echo connected >&2\nsleep 5")}
+	tun, err := New("db1", Local, "127.0.0.1:"+freePort(t)+":localhost:5432")
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	if status := s.Status(tun); status.Running || status.PortInUse {
+		t.Fatalf("Expected a stopped tunnel with a free port, got %+v", status)
+	}
+	pid, err := s.Start(tun)
+	if err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	status := s.Status(tun)
+	if !status.Running || status.PID != pid || status.Started.IsZero() {
+		t.Fatalf("Expected the tunnel to run as pid %d, got %+v", pid, status)
+	}
+	if _, err := s.Start(tun); err == nil {
+		t.Error("Expected starting a running tunnel to fail")
+	}
+
+	// A later run only has the pid file to go on, here after a host rename
+	renamed := tun
+	renamed.Host = "db-prod"
+	if err := s.Move(tun, renamed); err != nil {
+		t.Fatalf("Move: %v", err)
+	}
+	if s.Status(tun).Running || !s.Status(renamed).Running {
+		t.Fatal("Expected the running tunnel to follow the rename")
+	}
+	tun = renamed
+	again := NewSupervisor(s.Dir)
+	if err := again.Stop(tun); err != nil {
+		t.Fatalf("Stop: %v", err)
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for again.Status(tun).Running && time.Now().Before(deadline) {
+		time.Sleep(20 * time.Millisecond)
+	}
+	if status := again.Status(tun); status.Running || status.LastError != "" {
+		t.Errorf("Expected the tunnel to be stopped without an error, got %+v", status)
+	}
+}
+
+func TestSupervisorStartFailure(t *testing.T) {
+	startupGrace = 2 * time.Second
+	s := &Supervisor{Dir: t.TempDir(), SSH: fakeSSH(t, "echo 'bind [127.0.0.1]:5432: Address already in use' >&2\nexit 255")}
+	tun, _ := New("db1", Local, "5432:localhost:5432")
+
+	_, err := s.Start(tun)
+	if err == nil || !strings.Contains(err.Error(), "Address already in use") {
+		t.Fatalf("Expected ssh's own error, got %v", err)
+	}
+	status := s.Status(tun)
+	if status.Running || !strings.Contains(status.LastError, "Address already in use") {
+		t.Errorf("Expected a stopped tunnel with the last error, got %+v", status)
+	}
+}
+
+func TestSupervisorPortInUse(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer listener.Close()
+	_, port, _ := net.SplitHostPort(listener.Addr().String())
+
+	tun, _ := New("gw", Dynamic, "127.0.0.1:"+port)
+	status := NewSupervisor(t.TempDir()).Status(tun)
+	if status.Running || !status.PortInUse {
+		t.Errorf("Expected the port to be reported in use, got %+v", status)
+	}
+}
+
+func TestProcessRunning(t *testing.T) {
+	// The trailing command keeps sh from replacing itself with sleep
+	cmd := exec.Command("sh", "-c", "sleep 30; :", "127.0.0.1:15432:localhost:5432")
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("Failed to start: %v", err)
+	}
+	defer cmd.Process.Kill()
+	pid := cmd.Process.Pid
+
+	for _, dir := range []string{"/proc", filepath.Join(t.TempDir(), "missing")} {
+		procDir = dir
+		if !processRunning(pid, "127.0.0.1:15432:localhost:5432") {
+			t.Errorf("%s: expected the process to be recognised", dir)
+		}
+		if processRunning(pid, "127.0.0.1:2222:localhost:22") {
+			t.Errorf("%s: expected a process of another tunnel not to be recognised", dir)
+		}
+	}
+	procDir = "/proc"
+
+	cmd.Process.Kill()
+	cmd.Wait()
+	if processRunning(pid, "127.0.0.1:15432:localhost:5432") {
+		t.Error("Expected an exited process not to be running")
+	}
+}
@@ -0,0 +1,163 @@
+// Package tunnel defines SSH port forwards and runs them as background ssh
+// processes that outlive ohmyssh.
+package tunnel
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// Kind is the type of forward, named after the ssh option that creates it.
+type Kind string
+
+const (
+	Local   Kind = "L" // a local port forwarded to an address seen from the host
+	Remote  Kind = "R" // a port on the host forwarded to an address seen from here
+	Dynamic Kind = "D" // a local SOCKS proxy through the host
+)
+
+// ParseKind accepts L, R, D or the names local, remote and dynamic.
+func ParseKind(value string) (Kind, error) {
+	switch strings.ToLower(value) {
+	case "l", "local":
+		return Local, nil
+	case "r", "remote":
+		return Remote, nil
+	case "d", "dynamic", "socks":
+		return Dynamic, nil
+	}
+	return "", fmt.Errorf("unknown forward type %q, use L, R or D", value)
+}
+
+// Tunnel is a port forward kept open through a host. Spec is the argument
+// of ssh -L, -R or -D, e.g. "5432:localhost:5432" or "1080".
+type Tunnel struct {
+	Host string `json:"host"`
+	Kind Kind   `json:"kind"`
+	Spec string `json:"spec"`
+}
+
+// New validates a forward the way ssh would parse it.
+func New(host string, kind Kind, spec string) (Tunnel, error) {
+	t := Tunnel{Host: host, Kind: kind, Spec: strings.TrimSpace(spec)}
+	if host == "" {
+		return t, fmt.Errorf("host is required")
+	}
+	fields, err := splitSpec(t.Spec)
+	if err != nil {
+		return t, err
+	}
+
+	// The listening side is [bind:]port, the target host:port
+	var ports []string
+	switch {
+	case kind == Dynamic && (len(fields) == 1 || len(fields) == 2):
+		ports = fields[len(fields)-1:]
+	case kind == Remote && (len(fields) == 1 || len(fields) == 2):
+		// Remote dynamic forward: the host runs a SOCKS proxy
+		ports = fields[len(fields)-1:]
+	case (kind == Local || kind == Remote) && (len(fields) == 3 || len(fields) == 4):
+		ports = []string{fields[len(fields)-3], fields[len(fields)-1]}
+	case kind == Dynamic:
+		return t, fmt.Errorf("invalid dynamic forward %q, expected [bind:]port", spec)
+	case kind == Local || kind == Remote:
+		return t, fmt.Errorf("invalid forward %q, expected [bind:]port:host:hostport", spec)
+	default:
+		return t, fmt.Errorf("unknown forward type %q", kind)
+	}
+	for _, port := range ports {
+		if n, err := strconv.Atoi(port); err != nil || n < 1 || n > 65535 {
+			return t, fmt.Errorf("invalid port %q in %q", port, spec)
+		}
+	}
+	return t, nil
+}
+
+// splitSpec splits a forward spec on colons, keeping [IPv6] addresses whole.
+func splitSpec(spec string) ([]string, error) {
+	if spec == "" {
+		return nil, fmt.Errorf("forward is required")
+	}
+	var fields []string
+	for spec != "" {
+		if strings.HasPrefix(spec, "[") {
+			end := strings.Index(spec, "]")
+			if end < 0 {
+				return nil, fmt.Errorf("unclosed [ in %q", spec)
+			}
+			fields = append(fields, spec[1:end])
+			spec = strings.TrimPrefix(spec[end+1:], ":")
+			continue
+		}
+		field := spec
+		if i := strings.Index(spec, ":"); i >= 0 {
+			field, spec = spec[:i], spec[i+1:]
+		} else {
+			spec = ""
+		}
+		fields = append(fields, field)
+	}
+	return fields, nil
+}
+
+// ID identifies the tunnel; two tunnels with the same ID are the same
+// forward.
+func (t Tunnel) ID() string {
+	return fmt.Sprintf("%s -%s %s", t.Host, t.Kind, t.Spec)
+}
+
+// Args returns the ssh arguments that open the forward and nothing else.
+// ssh exits if the forward cannot be set up instead of running without it,
+// and never prompts, since nobody could answer.
+func (t Tunnel) Args() []string {
+	return []string{
+		"-N",
+		"-o", "ExitOnForwardFailure=yes",
+		"-o", "BatchMode=yes",
+		"-o", "ServerAliveInterval=30",
+		"-" + string(t.Kind), t.Spec,
+		"--", t.Host,
+	}
+}
+
+// ListenAddress is the local address the forward listens on, or "" for
+// remote forwards, which listen on the host.
+func (t Tunnel) ListenAddress() string {
+	if t.Kind == Remote {
+		return ""
+	}
+	fields, err := splitSpec(t.Spec)
+	if err != nil {
+		return ""
+	}
+	bind := "localhost"
+	port := fields[0]
+	switch {
+	case t.Kind == Dynamic && len(fields) == 2, t.Kind == Local && len(fields) == 4:
+		bind, port = fields[0], fields[1]
+	}
+	if bind == "" || bind == "*" {
+		bind = "0.0.0.0"
+	}
+	return net.JoinHostPort(bind, port)
+}
+
+// Describe explains the forward in words, e.g.
+// "localhost:5432 → localhost:5432 on db1".
+func (t Tunnel) Describe() string {
+	fields, _ := splitSpec(t.Spec)
+	switch {
+	case t.Kind == Dynamic:
+		return fmt.Sprintf("SOCKS proxy on %s through %s", t.ListenAddress(), t.Host)
+	case t.Kind == Remote && len(fields) <= 2:
+		return fmt.Sprintf("SOCKS proxy on %s port %s out through here", t.Host, fields[len(fields)-1])
+	case t.Kind == Remote:
+		target := net.JoinHostPort(fields[len(fields)-2], fields[len(fields)-1])
+		return fmt.Sprintf("%s port %s → %s here", t.Host, fields[len(fields)-3], target)
+	default:
+		target := net.JoinHostPort(fields[len(fields)-2], fields[len(fields)-1])
+		return fmt.Sprintf("%s → %s from %s", t.ListenAddress(), target, t.Host)
+	}
+}
@@ -0,0 +1,90 @@
+package tunnel
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNew(t *testing.T) {
+	valid := []struct {
+		kind   Kind
+		spec   string
+		listen string
+	}{
+		{Local, "5432:localhost:5432", "localhost:5432"},
+		{Local, "127.0.0.1:8080:web:80", "127.0.0.1:8080"},
+		{Local, "[::1]:8080:[fe80::1]:80", "[::1]:8080"},
+		{Local, "*:3306:db:3306", "0.0.0.0:3306"},
+		{Remote, "9000:localhost:3000", ""},
+		{Remote, "1080", ""},
+		{Dynamic, "1080", "localhost:1080"},
+		{Dynamic, "0.0.0.0:1080", "0.0.0.0:1080"},
+	}
+	for _, tc := range valid {
+		tun, err := New("db1", tc.kind, tc.spec)
+		if err != nil {
+			t.Errorf("New(%s %q): unexpected error %v", tc.kind, tc.spec, err)
+			continue
+		}
+		if got := tun.ListenAddress(); got != tc.listen {
+			t.Errorf("ListenAddress(%s %q) = %q, want %q", tc.kind, tc.spec, got, tc.listen)
+		}
+	}
+
+	invalid := []struct {
+		host string
+		kind Kind
+		spec string
+	}{
+		{"db1", Local, "5432"},
+		{"db1", Local, "5432:localhost"},
+		{"db1", Local, "99999:localhost:5432"},
+		{"db1", Local, "5432:localhost:pg"},
+		{"db1", Dynamic, "1080:localhost:80"},
+		{"db1", Local, "[::1:8080:web:80"},
+		{"db1", Local, ""},
+		{"", Local, "5432:localhost:5432"},
+		{"db1", Kind("X"), "1080"},
+	}
+	for _, tc := range invalid {
+		if _, err := New(tc.host, tc.kind, tc.spec); err == nil {
+			t.Errorf("New(%q, %s, %q): expected an error", tc.host, tc.kind, tc.spec)
+		}
+	}
+}
+
+func TestParseKind(t *testing.T) {
+	for value, want := range map[string]Kind{"L": Local, "remote": Remote, "d": Dynamic, "SOCKS": Dynamic} {
+		if got, err := ParseKind(value); err != nil || got != want {
+			t.Errorf("ParseKind(%q) = %q, %v, want %q", value, got, err, want)
+		}
+	}
+	if _, err := ParseKind("x"); err == nil {
+		t.Error("Expected an error for an unknown kind")
+	}
+}
+
+func TestArgsAndDescribe(t *testing.T) {
+	tun, _ := New("db1", Local, "5432:localhost:5432")
+	args := strings.Join(tun.Args(), " ")
+	for _, want := range []string{"-N", "ExitOnForwardFailure=yes", "BatchMode=yes", "-L 5432:localhost:5432 -- db1"} {
+		if !strings.Contains(args, want) {
+			t.Errorf("Args() = %q, missing %q", args, want)
+		}
+	}
+	if got := tun.ID(); got != "db1 -L 5432:localhost:5432" {
+		t.Errorf("ID() = %q", got)
+	}
+
+	descriptions := map[string]Tunnel{
+		"localhost:5432 → localhost:5432 from db1":       tun,
+		"SOCKS proxy on localhost:1080 through gw":       {Host: "gw", Kind: Dynamic, Spec: "1080"},
+		"web1 port 9000 → localhost:3000 here":           {Host: "web1", Kind: Remote, Spec: "9000:localhost:3000"},
+		"SOCKS proxy on web1 port 1080 out through here": {Host: "web1", Kind: Remote, Spec: "1080"},
+	}
+	for want, tun := range descriptions {
+		if got := tun.Describe(); got != want {
+			t.Errorf("Describe(%s) = %q, want %q", tun.ID(), got, want)
+		}
+	}
+}